@@ -0,0 +1,219 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "os"
+    "os/user"
+    "path/filepath"
+    "time"
+)
+
+// Журнал изменений audit.log - только дописывается, ClearDatabase и Compact его не трогают.
+// Запись:
+// Time   int64     - 8 байт, unix-время в наносекундах
+// Op     int32     - 4 байта
+// Actor  [40]byte  - 40 байт
+// BookID int32     - 4 байта
+// Before [152]byte - книга до изменения в формате books.db, нули - не было
+// After  [152]byte - книга после изменения, нули - удалена
+const auditRecordSize = 360
+
+type AuditOp int32
+
+const (
+    AuditAdd          AuditOp = 1
+    AuditUpdate       AuditOp = 2
+    AuditDelete       AuditOp = 3
+    AuditClear        AuditOp = 4  // по записи на каждую книгу, стертую очисткой
+    AuditImport       AuditOp = 5  // добавление или обновление из файла
+    AuditChangeID     AuditOp = 6
+    AuditRestore      AuditOp = 7  // откат к состоянию на момент времени
+    AuditPurge        AuditOp = 8  // окончательное удаление из корзины
+    AuditUndelete     AuditOp = 9  // возврат из корзины
+    AuditMergeAuthors AuditOp = 10 // переименование или объединение авторов
+)
+
+func (op AuditOp) String() string {
+    switch op {
+    case AuditAdd:
+        return "Добавление"
+    case AuditUpdate:
+        return "Изменение"
+    case AuditDelete:
+        return "Удаление"
+    case AuditClear:
+        return "Очистка БД"
+    case AuditImport:
+        return "Импорт"
+    case AuditChangeID:
+        return "Смена ID"
+    case AuditRestore:
+        return "Откат"
+    case AuditPurge:
+        return "Удаление из корзины"
+    case AuditUndelete:
+        return "Возврат из корзины"
+    case AuditMergeAuthors:
+        return "Объединение авторов"
+    }
+    return "?"
+}
+
+// Одна запись журнала. Before или After равны nil, если книги до или после не было
+type AuditEntry struct {
+    Time   time.Time
+    Op     AuditOp
+    Actor  string
+    BookID int32
+    Before *BookView
+    After  *BookView
+}
+
+// Фильтр журнала: BookID 0 - все книги, нулевые From/To - без ограничения
+type AuditQuery struct {
+    BookID int32
+    From   time.Time
+    To     time.Time
+}
+
+// O(1) журнал открывается отдельно от sidecars, чтобы очистка и сжатие его не стирали
+func (db *Database) openAudit(dir string) error {
+    audit, err := openRecordFile(filepath.Join(dir, "audit.log"), auditRecordSize)
+    if err != nil {
+        return err
+    }
+    db.audit = audit
+    return nil
+}
+
+// Имя пользователя ОС - автор изменений по умолчанию
+func defaultActor() string {
+    if current, err := user.Current(); err == nil && current.Username != "" {
+        return current.Username
+    }
+    if name := os.Getenv("USER"); name != "" {
+        return name
+    }
+    return "неизвестно"
+}
+
+// O(1) кто вносит изменения, попадает в каждую запись журнала
+func (db *Database) SetActor(name string) error {
+    if name == "" {
+        return fmt.Errorf("имя пользователя не может быть пустым")
+    }
+    if len([]byte(name)) > 40 {
+        return fmt.Errorf("имя пользователя длиннее 40 байт")
+    }
+    db.actor = name
+    return nil
+}
+
+// O(1)
+func (db *Database) Actor() string {
+    return db.actor
+}
+
+// O(1) операции внутри импорта записываются как импорт; возвращает функцию отмены
+func (db *Database) auditAs(op AuditOp) func() {
+    previous := db.auditOverride
+    db.auditOverride = op
+    return func() {
+        db.auditOverride = previous
+    }
+}
+
+// O(1)
+func auditToBytes(entry AuditEntry) []byte {
+    buf := make([]byte, auditRecordSize)
+    binary.LittleEndian.PutUint64(buf[0:8], uint64(entry.Time.UnixNano()))
+    binary.LittleEndian.PutUint32(buf[8:12], uint32(entry.Op))
+    copyStringToBytes(entry.Actor, buf[12:52])
+    binary.LittleEndian.PutUint32(buf[52:56], uint32(entry.BookID))
+    if entry.Before != nil {
+        copy(buf[56:208], bookToBytes(entry.Before.ToBook()))
+    }
+    if entry.After != nil {
+        copy(buf[208:360], bookToBytes(entry.After.ToBook()))
+    }
+    return buf
+}
+
+// O(1)
+func bytesToAudit(data []byte) AuditEntry {
+    entry := AuditEntry{
+        Time:   time.Unix(0, int64(binary.LittleEndian.Uint64(data[0:8]))),
+        Op:     AuditOp(binary.LittleEndian.Uint32(data[8:12])),
+        Actor:  bytesToString(data[12:52]),
+        BookID: int32(binary.LittleEndian.Uint32(data[52:56])),
+    }
+    if !isEmptyRecord(data[56:208]) {
+        before := bytesToBook(data[56:208]).ToView()
+        entry.Before = &before
+    }
+    if !isEmptyRecord(data[208:360]) {
+        after := bytesToBook(data[208:360]).ToView()
+        entry.After = &after
+    }
+    return entry
+}
+
+// O(1) дописываем запись в конец журнала
+func (db *Database) logAudit(op AuditOp, bookID int32, before, after *BookView) error {
+    if db.audit == nil {
+        return nil
+    }
+    if db.auditOverride != 0 && (op == AuditAdd || op == AuditUpdate || op == AuditDelete) {
+        op = db.auditOverride
+    }
+
+    entry := AuditEntry{
+        Time:   time.Now(),
+        Op:     op,
+        Actor:  db.actor,
+        BookID: bookID,
+        Before: before,
+        After:  after,
+    }
+    if _, err := db.audit.insert(auditToBytes(entry)); err != nil {
+        return fmt.Errorf("ошибка записи в журнал изменений: %v", err)
+    }
+    return nil
+}
+
+// O(n) n - записей в журнале, в порядке записи (от старых к новым)
+func (db *Database) GetAudit(query AuditQuery) ([]AuditEntry, error) {
+    var entries []AuditEntry
+    err := db.audit.scan(func(position int64, data []byte) {
+        entry := bytesToAudit(data)
+        if query.BookID != 0 && !entry.touches(query.BookID) {
+            return
+        }
+        if !query.From.IsZero() && entry.Time.Before(query.From) {
+            return
+        }
+        if !query.To.IsZero() && entry.Time.After(query.To) {
+            return
+        }
+        entries = append(entries, entry)
+    })
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения журнала изменений: %v", err)
+    }
+    return entries, nil
+}
+
+// O(1) запись относится к книге; при смене ID - и к старому, и к новому номеру
+func (e AuditEntry) touches(bookID int32) bool {
+    if e.BookID == bookID {
+        return true
+    }
+    return (e.Before != nil && e.Before.ID == bookID) || (e.After != nil && e.After.ID == bookID)
+}
+
+// O(1) ссылка на копию, чтобы запись журнала не зависела от дальнейших изменений
+func viewRef(book *Book) *BookView {
+    view := book.ToView()
+    return &view
+}
\ No newline at end of file
@@ -0,0 +1,397 @@
+package database
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+    "unicode"
+)
+
+// Запись в authors.db: вариант написания -> каноническое имя
+// Alias     [40]byte - 40 байт
+// Canonical [40]byte - 40 байт
+const authorAliasSize = 80
+
+// O(1)
+func aliasToBytes(alias, canonical string) []byte {
+    buf := make([]byte, authorAliasSize)
+    copyStringToBytes(alias, buf[0:40])
+    copyStringToBytes(canonical, buf[40:80])
+    return buf
+}
+
+// O(1)
+func bytesToAlias(data []byte) (string, string) {
+    return bytesToString(data[0:40]), bytesToString(data[40:80])
+}
+
+// O(n) n - количество псевдонимов
+func (db *Database) loadAuthorAliases() error {
+    db.aliasIndex = make(map[string]string)
+    db.aliasPositions = make(map[string]int64)
+    return db.authors.scan(func(position int64, data []byte) {
+        alias, canonical := bytesToAlias(data)
+        db.aliasIndex[alias] = canonical
+        db.aliasPositions[alias] = position
+    })
+}
+
+// O(1) в среднем. Если имя - известный вариант написания, возвращает каноническое
+func (db *Database) CanonicalAuthor(name string) string {
+    if canonical, ok := db.aliasIndex[name]; ok {
+        return canonical
+    }
+    return name
+}
+
+// O(n) n - количество псевдонимов
+func (db *Database) AuthorAliases(canonical string) []string {
+    var aliases []string
+    for alias, target := range db.aliasIndex {
+        if target == canonical {
+            aliases = append(aliases, alias)
+        }
+    }
+    sort.Strings(aliases)
+    return aliases
+}
+
+// O(k) k - количество различных авторов
+func (db *Database) GetAllAuthors() []string {
+    authors := make([]string, 0, len(db.authorIndex))
+    for author := range db.authorIndex {
+        authors = append(authors, author)
+    }
+    sort.Strings(authors)
+    return authors
+}
+
+// O(k^2 * t), k - количество различных авторов, t - количество слов в имени
+// Группирует варианты написания, похожие на одного человека:
+// "Лев Толстой", "Толстой Л.Н." и "L. Tolstoy" попадут в одну группу.
+// Первым в группе идет вариант, у которого больше всего книг
+func (db *Database) FindDuplicateAuthors() [][]string {
+    authors := db.GetAllAuthors()
+    sort.SliceStable(authors, func(i, j int) bool {
+        return len(db.authorIndex[authors[i]]) > len(db.authorIndex[authors[j]])
+    })
+
+    type group struct {
+        names []string
+        keys  []authorKey
+    }
+    var groups []*group
+
+    for _, author := range authors {
+        key := parseAuthorKey(author)
+        if len(key.words) == 0 {
+            continue
+        }
+
+        var target *group
+        for _, g := range groups {
+            // жадно: имя должно быть похоже на каждого в группе,
+            // иначе "Толстой" склеит Льва и Алексея через себя
+            fits := true
+            for _, other := range g.keys {
+                if !key.matches(other) {
+                    fits = false
+                    break
+                }
+            }
+            if fits {
+                target = g
+                break
+            }
+        }
+
+        if target == nil {
+            target = &group{}
+            groups = append(groups, target)
+        }
+        target.names = append(target.names, author)
+        target.keys = append(target.keys, key)
+    }
+
+    var result [][]string
+    for _, g := range groups {
+        if len(g.names) > 1 {
+            result = append(result, g.names)
+        }
+    }
+    return result
+}
+
+// O(m * k) m - количество затронутых книг, k - записей по ключу в индексах
+// Переписывает автора у всех книг с именами из variants на canonical,
+// а сами варианты запоминает как псевдонимы, чтобы по ним продолжал работать поиск.
+// Операция целая: если не удалась запись книги или псевдонима, все возвращается как было.
+// Каждая переписанная книга попадает в журнал как "Объединение авторов".
+// Возвращает количество переписанных книг
+func (db *Database) MergeAuthors(canonical string, variants []string) (int, error) {
+    canonical = strings.TrimSpace(canonical)
+    if canonical == "" {
+        return 0, fmt.Errorf("каноническое имя автора не может быть пустым")
+    }
+    if len([]byte(canonical)) > 40 {
+        return 0, fmt.Errorf("имя автора '%s' длиннее 40 байт", canonical)
+    }
+
+    type rewritten struct {
+        position int64
+        old      *Book
+        new      *Book
+    }
+    var done []rewritten
+
+    // псевдонимы, которые тронет объединение, и их прежние значения ("" - не было)
+    savedAliases := map[string]string{canonical: db.aliasIndex[canonical]}
+    for _, variant := range variants {
+        if variant == canonical || strings.TrimSpace(variant) == "" {
+            continue
+        }
+        savedAliases[variant] = db.aliasIndex[variant]
+        for _, alias := range db.AuthorAliases(variant) {
+            savedAliases[alias] = db.aliasIndex[alias]
+        }
+    }
+
+    // откатываем уже переписанные записи и псевдонимы, чтобы операция была целой
+    rollback := func() {
+        for i := len(done) - 1; i >= 0; i-- {
+            db.removeFromIndexes(done[i].new, done[i].position)
+            db.writeRecord(done[i].old, done[i].position)
+            db.updateIndexes(done[i].old, done[i].position)
+        }
+        for alias, previous := range savedAliases {
+            if previous == "" {
+                db.removeAuthorAlias(alias)
+            } else if db.aliasIndex[alias] != previous {
+                db.setAuthorAlias(alias, previous)
+            }
+        }
+    }
+
+    for _, variant := range variants {
+        if variant == canonical {
+            continue
+        }
+
+        positions := append([]int64(nil), db.authorIndex[variant]...)
+        for _, position := range positions {
+            book, err := db.readRecord(position)
+            if err != nil {
+                rollback()
+                return 0, err
+            }
+
+            updated := *book
+            copyStringToBytes(canonical, clearBytes(updated.Author[:]))
+
+            db.removeFromIndexes(book, position)
+            if err := db.writeRecord(&updated, position); err != nil {
+                db.updateIndexes(book, position)
+                rollback()
+                return 0, fmt.Errorf("ошибка перезаписи книги с ID %d: %v", book.ID, err)
+            }
+            db.updateIndexes(&updated, position)
+            done = append(done, rewritten{position: position, old: book, new: &updated})
+        }
+    }
+
+    // каноническое имя больше не может быть чьим-то псевдонимом
+    if err := db.removeAuthorAlias(canonical); err != nil {
+        rollback()
+        return 0, err
+    }
+
+    for _, variant := range variants {
+        if variant == canonical || strings.TrimSpace(variant) == "" {
+            continue
+        }
+        if err := db.setAuthorAlias(variant, canonical); err != nil {
+            rollback()
+            return 0, err
+        }
+        // псевдонимы старого имени переезжают к новому
+        for _, alias := range db.AuthorAliases(variant) {
+            if err := db.setAuthorAlias(alias, canonical); err != nil {
+                rollback()
+                return 0, err
+            }
+        }
+    }
+
+    defer db.auditAs(AuditMergeAuthors)()
+    for _, r := range done {
+        if err := db.logAudit(AuditUpdate, r.new.ID, viewRef(r.old), viewRef(r.new)); err != nil {
+            return len(done), err
+        }
+    }
+    return len(done), nil
+}
+
+// O(m * k), то же что MergeAuthors для одного имени
+func (db *Database) RenameAuthor(oldName, newName string) (int, error) {
+    if _, exists := db.authorIndex[oldName]; !exists {
+        return 0, fmt.Errorf("автор '%s' не найден", oldName)
+    }
+    return db.MergeAuthors(newName, []string{oldName})
+}
+
+// O(1) в среднем
+func (db *Database) setAuthorAlias(alias, canonical string) error {
+    data := aliasToBytes(alias, canonical)
+
+    if position, exists := db.aliasPositions[alias]; exists {
+        if err := db.authors.write(position, data); err != nil {
+            return fmt.Errorf("ошибка записи псевдонима: %v", err)
+        }
+    } else {
+        position, err := db.authors.insert(data)
+        if err != nil {
+            return fmt.Errorf("ошибка записи псевдонима: %v", err)
+        }
+        db.aliasPositions[alias] = position
+    }
+
+    db.aliasIndex[alias] = canonical
+    return nil
+}
+
+// O(1) в среднем
+func (db *Database) removeAuthorAlias(alias string) error {
+    position, exists := db.aliasPositions[alias]
+    if !exists {
+        return nil
+    }
+    if err := db.authors.remove(position); err != nil {
+        return fmt.Errorf("ошибка удаления псевдонима: %v", err)
+    }
+    delete(db.aliasPositions, alias)
+    delete(db.aliasIndex, alias)
+    return nil
+}
+
+// O(a) a - количество всех псевдонимов
+func (db *Database) authorMatches(author, searchValue string) bool {
+    if strings.Contains(strings.ToLower(author), searchValue) {
+        return true
+    }
+    for alias, canonical := range db.aliasIndex {
+        if canonical == author && strings.Contains(strings.ToLower(alias), searchValue) {
+            return true
+        }
+    }
+    return false
+}
+
+func clearBytes(data []byte) []byte {
+    for i := range data {
+        data[i] = 0
+    }
+    return data
+}
+
+// Нормализованное имя: полные слова (фамилия, имя) и инициалы,
+// все приведено к латинице, чтобы кириллица и транслит совпадали
+type authorKey struct {
+    words    []string
+    initials []string
+}
+
+// O(t)
+func parseAuthorKey(name string) authorKey {
+    var key authorKey
+    tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+        return !unicode.IsLetter(r)
+    })
+
+    for _, token := range tokens {
+        word := normalizeLatin(transliterate(token))
+        if word == "" {
+            continue
+        }
+        if len([]rune(token)) == 1 {
+            key.initials = append(key.initials, firstLetter(word))
+        } else {
+            key.words = append(key.words, word)
+        }
+    }
+    return key
+}
+
+// Два имени похожи, если есть общее полное слово,
+// а первые буквы остальных частей не противоречат друг другу
+func (k authorKey) matches(other authorKey) bool {
+    for _, word := range k.words {
+        for _, otherWord := range other.words {
+            if word != otherWord {
+                continue
+            }
+            rest := k.restInitials(word)
+            otherRest := other.restInitials(word)
+            if len(rest) == 0 || len(otherRest) == 0 {
+                return true
+            }
+            for _, letter := range rest {
+                for _, otherLetter := range otherRest {
+                    if letter == otherLetter {
+                        return true
+                    }
+                }
+            }
+        }
+    }
+    return false
+}
+
+// O(t) первые буквы всех частей имени, кроме общего слова
+func (k authorKey) restInitials(except string) []string {
+    var initials []string
+    for _, word := range k.words {
+        if word != except {
+            initials = append(initials, firstLetter(word))
+        }
+    }
+    return append(initials, k.initials...)
+}
+
+func firstLetter(word string) string {
+    return string([]rune(word)[:1])
+}
+
+var cyrillicToLatin = map[rune]string{
+    'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+    'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+    'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+    'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+    'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+}
+
+// O(t)
+func transliterate(s string) string {
+    var b strings.Builder
+    for _, r := range s {
+        if latin, ok := cyrillicToLatin[r]; ok {
+            b.WriteString(latin)
+        } else {
+            b.WriteRune(r)
+        }
+    }
+    return b.String()
+}
+
+// Сглаживаем расхождения разных систем транслитерации:
+// Tolstoy/Tolstoj, Dostoevsky/Dostoevskiy/Dostoyevsky
+func normalizeLatin(s string) string {
+    s = strings.NewReplacer("j", "y", "w", "v").Replace(s)
+    s = strings.ReplaceAll(s, "ye", "e")
+    for _, suffix := range []string{"iy", "yy", "ii"} {
+        if strings.HasSuffix(s, suffix) {
+            s = strings.TrimSuffix(s, suffix) + "y"
+            break
+        }
+    }
+    return s
+}
\ No newline at end of file
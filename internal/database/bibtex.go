@@ -0,0 +1,740 @@
+package database
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "os"
+    "slices"
+    "sort"
+    "strings"
+    "unicode"
+    "unicode/utf8"
+
+    "golang.org/x/text/unicode/norm"
+)
+
+// Транслитерация кириллицы для ключей цитирования (как в загранпаспорте)
+var citationTranslit = map[rune]string{
+    'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e",
+    'ж': "zh", 'з': "z", 'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m",
+    'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+    'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+    'ъ': "ie", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+}
+
+// O(n) фамилия автора: до запятой в "Фамилия, Имя", иначе последнее слово
+func authorSurname(author string) string {
+    if surname, _, found := strings.Cut(author, ","); found {
+        return strings.TrimSpace(surname)
+    }
+    words := strings.Fields(author)
+    if len(words) == 0 {
+        return ""
+    }
+    return words[len(words)-1]
+}
+
+// O(n) латиница и цифры в нижнем регистре: "Достоевский" -> "dostoevskii",
+// "Čapek" -> "capek"
+func citationWord(s string) string {
+    var b strings.Builder
+    for _, r := range norm.NFD.String(strings.ToLower(s)) {
+        switch {
+        case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+            b.WriteRune(r)
+        case citationTranslit[r] != "":
+            b.WriteString(citationTranslit[r])
+        }
+    }
+    return b.String()
+}
+
+// O(n log n) ключи цитирования вида фамилия+год ("dostoevskii1866").
+// Книги с одинаковым ключом различаются буквами a, b, c... в порядке ID,
+// так что ключ книги не зависит от порядка книг в списке
+func CitationKeys(books []BookView) map[int32]string {
+    groups := make(map[string][]int32)
+    for _, book := range books {
+        base := citationWord(authorSurname(book.Author))
+        if base == "" {
+            base = "anon"
+        }
+        base = fmt.Sprintf("%s%d", base, book.Year)
+        groups[base] = append(groups[base], book.ID)
+    }
+
+    keys := make(map[int32]string, len(books))
+    for base, ids := range groups {
+        if len(ids) == 1 {
+            keys[ids[0]] = base
+            continue
+        }
+        sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+        for i, id := range ids {
+            keys[id] = base + citationSuffix(i)
+        }
+    }
+    return keys
+}
+
+// O(n log n) n - книг в базе. Ключи для выгрузки books: буквы a, b, c...
+// раздаются по ID среди всех книг базы (вместе с корзиной) с тем же ключом,
+// а не только среди выгружаемых, поэтому ключ книги одинаков в любой выгрузке
+func (db *Database) exportCitationKeys(books []BookView) (map[int32]string, error) {
+    all, err := db.GetAllBooks()
+    if err != nil {
+        return nil, err
+    }
+    trash, err := db.GetTrash()
+    if err != nil {
+        return nil, err
+    }
+    known := make(map[int32]bool, len(all)+len(trash))
+    for _, book := range all {
+        known[book.ID] = true
+    }
+    for _, entry := range trash {
+        all = append(all, entry.Book)
+        known[entry.Book.ID] = true
+    }
+    // книги, которых в базе уже нет, получают ключ среди остальных
+    for _, book := range books {
+        if !known[book.ID] {
+            all = append(all, book)
+            known[book.ID] = true
+        }
+    }
+
+    keys := CitationKeys(all)
+    exported := make(map[int32]string, len(books))
+    for _, book := range books {
+        exported[book.ID] = keys[book.ID]
+    }
+    return exported, nil
+}
+
+// O(log i) a, b, ..., z, aa, ab, ...
+func citationSuffix(i int) string {
+    suffix := ""
+    for i++; i > 0; i = (i - 1) / 26 {
+        suffix = string(rune('a'+(i-1)%26)) + suffix
+    }
+    return suffix
+}
+
+// Символы, которые в BibTeX нужно экранировать
+var bibtexEscaper = strings.NewReplacer(
+    `\`, `\textbackslash{}`,
+    "{", `\{`, "}", `\}`,
+    "&", `\&`, "%", `\%`, "$", `\$`, "#", `\#`, "_", `\_`,
+    "~", `\textasciitilde{}`, "^", `\textasciicircum{}`,
+    "\r\n", " ", "\n", " ", "\r", " ",
+)
+
+// O(n) BibTeX и biber понимают UTF-8, поэтому экранируем только служебные символы
+func escapeBibTeX(s string) string {
+    return bibtexEscaper.Replace(s)
+}
+
+// O(n) "Фамилия, Имя". Авторы в BibTeX разделяются словом and, поэтому
+// имя, где оно встречается само по себе, берем в скобки целиком
+func bibtexAuthor(author string) string {
+    if strings.Contains(" "+strings.ToLower(author)+" ", " and ") {
+        return "{" + escapeBibTeX(author) + "}"
+    }
+    name, _ := invertName(author)
+    return escapeBibTeX(name)
+}
+
+// O(n) книги как записи @book. Теги - поле keywords
+func (db *Database) ExportToBibTeX(filename string, books []BookView) error {
+    keys, err := db.exportCitationKeys(books)
+    if err != nil {
+        return err
+    }
+
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    writer := bufio.NewWriter(file)
+    for _, book := range books {
+        fmt.Fprintf(writer, "@book{%s,\n", keys[book.ID])
+        fmt.Fprintf(writer, "  author = {%s},\n", bibtexAuthor(book.Author))
+        // двойные скобки сохраняют регистр букв в названии
+        fmt.Fprintf(writer, "  title = {{%s}},\n", escapeBibTeX(book.Title))
+        fmt.Fprintf(writer, "  year = {%d},\n", book.Year)
+        if tags := db.GetTags(book.ID); len(tags) > 0 {
+            for i := range tags {
+                tags[i] = escapeBibTeX(tags[i])
+            }
+            fmt.Fprintf(writer, "  keywords = {%s},\n", strings.Join(tags, ", "))
+        }
+        writer.WriteString("}\n\n")
+    }
+
+    if err := writer.Flush(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// Запись BibTeX: тип, ключ, строка начала и поля с именами в нижнем регистре
+type bibtexEntry struct {
+    kind   string
+    key    string
+    line   int
+    fields map[string]string
+}
+
+// Разбор BibTeX: @тип{ключ, поле = значение, ...}. Значения в скобках,
+// в кавычках, числа и макросы @string, склеенные через #
+type bibtexParser struct {
+    data   string
+    pos    int
+    macros map[string]string
+}
+
+// O(n)
+func (p *bibtexParser) line() int {
+    return 1 + strings.Count(p.data[:min(p.pos, len(p.data))], "\n")
+}
+
+// O(k)
+func (p *bibtexParser) skipSpace() {
+    for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+        p.pos++
+    }
+}
+
+// O(k) имя типа, поля или макроса
+func (p *bibtexParser) ident() string {
+    start := p.pos
+    for p.pos < len(p.data) {
+        c := p.data[p.pos]
+        if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') &&
+            strings.IndexByte("-_:.+/", c) < 0 {
+            break
+        }
+        p.pos++
+    }
+    return p.data[start:p.pos]
+}
+
+// O(k) содержимое сбалансированных фигурных скобок, p.pos на открывающей
+func (p *bibtexParser) braced() (string, error) {
+    start := p.pos + 1
+    depth := 0
+    for ; p.pos < len(p.data); p.pos++ {
+        switch p.data[p.pos] {
+        case '\\':
+            p.pos++
+        case '{':
+            depth++
+        case '}':
+            depth--
+            if depth == 0 {
+                p.pos++
+                return p.data[start : p.pos-1], nil
+            }
+        }
+    }
+    return "", fmt.Errorf("строка %d: не закрыта фигурная скобка", p.line())
+}
+
+// O(k) строка в кавычках; кавычки внутри скобок значения не заканчивают
+func (p *bibtexParser) quoted() (string, error) {
+    start := p.pos + 1
+    depth := 0
+    for p.pos++; p.pos < len(p.data); p.pos++ {
+        switch p.data[p.pos] {
+        case '\\':
+            p.pos++
+        case '{':
+            depth++
+        case '}':
+            depth--
+        case '"':
+            if depth == 0 {
+                p.pos++
+                return p.data[start : p.pos-1], nil
+            }
+        }
+    }
+    return "", fmt.Errorf("строка %d: не закрыта кавычка", p.line())
+}
+
+// O(k) значение поля без раскрытия команд LaTeX
+func (p *bibtexParser) value() (string, error) {
+    var parts []string
+    for {
+        p.skipSpace()
+        if p.pos >= len(p.data) {
+            return "", fmt.Errorf("строка %d: нет значения поля", p.line())
+        }
+        var part string
+        var err error
+        switch c := p.data[p.pos]; {
+        case c == '{':
+            part, err = p.braced()
+        case c == '"':
+            part, err = p.quoted()
+        default:
+            name := p.ident()
+            if name == "" {
+                return "", fmt.Errorf("строка %d: неожиданный символ %q", p.line(), c)
+            }
+            part = name
+            if macro, ok := p.macros[strings.ToLower(name)]; ok {
+                part = macro
+            }
+        }
+        if err != nil {
+            return "", err
+        }
+        parts = append(parts, part)
+
+        p.skipSpace()
+        if p.pos < len(p.data) && p.data[p.pos] == '#' {
+            p.pos++
+            continue
+        }
+        return strings.Join(parts, ""), nil
+    }
+}
+
+// O(k) поля записи до закрывающей скобки closing
+func (p *bibtexParser) fields(closing byte) (map[string]string, error) {
+    fields := make(map[string]string)
+    for {
+        p.skipSpace()
+        if p.pos >= len(p.data) {
+            return nil, fmt.Errorf("строка %d: запись не закрыта", p.line())
+        }
+        if p.data[p.pos] == closing {
+            p.pos++
+            return fields, nil
+        }
+        name := strings.ToLower(p.ident())
+        p.skipSpace()
+        if name == "" || p.pos >= len(p.data) || p.data[p.pos] != '=' {
+            return nil, fmt.Errorf("строка %d: ожидалось имя поля и '='", p.line())
+        }
+        p.pos++
+        value, err := p.value()
+        if err != nil {
+            return nil, err
+        }
+        fields[name] = value
+
+        p.skipSpace()
+        if p.pos < len(p.data) && p.data[p.pos] == ',' {
+            p.pos++
+        } else if p.pos >= len(p.data) || p.data[p.pos] != closing {
+            return nil, fmt.Errorf("строка %d: ожидалась ',' после поля %s", p.line(), name)
+        }
+    }
+}
+
+// O(k) следующая запись; комментарии, @preamble и @string пропускаются
+func (p *bibtexParser) next() (*bibtexEntry, error) {
+    for {
+        at := strings.IndexByte(p.data[p.pos:], '@')
+        if at == -1 {
+            return nil, nil
+        }
+        p.pos += at + 1
+        line := p.line()
+        kind := strings.ToLower(p.ident())
+        p.skipSpace()
+        if p.pos >= len(p.data) || (p.data[p.pos] != '{' && p.data[p.pos] != '(') {
+            // @ в тексте между записями - это комментарий
+            continue
+        }
+        closing := byte('}')
+        if p.data[p.pos] == '(' {
+            closing = ')'
+        }
+
+        switch kind {
+        case "comment", "preamble":
+            if closing == ')' {
+                end := strings.IndexByte(p.data[p.pos:], ')')
+                if end == -1 {
+                    return nil, fmt.Errorf("строка %d: не закрыта запись @%s", line, kind)
+                }
+                p.pos += end + 1
+            } else if _, err := p.braced(); err != nil {
+                return nil, err
+            }
+            continue
+        case "string":
+            p.pos++
+            macros, err := p.fields(closing)
+            if err != nil {
+                return nil, err
+            }
+            for name, value := range macros {
+                p.macros[name] = value
+            }
+            continue
+        }
+
+        p.pos++
+        keyStart := p.pos
+        for p.pos < len(p.data) && p.data[p.pos] != ',' && p.data[p.pos] != closing {
+            p.pos++
+        }
+        entry := &bibtexEntry{kind: kind, key: strings.TrimSpace(p.data[keyStart:p.pos]), line: line}
+        if p.pos < len(p.data) && p.data[p.pos] == ',' {
+            p.pos++
+        }
+        fields, err := p.fields(closing)
+        if err != nil {
+            return nil, err
+        }
+        entry.fields = fields
+        return entry, nil
+    }
+}
+
+// Диакритика команд LaTeX: \'e, \"{o}, \c{c}
+var latexAccents = map[string]rune{
+    "'": '\u0301', "`": '\u0300', "^": '\u0302', "\"": '\u0308', "~": '\u0303',
+    "=": '\u0304', ".": '\u0307', "c": '\u0327', "v": '\u030C', "u": '\u0306',
+    "H": '\u030B', "k": '\u0328', "r": '\u030A',
+}
+
+// Команды LaTeX, которые заменяются символом
+var latexSymbols = map[string]string{
+    "textbackslash": `\`, "textasciitilde": "~", "textasciicircum": "^",
+    "ss": "ß", "o": "ø", "O": "Ø", "ae": "æ", "AE": "Æ", "aa": "å", "AA": "Å",
+    "l": "ł", "L": "Ł", "i": "ı", "oe": "œ", "OE": "Œ", "&": "&", "%": "%",
+    "$": "$", "#": "#", "_": "_", "{": "{", "}": "}", " ": " ",
+    "TeX": "TeX", "LaTeX": "LaTeX", "BibTeX": "BibTeX",
+}
+
+// O(n) значение поля BibTeX в обычный текст: команды LaTeX раскрываются,
+// скобки группировки убираются, пробелы схлопываются
+func decodeBibTeX(s string) string {
+    var b strings.Builder
+    for i := 0; i < len(s); {
+        c := s[i]
+        switch {
+        case c == '{' || c == '}':
+            i++
+        case c == '~':
+            b.WriteByte(' ')
+            i++
+        case c == '\\' && i+1 < len(s):
+            i++
+            name := s[i : i+1]
+            if unicode.IsLetter(rune(s[i])) && s[i] < utf8.RuneSelf {
+                end := i
+                for end < len(s) && s[end] < utf8.RuneSelf && unicode.IsLetter(rune(s[end])) {
+                    end++
+                }
+                name = s[i:end]
+            }
+            i += len(name)
+
+            if mark, ok := latexAccents[name]; ok {
+                // буква после команды: \'e, \'{e}, {\'e}, \c c
+                for i < len(s) && (s[i] == '{' || (s[i] == ' ' && len(name) == 1 && unicode.IsLetter(rune(name[0])))) {
+                    i++
+                }
+                if i < len(s) {
+                    r, width := utf8.DecodeRuneInString(s[i:])
+                    b.WriteRune(r)
+                    b.WriteRune(mark)
+                    i += width
+                }
+                continue
+            }
+            if symbol, ok := latexSymbols[name]; ok {
+                b.WriteString(symbol)
+            }
+            if unicode.IsLetter(rune(name[0])) {
+                // пробел и пустые скобки после команды только отделяют ее от текста
+                if strings.HasPrefix(s[i:], "{}") {
+                    i += 2
+                } else if i < len(s) && s[i] == ' ' {
+                    i++
+                }
+            }
+        default:
+            b.WriteByte(c)
+            i++
+        }
+    }
+    return norm.NFC.String(strings.Join(strings.Fields(b.String()), " "))
+}
+
+// O(n) авторы через " and " вне фигурных скобок
+func splitBibTeXAuthors(s string) []string {
+    isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\r' || c == '\n' }
+    var authors []string
+    depth, start := 0, 0
+    for i := 0; i < len(s); i++ {
+        switch {
+        case s[i] == '{':
+            depth++
+        case s[i] == '}':
+            depth--
+        case depth == 0 && isSpace(s[i]) && i+4 < len(s) &&
+            strings.EqualFold(s[i+1:i+4], "and") && isSpace(s[i+4]):
+            authors = append(authors, s[start:i])
+            start = i + 5
+            i = start - 1
+        }
+    }
+    return append(authors, s[start:])
+}
+
+// O(n) список тегов: через запятую или точку с запятой
+func splitKeywords(s string) []string {
+    var keywords []string
+    for _, keyword := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+        if keyword = strings.TrimSpace(keyword); keyword != "" {
+            keywords = append(keywords, keyword)
+        }
+    }
+    return keywords
+}
+
+// O(1) книга из разобранной библиографической записи. Соавторы отбрасываются,
+// "Фамилия, Имя" переворачивается в "Имя Фамилия"
+func bibliographyBook(title, author, year string) (BookView, error) {
+    book := BookView{Title: title, Author: directName(author)}
+    if book.Title == "" {
+        return book, fmt.Errorf("нет названия")
+    }
+    if book.Author == "" {
+        return book, fmt.Errorf("нет автора")
+    }
+    parsedYear, ok := firstYear(year)
+    if !ok {
+        return book, fmt.Errorf("нет года издания")
+    }
+    book.Year = parsedYear
+    return book, nil
+}
+
+// Запись библиографии, разобранная до записи в базу. Raw - ключ BibTeX
+// или название из RIS, чтобы запись можно было найти в отчете
+type bibliographyRecord struct {
+    line     int
+    raw      string
+    book     BookView
+    keywords []string
+    notes    []string
+    err      error
+}
+
+// O(t) поля, не влезающие в запись базы, обрезаются с замечанием, как при импорте
+// MARC: название до 100 байт, автор и теги до 40. Повторы тегов убираются
+func newBibliographyRecord(line int, raw string, book BookView, keywords []string, err error) bibliographyRecord {
+    record := bibliographyRecord{line: line, raw: raw, book: book, err: err}
+    if err != nil {
+        return record
+    }
+
+    var cut bool
+    if record.book.Title, cut = fitBytes(book.Title, 100); cut {
+        record.notes = append(record.notes, "название обрезано до 100 байт")
+    }
+    if record.book.Author, cut = fitBytes(book.Author, 40); cut {
+        record.notes = append(record.notes, "имя автора обрезано до 40 байт")
+    }
+
+    seen := make(map[string]bool)
+    for _, keyword := range keywords {
+        tag, cut := fitBytes(normalizeTag(keyword), 40)
+        if cut {
+            record.notes = append(record.notes, fmt.Sprintf("тег %q обрезан до 40 байт", keyword))
+        }
+        if tag != "" && !seen[tag] {
+            seen[tag] = true
+            record.keywords = append(record.keywords, tag)
+        }
+    }
+    return record
+}
+
+// O(k) k - книг с тем же названием. В библиографии нет ID, поэтому книга
+// считается уже импортированной, если совпали название, автор (с учетом
+// псевдонимов) и год - так повторный импорт файла не плодит дубликаты
+func (db *Database) findBibliographyBook(book BookView) (int32, bool) {
+    author := db.CanonicalAuthor(book.Author)
+    for _, position := range db.titleIndex[book.Title] {
+        existing, err := db.readRecord(position)
+        if err != nil {
+            continue
+        }
+        view := existing.ToView()
+        if view.Author == author && view.Year == book.Year {
+            return view.ID, true
+        }
+    }
+    return 0, false
+}
+
+// O(t) книга из записи: новая добавляется с тегами (ID автоматически, тираж 0),
+// уже импортированная решается по opts.OnConflict. Перезапись заменяет теги
+// книги тегами из файла, объединение - дописывает недостающие
+func (db *Database) saveBibliographyRecord(record bibliographyRecord, opts ImportOptions, summary *ImportSummary) error {
+    id, exists := db.findBibliographyBook(record.book)
+    if exists {
+        switch opts.OnConflict {
+        case ConflictSkip:
+            summary.add(OutcomeSkipped)
+            return nil
+        case ConflictFail:
+            return &ConflictError{ID: id}
+        case ConflictOverwrite, ConflictMerge:
+            tags := record.keywords
+            if opts.OnConflict == ConflictMerge {
+                tags = append(db.GetTags(id), tags...)
+            }
+            before := db.GetTags(id)
+            if err := db.SetTags(id, tags); err != nil {
+                return fmt.Errorf("ошибка записи тегов: %v", err)
+            }
+            if slices.Equal(db.GetTags(id), before) {
+                summary.add(OutcomeUnchanged)
+            } else {
+                summary.add(OutcomeUpdated)
+            }
+            return nil
+        }
+    }
+
+    id, err := db.AddBook(record.book)
+    if err != nil {
+        // %w: errorColumn достает из ошибки *ValidationError для столбца отчета
+        return fmt.Errorf("ошибка добавления книги: %w", err)
+    }
+    for _, keyword := range record.keywords {
+        if err := db.AddTag(id, keyword); err != nil {
+            // книга без части тегов не остается: убираем ее целиком
+            if db.DeleteBook(id) == nil {
+                db.PurgeBook(id)
+            }
+            return fmt.Errorf("ошибка добавления тега: %v", err)
+        }
+    }
+    if exists {
+        summary.add(OutcomeRenumbered)
+    } else {
+        summary.add(OutcomeAdded)
+    }
+    return nil
+}
+
+// O(r * t) r - записей. Записи разобраны целиком заранее, так что синтаксическая
+// ошибка файла ничего не записывает; при ConflictFail уже импортированные книги
+// ищутся до первой записи. Ошибочные записи в мягком режиме попадают в отчет
+func (db *Database) importBibliography(records []bibliographyRecord, opts ImportOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    // записи без названия, автора или года отсеиваем до первой записи в базу
+    var valid []bibliographyRecord
+    for _, record := range records {
+        if record.err == nil {
+            valid = append(valid, record)
+            continue
+        }
+        if err := summary.rejectBook(opts, record.line, record.raw, record.err); err != nil {
+            return summary, err
+        }
+    }
+
+    if opts.OnConflict == ConflictFail {
+        // повтор внутри файла после записи первой копии тоже стал бы конфликтом
+        seen := make(map[BookView]int)
+        for _, record := range valid {
+            key := BookView{Title: record.book.Title, Author: db.CanonicalAuthor(record.book.Author), Year: record.book.Year}
+            if id, exists := db.findBibliographyBook(record.book); exists {
+                return summary, &ImportError{Line: record.line, Reason: fmt.Sprintf("книга уже есть в базе под ID %d", id),
+                    Raw: record.raw, err: &ConflictError{ID: id}}
+            }
+            if line, repeated := seen[key]; repeated {
+                return summary, &ImportError{Line: record.line, Reason: fmt.Sprintf("книга повторяет запись со строки %d", line),
+                    Raw: record.raw, err: &ConflictError{}}
+            }
+            seen[key] = record.line
+        }
+    }
+
+    defer db.auditAs(AuditImport)()
+    for _, record := range valid {
+        if err := db.saveBibliographyRecord(record, opts, &summary); err != nil {
+            if err := summary.rejectBook(opts, record.line, record.raw, err); err != nil {
+                return summary, err
+            }
+            continue
+        }
+        for _, note := range record.notes {
+            summary.Notes = append(summary.Notes, ImportError{Line: record.line, Reason: note, Raw: record.raw})
+        }
+    }
+    sort.SliceStable(summary.Errors, func(i, j int) bool {
+        return summary.Errors[i].Line < summary.Errors[j].Line
+    })
+    return summary, nil
+}
+
+// O(n) файл библиографии в UTF-8 без BOM
+func readBibliography(filename string) (string, error) {
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return "", fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    data = bytes.TrimPrefix(data, utf8BOM)
+    if !utf8.Valid(data) {
+        return "", fmt.Errorf("файл не в кодировке UTF-8")
+    }
+    return string(data), nil
+}
+
+// O(n) записи любого типа (@book, @article...) добавляются новыми книгами:
+// author (или editor), title, year (или date), keywords - в теги.
+// Книга, которая уже есть в базе, решается по opts.OnConflict
+func (db *Database) ImportFromBibTeX(filename string, opts ImportOptions) (ImportSummary, error) {
+    data, err := readBibliography(filename)
+    if err != nil {
+        return ImportSummary{}, err
+    }
+
+    parser := &bibtexParser{data: data, macros: map[string]string{
+        "jan": "1", "feb": "2", "mar": "3", "apr": "4", "may": "5", "jun": "6",
+        "jul": "7", "aug": "8", "sep": "9", "oct": "10", "nov": "11", "dec": "12",
+    }}
+
+    var records []bibliographyRecord
+    for {
+        entry, err := parser.next()
+        if err != nil {
+            return ImportSummary{}, fmt.Errorf("ошибка разбора BibTeX: %v", err)
+        }
+        if entry == nil {
+            break
+        }
+
+        authors := entry.fields["author"]
+        if authors == "" {
+            authors = entry.fields["editor"]
+        }
+        year := entry.fields["year"]
+        if year == "" {
+            year = entry.fields["date"]
+        }
+        book, err := bibliographyBook(decodeBibTeX(entry.fields["title"]),
+            decodeBibTeX(splitBibTeXAuthors(authors)[0]), decodeBibTeX(year))
+        records = append(records, newBibliographyRecord(entry.line, fmt.Sprintf("@%s{%s}", entry.kind, entry.key),
+            book, splitKeywords(decodeBibTeX(entry.fields["keywords"])), err))
+    }
+    return db.importBibliography(records, opts)
+}
\ No newline at end of file
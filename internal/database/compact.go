@@ -0,0 +1,71 @@
+package database
+
+import (
+    "fmt"
+    "sort"
+)
+
+type CompactStats struct {
+    ReclaimedSlots int // освобожденные слоты во всех файлах
+    RemovedCovers  int // удаленные картинки без ссылок
+}
+
+// O(n log n) из-за сортировки позиций, само переписывание O(n)
+// Выкидывает свободные слоты из books.db и вспомогательных файлов.
+// Книги из корзины переносятся как живые: освобождаются только окончательно удаленные
+// и удаляет обложки, на которые больше не ссылается ни одна книга
+func (db *Database) Compact() (CompactStats, error) {
+    var stats CompactStats
+
+    if len(db.freeList) > 0 {
+        positions := make([]int64, 0, len(db.idIndex)+len(db.trashIndex))
+        for _, position := range db.idIndex {
+            positions = append(positions, position)
+        }
+        for _, position := range db.trashIndex {
+            positions = append(positions, position)
+        }
+        // сохраняем порядок записей в файле
+        sort.Slice(positions, func(i, j int) bool {
+            return positions[i] < positions[j]
+        })
+
+        books := make([]*Book, 0, len(positions))
+        for _, position := range positions {
+            book, err := db.readRecord(position)
+            if err != nil {
+                return stats, fmt.Errorf("ошибка чтения записи: %v", err)
+            }
+            books = append(books, book)
+        }
+
+        stats.ReclaimedSlots += len(db.freeList)
+        if err := db.rewriteBooksFile(books); err != nil {
+            return stats, err
+        }
+
+        db.resetIndexes()
+        if err := db.rebuildIndexes(); err != nil {
+            return stats, fmt.Errorf("ошибка восстановления индексов: %v", err)
+        }
+    }
+
+    for _, s := range db.sidecars() {
+        reclaimed, err := (*s.file).compact()
+        if err != nil {
+            return stats, fmt.Errorf("ошибка сжатия %s: %v", s.name, err)
+        }
+        stats.ReclaimedSlots += reclaimed
+        if err := s.load(); err != nil {
+            return stats, err
+        }
+    }
+
+    removed, err := db.collectCoverGarbage()
+    stats.RemovedCovers = removed
+    if err != nil {
+        return stats, err
+    }
+
+    return stats, nil
+}
\ No newline at end of file
@@ -0,0 +1,233 @@
+package database
+
+import (
+    "bytes"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "fmt"
+    "image"
+    _ "image/jpeg"
+    _ "image/png"
+    "os"
+    "path/filepath"
+)
+
+// Обложки лежат рядом с books.db в папке covers/, имя файла - SHA-256 содержимого,
+// поэтому одинаковые картинки хранятся один раз.
+// Ссылка книги на обложку - запись в covers.db:
+// ID   int32    - 4 байта
+// Hash [32]byte - 32 байта
+const (
+    coverRefSize = 36
+    maxCoverSize = 10 << 20
+)
+
+// O(1)
+func coverRefToBytes(id int32, hash []byte) []byte {
+    buf := make([]byte, coverRefSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(id))
+    copy(buf[4:36], hash)
+    return buf
+}
+
+// O(1)
+func bytesToCoverRef(data []byte) (int32, string) {
+    return int32(binary.LittleEndian.Uint32(data[0:4])), hex.EncodeToString(data[4:36])
+}
+
+// O(n) n - количество обложек
+func (db *Database) loadCovers() error {
+    db.coverIndex = make(map[int32]string)
+    db.coverPositions = make(map[int32]int64)
+    return db.covers.scan(func(position int64, data []byte) {
+        id, hash := bytesToCoverRef(data)
+        db.coverIndex[id] = hash
+        db.coverPositions[id] = position
+    })
+}
+
+// O(1)
+func (db *Database) blobPath(hash string) string {
+    return filepath.Join(db.blobDir, hash)
+}
+
+// O(m) m - размер картинки. Принимаются PNG и JPEG
+func (db *Database) SetCover(id int32, data []byte) error {
+    if _, exists := db.idIndex[id]; !exists {
+        return fmt.Errorf("книга с ID %d не найдена", id)
+    }
+    if len(data) > maxCoverSize {
+        return fmt.Errorf("обложка больше %d МБ", maxCoverSize>>20)
+    }
+    if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+        return fmt.Errorf("обложка должна быть изображением PNG или JPEG: %v", err)
+    }
+
+    sum := sha256.Sum256(data)
+    hash := hex.EncodeToString(sum[:])
+
+    path := db.blobPath(hash)
+    if _, err := os.Stat(path); os.IsNotExist(err) {
+        // пишем во временный файл, чтобы не оставить полкартинки под правильным именем
+        if err := os.WriteFile(path+".tmp", data, 0666); err != nil {
+            return fmt.Errorf("ошибка записи обложки: %v", err)
+        }
+        if err := os.Rename(path+".tmp", path); err != nil {
+            return fmt.Errorf("ошибка записи обложки: %v", err)
+        }
+    }
+
+    ref := coverRefToBytes(id, sum[:])
+    if position, exists := db.coverPositions[id]; exists {
+        if err := db.covers.write(position, ref); err != nil {
+            return fmt.Errorf("ошибка записи ссылки на обложку: %v", err)
+        }
+    } else {
+        position, err := db.covers.insert(ref)
+        if err != nil {
+            return fmt.Errorf("ошибка записи ссылки на обложку: %v", err)
+        }
+        db.coverPositions[id] = position
+    }
+
+    db.coverIndex[id] = hash
+    return nil
+}
+
+// O(m) m - размер картинки
+func (db *Database) GetCover(id int32) ([]byte, error) {
+    hash, exists := db.coverIndex[id]
+    if !exists {
+        return nil, fmt.Errorf("у книги с ID %d нет обложки", id)
+    }
+
+    data, err := os.ReadFile(db.blobPath(hash))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения обложки: %v", err)
+    }
+    return data, nil
+}
+
+// O(1)
+func (db *Database) HasCover(id int32) bool {
+    _, exists := db.coverIndex[id]
+    return exists
+}
+
+// O(1) SHA-256 картинки обложки, "" - обложки нет. Меняется вместе с картинкой,
+// поэтому подходит как ключ кэша миниатюр
+func (db *Database) CoverHash(id int32) string {
+    return db.coverIndex[id]
+}
+
+// O(m + w*h) m - размер картинки. Уменьшенная обложка, вписанная в w x h
+// с сохранением пропорций; каждый пиксель - среднее по сетке 4x4 точек исходника
+func (db *Database) CoverThumbnail(id int32, width, height int) (image.Image, error) {
+    data, err := db.GetCover(id)
+    if err != nil {
+        return nil, err
+    }
+    source, _, err := image.Decode(bytes.NewReader(data))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения обложки: %v", err)
+    }
+
+    bounds := source.Bounds()
+    scale := min(float64(width)/float64(bounds.Dx()), float64(height)/float64(bounds.Dy()), 1)
+    width = max(1, int(float64(bounds.Dx())*scale))
+    height = max(1, int(float64(bounds.Dy())*scale))
+
+    const samples = 4
+    thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            var r, g, b, a uint32
+            for sy := 0; sy < samples; sy++ {
+                for sx := 0; sx < samples; sx++ {
+                    px := bounds.Min.X + int((float64(x)+(float64(sx)+0.5)/samples)/scale)
+                    py := bounds.Min.Y + int((float64(y)+(float64(sy)+0.5)/samples)/scale)
+                    cr, cg, cb, ca := source.At(min(px, bounds.Max.X-1), min(py, bounds.Max.Y-1)).RGBA()
+                    r, g, b, a = r+cr, g+cg, b+cb, a+ca
+                }
+            }
+            const n = samples * samples
+            offset := thumbnail.PixOffset(x, y)
+            thumbnail.Pix[offset] = uint8(r / n >> 8)
+            thumbnail.Pix[offset+1] = uint8(g / n >> 8)
+            thumbnail.Pix[offset+2] = uint8(b / n >> 8)
+            thumbnail.Pix[offset+3] = uint8(a / n >> 8)
+        }
+    }
+    return thumbnail, nil
+}
+
+// O(1) удаляется только ссылка, сам файл уберет сборка мусора в Compact,
+// потому что та же картинка может быть обложкой другой книги
+func (db *Database) DeleteCover(id int32) error {
+    position, exists := db.coverPositions[id]
+    if !exists {
+        return fmt.Errorf("у книги с ID %d нет обложки", id)
+    }
+    if err := db.covers.remove(position); err != nil {
+        return fmt.Errorf("ошибка удаления ссылки на обложку: %v", err)
+    }
+    delete(db.coverPositions, id)
+    delete(db.coverIndex, id)
+    return nil
+}
+
+// O(1) при смене ID ссылка на обложку переезжает вместе с книгой.
+// Возвращает функцию отката переезда
+func (db *Database) moveCover(oldID, newID int32) (func() error, error) {
+    hash, exists := db.coverIndex[oldID]
+    if !exists {
+        return func() error { return nil }, nil
+    }
+    position := db.coverPositions[oldID]
+
+    sum, err := hex.DecodeString(hash)
+    if err != nil {
+        return nil, err
+    }
+    rewrite := []recordRewrite{{position: position, old: coverRefToBytes(oldID, sum), new: coverRefToBytes(newID, sum)}}
+    if err := db.covers.rewriteAll(rewrite); err != nil {
+        return nil, fmt.Errorf("ошибка записи ссылки на обложку: %v", err)
+    }
+
+    moveKey(db.coverIndex, oldID, newID)
+    moveKey(db.coverPositions, oldID, newID)
+    return func() error {
+        if err := db.covers.revertAll(rewrite); err != nil {
+            return err
+        }
+        moveKey(db.coverIndex, newID, oldID)
+        moveKey(db.coverPositions, newID, oldID)
+        return nil
+    }, nil
+}
+
+// O(b + n) b - файлов в covers/, n - ссылок. Удаляет картинки, на которые никто не ссылается
+func (db *Database) collectCoverGarbage() (int, error) {
+    used := make(map[string]bool, len(db.coverIndex))
+    for _, hash := range db.coverIndex {
+        used[hash] = true
+    }
+
+    entries, err := os.ReadDir(db.blobDir)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка чтения папки обложек: %v", err)
+    }
+
+    removed := 0
+    for _, entry := range entries {
+        if entry.IsDir() || used[entry.Name()] {
+            continue
+        }
+        if err := os.Remove(db.blobPath(entry.Name())); err != nil {
+            return removed, fmt.Errorf("ошибка удаления обложки: %v", err)
+        }
+        removed++
+    }
+    return removed, nil
+}
\ No newline at end of file
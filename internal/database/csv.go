@@ -0,0 +1,232 @@
+package database
+
+import (
+    "bytes"
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+    "unicode/utf8"
+
+    "golang.org/x/text/encoding/charmap"
+)
+
+// Кодировка CSV-файла
+type CSVEncoding int
+
+const (
+    // при импорте: BOM или корректный UTF-8 - UTF-8, иначе Windows-1251;
+    // при экспорте - UTF-8
+    CSVAuto CSVEncoding = iota
+    CSVUTF8
+    CSVWindows1251
+)
+
+// Настройки CSV (RFC 4180: поля с разделителем, кавычками
+// или переводом строки берутся в кавычки)
+type CSVOptions struct {
+    Delimiter rune        // 0 - запятая
+    Encoding  CSVEncoding
+    BOM       bool        // при экспорте в UTF-8 писать BOM (нужен Excel, чтобы узнать кодировку)
+    ImportOptions
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+var csvHeader = []string{"ID", "Название", "Автор", "Год", "Тираж"}
+
+// O(1)
+func (o CSVOptions) delimiter() (rune, error) {
+    if o.Delimiter == 0 {
+        return ',', nil
+    }
+    if o.Delimiter == '"' || o.Delimiter == '\r' || o.Delimiter == '\n' || !utf8.ValidRune(o.Delimiter) {
+        return 0, fmt.Errorf("недопустимый разделитель: %q", o.Delimiter)
+    }
+    return o.Delimiter, nil
+}
+
+// O(n) n - размер файла, приводим содержимое к UTF-8
+func decodeCSV(data []byte, encoding CSVEncoding) ([]byte, error) {
+    if bytes.HasPrefix(data, utf8BOM) {
+        return data[len(utf8BOM):], nil
+    }
+    if encoding == CSVAuto {
+        encoding = CSVUTF8
+        if !utf8.Valid(data) {
+            encoding = CSVWindows1251
+        }
+    }
+
+    if encoding == CSVWindows1251 {
+        decoded, err := charmap.Windows1251.NewDecoder().Bytes(data)
+        if err != nil {
+            return nil, fmt.Errorf("ошибка перекодировки из Windows-1251: %v", err)
+        }
+        return decoded, nil
+    }
+    if !utf8.Valid(data) {
+        return nil, fmt.Errorf("файл не в кодировке UTF-8")
+    }
+    return data, nil
+}
+
+// O(n)
+func (db *Database) ExportToCSV(filename string, opts CSVOptions) error {
+    delimiter, err := opts.delimiter()
+    if err != nil {
+        return err
+    }
+    books, err := db.GetAllBooks()
+    if err != nil {
+        return fmt.Errorf("ошибка получения книг: %v", err)
+    }
+
+    var buf bytes.Buffer
+    writer := csv.NewWriter(&buf)
+    writer.Comma = delimiter
+    // RFC 4180 требует CRLF
+    writer.UseCRLF = true
+
+    if err := writer.Write(csvHeader); err != nil {
+        return fmt.Errorf("ошибка записи заголовка: %v", err)
+    }
+    for _, book := range books {
+        record := []string{
+            fmt.Sprintf("%d", book.ID), book.Title, book.Author,
+            fmt.Sprintf("%d", book.Year), fmt.Sprintf("%d", book.Copies),
+        }
+        if err := writer.Write(record); err != nil {
+            return fmt.Errorf("ошибка записи данных: %v", err)
+        }
+    }
+    writer.Flush()
+    if err := writer.Error(); err != nil {
+        return fmt.Errorf("ошибка записи данных: %v", err)
+    }
+
+    data := buf.Bytes()
+    if opts.Encoding == CSVWindows1251 {
+        data, err = charmap.Windows1251.NewEncoder().Bytes(data)
+        if err != nil {
+            return fmt.Errorf("в данных есть символы, которых нет в Windows-1251: %v", err)
+        }
+    } else if opts.BOM {
+        data = append(append([]byte{}, utf8BOM...), data...)
+    }
+
+    if err := os.WriteFile(filename, data, 0644); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(m) заголовок узнается по названиям столбцов, а не по нечисловому ID:
+// хотя бы 3 из 5 ячеек должны называть свой столбец (см. excelHeaderAliases).
+// Иначе строка - данные, и неверный ID в ней - ошибка строки
+func isCSVHeader(record []string) bool {
+    named := 0
+    for i, field := range BookFields {
+        if i >= len(record) {
+            break
+        }
+        header := normalizeExcelHeader(record[i])
+        for _, alias := range excelHeaderAliases[field] {
+            if header == alias {
+                named++
+                break
+            }
+        }
+    }
+    return named >= 3
+}
+
+// O(n) строки CSV по очереди передаются в fn. Первая строка пропускается,
+// если это заголовок (см. isCSVHeader). Ошибка кавычек приходит в row.Err
+// как *csv.ParseError, после нее чтение продолжается со следующей записи
+func eachCSVRow(data []byte, delimiter rune, fn func(row importRow) error) error {
+    reader := csv.NewReader(bytes.NewReader(data))
+    reader.Comma = delimiter
+    reader.FieldsPerRecord = -1
+
+    first := true
+    for {
+        record, err := reader.Read()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            var parseErr *csv.ParseError
+            if !errors.As(err, &parseErr) {
+                return fmt.Errorf("ошибка разбора CSV: %v", err)
+            }
+            if err := fn(importRow{Line: parseErr.StartLine, Err: err}); err != nil {
+                return err
+            }
+            continue
+        }
+        line, _ := reader.FieldPos(0)
+
+        if first {
+            first = false
+            if isCSVHeader(record) {
+                continue
+            }
+        }
+        if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+            continue
+        }
+        book, column, err := parseBookFields(record)
+        if err := fn(importRow{Line: line, Raw: strings.Join(record, string(delimiter)), Book: book, Column: column, Err: err}); err != nil {
+            return err
+        }
+    }
+}
+
+// O(n) книги добавляются, конфликты ID решаются по opts.OnConflict.
+// Первая строка пропускается, если это заголовок с названиями столбцов.
+// В мягком режиме ошибочные строки (и ошибки кавычек) не прерывают импорт
+func (db *Database) ImportFromCSV(filename string, opts CSVOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    delimiter, err := opts.delimiter()
+    if err != nil {
+        return summary, err
+    }
+    data, err := os.ReadFile(filename)
+    if err != nil {
+        return summary, fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    data, err = decodeCSV(data, opts.Encoding)
+    if err != nil {
+        return summary, err
+    }
+
+    if opts.OnConflict == ConflictFail {
+        if err := eachCSVRow(data, delimiter, db.newConflictScan().checkRow); err != nil {
+            return summary, err
+        }
+    }
+    defer db.auditAs(AuditImport)()
+
+    err = eachCSVRow(data, delimiter, func(row importRow) error {
+        var parseErr *csv.ParseError
+        if errors.As(row.Err, &parseErr) {
+            importErr := ImportError{Line: row.Line, Reason: parseErr.Err.Error(), err: row.Err}
+            if err := summary.reject(opts.ImportOptions, importErr); err != nil {
+                return fmt.Errorf("ошибка разбора CSV: %v", parseErr)
+            }
+            return nil
+        }
+        if row.Err != nil {
+            return summary.reject(opts.ImportOptions, row.importError())
+        }
+
+        if _, err := db.importBook(row.Book, opts.ImportOptions, &summary); err != nil {
+            return summary.rejectBook(opts.ImportOptions, row.Line, row.Raw, err)
+        }
+        return nil
+    })
+    return summary, err
+}
\ No newline at end of file
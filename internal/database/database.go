@@ -1,14 +1,13 @@
 package database
 
 import (
-    "bufio"
     "fmt"
     "os"
+    "path/filepath"
     "sort"
     "strconv"
     "strings"
-
-    "github.com/xuri/excelize/v2"
+    "time"
     // "unicode/utf8"
 )
 
@@ -24,11 +23,83 @@ type Database struct {
     yearIndex   map[int32][]int64
     
     freeList []int64
+
+    // последовательность для автоматических ID, хранится в заголовке файла
+    nextID int32
+
+    // проверка данных перед AddBook/UpdateBook
+    validator Validator
+
+    // псевдонимы авторов (authors.db рядом с books.db)
+    authors        *recordFile
+    aliasIndex     map[string]string
+    aliasPositions map[string]int64
+
+    // обложки: картинки в covers/, ссылки на них в covers.db
+    blobDir        string
+    covers         *recordFile
+    coverIndex     map[int32]string
+    coverPositions map[int32]int64
+
+    // ISBN изданий (isbn.db)
+    isbns         *recordFile
+    isbnIndex     map[int32]string
+    isbnPositions map[int32]int64
+
+    // теги: пары книга-тег в tags.db
+    tags         *recordFile
+    tagIndex     map[string][]int32
+    bookTags     map[int32][]string
+    tagPositions map[tagKey]int64
+
+    // читатели и выдачи (members.db, loans.db)
+    members        *recordFile
+    memberIndex    map[int32]int64
+    nextMemberID   int32
+    loans          *recordFile
+    loanIndex      map[int32]int64
+    activeByBook   map[int32]int
+    activeByMember map[int32]int
+    nextLoanID     int32
+
+    // штрафы: начисленные при возврате (fines.db) и правила (fine_policy.db)
+    fines          *recordFile
+    fineIndex      map[int32]int64
+    finePolicy     FinePolicy
+    finePolicyFile *recordFile
+
+    // очереди броней (holds.db)
+    holds          *recordFile
+    holdIndex      map[int32]int64
+    holdQueues     map[int32][]int32
+    readyByBook    map[int32]int
+    nextHoldID     int32
+    holdPickupDays int
+    holdPolicyFile *recordFile
+
+    // физические экземпляры (items.db), ключ - штрихкод
+    items       *recordFile
+    itemIndex   map[string]int64
+    bookItems   map[int32][]string
+    nextBarcode int64
+
+    // корзина (trash.db): книга -> ее слот в books.db
+    trash              *recordFile
+    trashIndex         map[int32]int64
+    trashPositions     map[int32]int64
+    trashRetentionDays int
+    trashPolicyFile    *recordFile
+
+    // журнал изменений (audit.log)
+    audit         *recordFile
+    actor         string
+    auditOverride AuditOp
 }
 
 // O(n) просто считываем весь файл, зная расположение нужных нам полей
 func OpenDatabase(filePath string) (*Database, error) {
-    os.MkdirAll("data", 0755)
+    dir := filepath.Dir(filePath)
+    os.MkdirAll(dir, 0755)
     
     file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
     if err != nil {
@@ -44,26 +115,119 @@ func OpenDatabase(filePath string) (*Database, error) {
         authorIndex: make(map[string][]int64),
         yearIndex:  make(map[int32][]int64),
         freeList:   []int64{},
+        blobDir:    filepath.Join(dir, "covers"),
+        validator:  DefaultValidator(),
+        finePolicy: DefaultFinePolicy(),
+        holdPickupDays: 3,
+        trashRetentionDays: 30,
+        actor:      defaultActor(),
+    }
+    
+    if err := db.loadHeader(); err != nil {
+        db.file.Close()
+        return nil, fmt.Errorf("ошибка чтения заголовка: %v", err)
     }
     
     if err := db.rebuildIndexes(); err != nil {
-        file.Close()
+        db.file.Close()
         return nil, fmt.Errorf("ошибка восстановления индексов: %v", err)
     }
+
+    if err := os.MkdirAll(db.blobDir, 0755); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("ошибка создания папки обложек: %v", err)
+    }
+
+    if err := db.openSidecars(dir); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    if err := db.openAudit(dir); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    if err := db.openFinePolicy(dir); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    if err := db.openTrashPolicy(dir); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    if err := db.openHoldPolicy(dir); err != nil {
+        db.Close()
+        return nil, err
+    }
+
+    if _, err := db.ReconcileCopies(); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("ошибка сверки тиража с экземплярами: %v", err)
+    }
+
+    if _, err := db.PurgeExpiredTrash(time.Now()); err != nil {
+        db.Close()
+        return nil, fmt.Errorf("ошибка очистки корзины: %v", err)
+    }
     
     return db, nil
 }
 
 // O(1)
 func (db *Database) Close() error {
+    for _, s := range db.sidecars() {
+        if *s.file != nil {
+            (*s.file).Close()
+        }
+    }
+    if db.audit != nil {
+        db.audit.Close()
+    }
+    if db.finePolicyFile != nil {
+        db.finePolicyFile.Close()
+    }
+    if db.trashPolicyFile != nil {
+        db.trashPolicyFile.Close()
+    }
+    if db.holdPolicyFile != nil {
+        db.holdPolicyFile.Close()
+    }
     if db.file != nil {
         return db.file.Close()
     }
     return nil
 }
 
-// O(1), константы небольшие
+// O(n) n - книг вместе с корзиной. Стирает книги, корзину и данные книг
+// (теги, обложки, экземпляры); читатели, история выдач, штрафы, брони и псевдонимы
+// авторов остаются. Пока есть невозвращенные книги или активные брони, очищать нельзя.
+// Последовательность ID не сбрасывается, чтобы старые номера не выдавались повторно
 func (db *Database) ClearDatabase() error {
+    if active := len(db.activeByBook); active > 0 {
+        return fmt.Errorf("нельзя очистить базу: есть невозвращенные книги (%d)", active)
+    }
+    for bookID := range db.holdQueues {
+        if holds := db.ActiveHoldsOfBook(bookID); holds > 0 {
+            return fmt.Errorf("нельзя очистить базу: у книги с ID %d активных броней: %d", bookID, holds)
+        }
+    }
+
+    // стертые книги попадают в журнал, чтобы их можно было найти и восстановить
+    cleared, err := db.GetAllBooks()
+    if err != nil {
+        return err
+    }
+    trash, err := db.GetTrash()
+    if err != nil {
+        return err
+    }
+    for _, entry := range trash {
+        cleared = append(cleared, entry.Book)
+    }
+
     if err := db.file.Truncate(0); err != nil {
         return fmt.Errorf("ошибка очистки файла: %v", err)
     }
@@ -71,13 +235,31 @@ func (db *Database) ClearDatabase() error {
     if _, err := db.file.Seek(0, 0); err != nil {
         return fmt.Errorf("ошибка перемещения в начало файла: %v", err)
     }
+
+    if err := db.writeHeader(); err != nil {
+        return err
+    }
     
-    db.idIndex = make(map[int32]int64)
-    db.titleIndex = make(map[string][]int64)
-    db.authorIndex = make(map[string][]int64)
-    db.yearIndex = make(map[int32][]int64)
-    db.freeList = []int64{}
-    
+    db.resetIndexes()
+
+    // сами картинки обложек удалит Compact
+    for _, s := range db.sidecars() {
+        if !s.bookData {
+            continue
+        }
+        if err := (*s.file).clear(); err != nil {
+            return fmt.Errorf("ошибка очистки %s: %v", s.name, err)
+        }
+        if err := s.load(); err != nil {
+            return err
+        }
+    }
+
+    for i := range cleared {
+        if err := db.logAudit(AuditClear, cleared[i].ID, &cleared[i], nil); err != nil {
+            return err
+        }
+    }
     return nil
 }
 
@@ -103,33 +285,58 @@ func (db *Database) GetAllBooks() ([]BookView, error) {
 }
 
 // O(1) в среднем
-func (db *Database) AddBook(bookView BookView) error {
+// ID 0 означает "выдать следующий по последовательности", возвращается назначенный ID
+func (db *Database) AddBook(bookView BookView) (int32, error) {
+    bookView.Author = db.CanonicalAuthor(bookView.Author)
+    if err := db.validate(bookView); err != nil {
+        return 0, err
+    }
     book := bookView.ToBook()
     
     // благодаря мапам все быренько (проверяем на существование по айди в мапе, потом запись в мапу и обновление индексов)
     // Но, в случае коллизий О(n)
     if _, exists := db.idIndex[book.ID]; exists {
-        return fmt.Errorf("книга с ID %d уже существует", book.ID)
+        return 0, fmt.Errorf("книга с ID %d уже существует", book.ID)
+    }
+    if db.InTrash(book.ID) {
+        return 0, fmt.Errorf("книга с ID %d лежит в корзине", book.ID)
+    }
+
+    if book.ID < 0 {
+        return 0, fmt.Errorf("ID книги не может быть отрицательным")
     }
     
     var position int64
     if len(db.freeList) > 0 {
         position = db.freeList[len(db.freeList)-1]
-        db.freeList = db.freeList[:len(db.freeList)-1]
     } else {
         stat, err := db.file.Stat()
         if err != nil {
-            return err
+            return 0, err
         }
         position = stat.Size()
     }
+
+    if book.ID == 0 {
+        id, err := db.allocateID()
+        if err != nil {
+            return 0, err
+        }
+        book.ID = id
+    } else if err := db.advanceSequence(book.ID); err != nil {
+        return 0, err
+    }
     
     if err := db.writeRecord(book, position); err != nil {
-        return err
+        return 0, err
+    }
+
+    if len(db.freeList) > 0 && db.freeList[len(db.freeList)-1] == position {
+        db.freeList = db.freeList[:len(db.freeList)-1]
     }
     
     db.updateIndexes(book, position)
-    return nil
+    return book.ID, db.logAudit(AuditAdd, book.ID, nil, viewRef(book))
 }
 
 // O(1)в среднем, но из-за коллизий худший - O(n)
@@ -138,12 +345,31 @@ func (db *Database) UpdateBook(bookView BookView) error {
     if !exists {
         return fmt.Errorf("книга с ID %d не найдена", bookView.ID)
     }
+
+    bookView.Author = db.CanonicalAuthor(bookView.Author)
+    // у книги с учтенными экземплярами тираж считается по ним
+    if db.HasItems(bookView.ID) {
+        count, err := db.countedItems(bookView.ID)
+        if err != nil {
+            return err
+        }
+        bookView.Copies = count
+    }
+    if err := db.validate(bookView); err != nil {
+        return err
+    }
+
+    if active := int32(db.activeByBook[bookView.ID]); bookView.Copies < active {
+        return fmt.Errorf("тираж не может быть меньше числа выданных экземпляров: %d", active)
+    }
+
     
     // O(1)
     oldBook, err := db.readRecord(position)
     if err != nil {
         return err
     }
+    oldCopies := oldBook.Copies
     
     // O(1) в среднем
     db.removeFromIndexes(oldBook, position)
@@ -156,25 +382,166 @@ func (db *Database) UpdateBook(bookView BookView) error {
     
     // O(1) в среднем
     db.updateIndexes(newBook, position)
+
+    if err := db.logAudit(AuditUpdate, newBook.ID, viewRef(oldBook), viewRef(newBook)); err != nil {
+        return err
+    }
+
+    // прибавились экземпляры - отдаем их очереди броней
+    if bookView.Copies > oldCopies {
+        return db.promoteHolds(bookView.ID)
+    }
     return nil
 }
 
 // O(1) в среднем, O(n) - худший
+// Меняет первичный ключ книги. Запись остается в своем слоте, поэтому
+// это одна перезапись, а индексы перестраиваются как при UpdateBook
+func (db *Database) ChangeID(oldID, newID int32) error {
+    position, exists := db.idIndex[oldID]
+    if !exists {
+        return fmt.Errorf("книга с ID %d не найдена", oldID)
+    }
+    book, err := db.readRecord(position)
+    if err != nil {
+        return err
+    }
+    view := book.ToView()
+    view.ID = newID
+    return db.replaceBook(oldID, view, false)
+}
+
+// O(1) в среднем, O(n) - худший
+// Изменение книги oldID вместе со сменой ее ID на bookView.ID: одна
+// перезапись и одна запись журнала. Если что-то не удалось, база
+// остается такой, какой была до вызова
+func (db *Database) ReplaceBook(oldID int32, bookView BookView) error {
+    if oldID == bookView.ID {
+        return db.UpdateBook(bookView)
+    }
+    return db.replaceBook(oldID, bookView, true)
+}
+
+// O(n) check - проверять ли поля книги как в UpdateBook (при чистой смене ID
+// поля не меняются, и старую книгу не бракуем из-за новых правил)
+func (db *Database) replaceBook(oldID int32, bookView BookView, check bool) error {
+    newID := bookView.ID
+    if oldID == newID {
+        return nil
+    }
+
+    // все проверки - до первой записи
+    position, exists := db.idIndex[oldID]
+    if !exists {
+        return fmt.Errorf("книга с ID %d не найдена", oldID)
+    }
+    if newID <= 0 {
+        return fmt.Errorf("ID должен быть положительным числом")
+    }
+    if _, exists := db.idIndex[newID]; exists {
+        return fmt.Errorf("книга с ID %d уже существует", newID)
+    }
+    if db.InTrash(newID) {
+        return fmt.Errorf("книга с ID %d лежит в корзине", newID)
+    }
+
+    oldBook, err := db.readRecord(position)
+    if err != nil {
+        return err
+    }
+    if check {
+        bookView.Author = db.CanonicalAuthor(bookView.Author)
+        if db.HasItems(oldID) {
+            count, err := db.countedItems(oldID)
+            if err != nil {
+                return err
+            }
+            bookView.Copies = count
+        }
+        if err := db.validate(bookView); err != nil {
+            return err
+        }
+        if active := int32(db.activeByBook[oldID]); bookView.Copies < active {
+            return fmt.Errorf("тираж не может быть меньше числа выданных экземпляров: %d", active)
+        }
+    }
+    newBook := bookView.ToBook()
+
+    if err := db.advanceSequence(newID); err != nil {
+        return err
+    }
+
+    // данные книги в других хранилищах переезжают на новый ID; при ошибке
+    // уже переехавшие возвращаются обратно
+    var undos []func() error
+    rollback := func() {
+        for i := len(undos) - 1; i >= 0; i-- {
+            undos[i]()
+        }
+    }
+    for _, move := range []func(oldID, newID int32) (func() error, error){
+        db.moveCover, db.moveISBN, db.moveTags, db.moveLoans, db.moveHolds, db.moveItems,
+    } {
+        undo, err := move(oldID, newID)
+        if err != nil {
+            rollback()
+            return err
+        }
+        undos = append(undos, undo)
+    }
+
+    if err := db.writeRecord(newBook, position); err != nil {
+        rollback()
+        return err
+    }
+    if err := db.logAudit(AuditChangeID, newID, viewRef(oldBook), viewRef(newBook)); err != nil {
+        db.writeRecord(oldBook, position)
+        rollback()
+        return err
+    }
+
+    db.removeFromIndexes(oldBook, position)
+    db.updateIndexes(newBook, position)
+
+    // прибавились экземпляры - отдаем их очереди броней
+    if newBook.Copies > oldBook.Copies {
+        return db.promoteHolds(newID)
+    }
+    return nil
+}
+
+// O(1) в среднем, O(n) - худший
+// Книга уходит в корзину вместе с тегами, обложкой и экземплярами,
+// окончательно ее удаляет PurgeBook или автоочистка корзины
 func (db *Database) DeleteBook(id int32) error {
     // в худшем O(n), среднее O(1)
+    return db.DeleteBookAt(id, time.Now())
+}
+
+// O(1) в среднем, книга уходит в корзину с заданной датой удаления -
+// так отмена очистки возвращает корзину с прежними сроками хранения
+func (db *Database) DeleteBookAt(id int32, at time.Time) error {
     position, exists := db.idIndex[id]
     if !exists {
         return fmt.Errorf("книга с ID %d не найдена", id)
     }
+
+    if active := db.activeByBook[id]; active > 0 {
+        return fmt.Errorf("книгу с ID %d нельзя удалить: не возвращено экземпляров: %d", id, active)
+    }
+    if holds := db.ActiveHoldsOfBook(id); holds > 0 {
+        return fmt.Errorf("книгу с ID %d нельзя удалить: активных броней: %d", id, holds)
+    }
     
     book, err := db.readRecord(position)
     if err != nil {
         return err
     }
     
-    db.removeFromIndexes(book, position)
-    db.freeList = append(db.freeList, position)
-    return nil
+    if err := db.moveToTrash(book, position, at); err != nil {
+        return err
+    }
+    return db.logAudit(AuditDelete, id, viewRef(book), nil)
 }
 
 // есть все ключи в одной корзине O(n), O(1) в среднем
@@ -219,9 +586,8 @@ func (db *Database) FindBooks(field, value string) ([]BookView, error) {
             }
             
         case "Автор":
-            // O(m)
-            bookAuthor := strings.ToLower(book.Author)
-            if strings.Contains(bookAuthor, searchValue) {
+            // O(m), плюс псевдонимы автора
+            if db.authorMatches(book.Author, searchValue) {
                 match = true
             }
             
@@ -257,110 +623,58 @@ func (db *Database) ExportToTxt(filename string) error {
         return fmt.Errorf("ошибка получения книг: %v", err)
     }
 
-    file, err := os.Create(filename)
-    if err != nil {
-        return fmt.Errorf("ошибка создания файла: %v", err)
-    }
-    defer file.Close()
-
-    writer := bufio.NewWriter(file)
-    
-    header := "ID|Название|Автор|Год|Тираж\n"
-    if _, err := writer.WriteString(header); err != nil {
-        return fmt.Errorf("ошибка записи заголовка: %v", err)
-    }
-
-    for _, book := range books {
-        line := fmt.Sprintf("%d|%s|%s|%d|%d\n", 
-            book.ID, book.Title, book.Author, book.Year, book.Copies)
-        if _, err := writer.WriteString(line); err != nil {
-            return fmt.Errorf("ошибка записи данных: %v", err)
+    rows := make([][]string, len(books))
+    for i, book := range books {
+        rows[i] = []string{
+            fmt.Sprintf("%d", book.ID), book.Title, book.Author,
+            fmt.Sprintf("%d", book.Year), fmt.Sprintf("%d", book.Copies),
         }
     }
 
-    if err := writer.Flush(); err != nil {
-        return fmt.Errorf("ошибка сохранения файла: %v", err)
-    }
-
-    return nil
+    return writeTxtTable(filename, strings.Split(txtBooksHeader, "|"), rows)
 }
 
-// из-за сортировки O(nlogn), а вообще считывание все так же O(n)
-func (db *Database) ImportFromTxt(filename string) (int, error) {
-    file, err := os.Open(filename)
+// из-за сортировки O(nlogn), а вообще считывание все так же O(n).
+// В мягком режиме (opts.Lenient) ошибочные строки не прерывают импорт
+func (db *Database) ImportFromTxt(filename string, opts ImportOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    rows, err := readTxtRows(filename)
     if err != nil {
-        return 0, fmt.Errorf("ошибка открытия файла: %v", err)
+        return summary, err
     }
-    defer file.Close()
-
-    scanner := bufio.NewScanner(file)
-    importedCount := 0
-    lineNumber := 0
-
-    var booksToImport []BookView
+    if err := db.checkConflicts(rows, opts); err != nil {
+        return summary, err
+    }
+    defer db.auditAs(AuditImport)()
 
-    for scanner.Scan() {
-        lineNumber++
-        line := strings.TrimSpace(scanner.Text())
-        
-        if line == "" || line == "ID|Название|Автор|Год|Тираж" {
+    var booksToImport []importRow
+    for _, row := range rows {
+        if row.Err != nil {
+            if err := summary.reject(opts, row.importError()); err != nil {
+                return summary, err
+            }
             continue
         }
-
-        parts := strings.Split(line, "|")
-        if len(parts) != 5 {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный формат данных", lineNumber)
-        }
-
-        id, err := strconv.Atoi(parts[0])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный ID", lineNumber)
-        }
-
-        year, err := strconv.Atoi(parts[3])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный год", lineNumber)
-        }
-
-        copies, err := strconv.Atoi(parts[4])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный тираж", lineNumber)
-        }
-
-        book := BookView{
-            ID:     int32(id),
-            Title:  parts[1],
-            Author: parts[2],
-            Year:   int32(year),
-            Copies: int32(copies),
-        }
-
-        booksToImport = append(booksToImport, book)
+        booksToImport = append(booksToImport, row)
     }
 
-    if err := scanner.Err(); err != nil {
-        return importedCount, fmt.Errorf("ошибка чтения файла: %v", err)
-    }
-
-    sort.Slice(booksToImport, func(i, j int) bool {
-        return booksToImport[i].ID < booksToImport[j].ID
+    sort.SliceStable(booksToImport, func(i, j int) bool {
+        return booksToImport[i].Book.ID < booksToImport[j].Book.ID
     })
 
-    for _, book := range booksToImport {
-        if err := db.AddBook(book); err != nil {
-            if strings.Contains(err.Error(), "уже существует") {
-                if err := db.UpdateBook(book); err != nil {
-                    return importedCount, fmt.Errorf("ошибка обновления книги в строке %d: %v", lineNumber, err)
-                }
-            } else {
-                return importedCount, fmt.Errorf("ошибка добавления книги в строке %d: %v", lineNumber, err)
+    for _, row := range booksToImport {
+        if _, err := db.importBook(row.Book, opts, &summary); err != nil {
+            if err := summary.rejectBook(opts, row.Line, row.Raw, err); err != nil {
+                return summary, err
             }
         }
-        
-        importedCount++
     }
 
-    return importedCount, nil
+    // ошибки разбора и записи собраны в разных проходах
+    sort.SliceStable(summary.Errors, func(i, j int) bool {
+        return summary.Errors[i].Line < summary.Errors[j].Line
+    })
+    return summary, nil
 }
 
 func (db *Database) GetStats() (int, int64, error) {
@@ -410,6 +724,15 @@ func (db *Database) writeRecord(book *Book, position int64) error {
     return err
 }
 
+// O(1)
+func (db *Database) resetIndexes() {
+    db.idIndex = make(map[int32]int64)
+    db.titleIndex = make(map[string][]int64)
+    db.authorIndex = make(map[string][]int64)
+    db.yearIndex = make(map[int32][]int64)
+    db.freeList = []int64{}
+}
+
 // O(n)
 func (db *Database) rebuildIndexes() error {
     stat, err := db.file.Stat()
@@ -418,11 +741,11 @@ func (db *Database) rebuildIndexes() error {
     }
     
     fileSize := stat.Size()
-    var position int64 = 0
+    var position int64 = headerSize
     
     for position < fileSize {
         book, err := db.readRecord(position)
-        if err != nil {
+        if err != nil || book.ID == 0 {
             db.freeList = append(db.freeList, position)
         } else {
             db.idIndex[book.ID] = position
@@ -496,115 +819,41 @@ func (db *Database) removeFromSliceIndexInt(index map[int32][]int64, key int32,
     }
 }
 
-// O(n)
+// O(n log n) вся база со сводкой
 func (db *Database) ExportToExcel(filename string) error {
     books, err := db.GetAllBooks()
     if err != nil {
         return fmt.Errorf("ошибка получения книг: %v", err)
     }
-
-    f := excelize.NewFile()
-    defer f.Close()
-
-    index, err := f.NewSheet("Книги")
-    if err != nil {
-        return fmt.Errorf("ошибка создания листа: %v", err)
-    }
-
-    headers := []string{"ID", "Название", "Автор", "Год издания", "Тираж"}
-    for i, h := range headers {
-        cell, _ := excelize.CoordinatesToCellName(i+1, 1)
-        f.SetCellValue("Книги", cell, h)
-        
-        style, _ := f.NewStyle(&excelize.Style{
-            Font: &excelize.Font{Bold: true},
-            Fill: excelize.Fill{Type: "pattern", Color: []string{"#f5f7ddff"}, Pattern: 1},
-        })
-        f.SetCellStyle("Книги", cell, cell, style)
-    }
-
-    for i, book := range books {
-        row := i + 2
-        f.SetCellValue("Книги", "A"+strconv.Itoa(row), book.ID)
-        f.SetCellValue("Книги", "B"+strconv.Itoa(row), book.Title)
-        f.SetCellValue("Книги", "C"+strconv.Itoa(row), book.Author)
-        f.SetCellValue("Книги", "D"+strconv.Itoa(row), book.Year)
-        f.SetCellValue("Книги", "E"+strconv.Itoa(row), book.Copies)
-    }
-
-    f.SetColWidth("Книги", "A", "A", 10)
-    f.SetColWidth("Книги", "B", "B", 40)
-    f.SetColWidth("Книги", "C", "C", 25)
-    f.SetColWidth("Книги", "D", "D", 12)
-    f.SetColWidth("Книги", "E", "E", 12)
-
-    f.SetActiveSheet(index)
-
-    f.DeleteSheet("Sheet1")
-
-    if err := f.SaveAs(filename); err != nil {
-        return fmt.Errorf("ошибка сохранения файла: %v", err)
-    }
-
-    return nil
+    return db.ExportBooksToExcel(filename, books, ExcelExportOptions{Summary: true})
 }
 
-// O(n)
-func (db *Database) ImportFromExcel(filename string) (int, error) {
-    f, err := excelize.OpenFile(filename)
+// O(n) лист и столбцы - по opts (по умолчанию лист "Книги" и столбцы
+// по заголовкам). В мягком режиме ошибочные строки не прерывают импорт
+func (db *Database) ImportFromExcel(filename string, opts ExcelOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    rows, err := readExcelRows(filename, opts)
     if err != nil {
-        return 0, fmt.Errorf("ошибка открытия файла: %v", err)
+        return summary, err
     }
-    defer f.Close()
-
-    rows, err := f.GetRows("Книги")
-    if err != nil {
-        return 0, fmt.Errorf("ошибка чтения листа: %v", err)
+    if err := db.checkConflicts(rows, opts.ImportOptions); err != nil {
+        return summary, err
     }
+    defer db.auditAs(AuditImport)()
 
-    if len(rows) < 2 {
-        return 0, fmt.Errorf("файл не содержит данных")
-    }
-
-    importedCount := 0
-
-    for i, row := range rows[1:] {
-        // Пропускаем пустые строки
-        if len(row) < 5 {
+    for _, row := range rows {
+        if row.Err != nil {
+            if err := summary.reject(opts.ImportOptions, row.importError()); err != nil {
+                return summary, err
+            }
             continue
         }
-
-        id, err := strconv.Atoi(row[0])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный ID", i+2)
-        }
-
-        year, err := strconv.Atoi(row[3])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный год", i+2)
-        }
-
-        copies, err := strconv.Atoi(row[4])
-        if err != nil {
-            return importedCount, fmt.Errorf("ошибка в строке %d: неверный тираж", i+2)
-        }
-
-        book := BookView{
-            ID:     int32(id),
-            Title:  row[1],
-            Author: row[2],
-            Year:   int32(year),
-            Copies: int32(copies),
-        }
-
-        if err := db.AddBook(book); err != nil {
-            if err := db.UpdateBook(book); err != nil {
-                return importedCount, fmt.Errorf("ошибка обновления книги в строке %d: %v", i+2, err)
+        if _, err := db.importBook(row.Book, opts.ImportOptions, &summary); err != nil {
+            if err := summary.rejectBook(opts.ImportOptions, row.Line, row.Raw, err); err != nil {
+                return summary, err
             }
         }
-
-        importedCount++
     }
 
-    return importedCount, nil
+    return summary, nil
 }
\ No newline at end of file
@@ -0,0 +1,490 @@
+package database
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "sort"
+    "strconv"
+    "strings"
+
+    "github.com/xuri/excelize/v2"
+)
+
+// Поля книги в порядке столбцов экспорта. Названия - те же, что в FindBooks
+var BookFields = []string{"ID", "Название", "Автор", "Год издания", "Тираж"}
+
+// Без этих столбцов книгу не собрать; без ID книги добавляются под новыми ID,
+// без тиража тираж считается нулевым
+var requiredBookFields = []string{"Название", "Автор", "Год издания"}
+
+// Как поставщики подписывают столбцы: заголовок приводится к виду
+// normalizeExcelHeader и ищется здесь
+var excelHeaderAliases = map[string][]string{
+    "ID":          {"id", "ид", "код", "номер", "№", "book id"},
+    "Название":    {"название", "наименование", "заглавие", "книга", "title", "name", "book", "book title"},
+    "Автор":       {"автор", "авторы", "author", "authors", "writer"},
+    "Год издания": {"год", "год издания", "год выпуска", "дата издания", "year", "publication year", "published", "date"},
+    "Тираж":       {"тираж", "количество", "кол-во", "экземпляры", "экз", "copies", "quantity", "qty", "count"},
+}
+
+// Столбец листа (с 0) для каждого поля из BookFields; поля без столбца нет в карте
+type ExcelMapping map[string]int
+
+// Настройки импорта Excel. Пустой Sheet - лист "Книги", а если его нет - первый
+// лист файла; пустой Mapping - столбцы определяются по заголовкам первой строки
+type ExcelOptions struct {
+    Sheet   string
+    Mapping ExcelMapping
+    ImportOptions
+}
+
+// Что есть в файле: для диалога выбора листа и столбцов
+type ExcelLayout struct {
+    Sheets  []string
+    Sheet   string
+    Headers []string     // первая строка листа
+    Mapping ExcelMapping // столбцы, найденные по заголовкам
+}
+
+// O(m) "Год  издания:" -> "год издания"
+func normalizeExcelHeader(header string) string {
+    header = strings.ToLower(strings.ReplaceAll(header, "ё", "е"))
+    header = strings.Trim(strings.TrimSpace(header), ".:*")
+    return strings.Join(strings.Fields(header), " ")
+}
+
+// O(h) столбцы по заголовкам на русском или английском. Если заголовок
+// подходит к нескольким столбцам, берется первый
+func DetectExcelMapping(headers []string) ExcelMapping {
+    mapping := make(ExcelMapping)
+    for column, header := range headers {
+        header = normalizeExcelHeader(header)
+        for _, field := range BookFields {
+            if _, taken := mapping[field]; taken {
+                continue
+            }
+            for _, alias := range excelHeaderAliases[field] {
+                if header == alias {
+                    mapping[field] = column
+                }
+            }
+        }
+    }
+    return mapping
+}
+
+// O(1) обязательные поля, для которых не выбран столбец
+func (m ExcelMapping) Missing() []string {
+    var missing []string
+    for _, field := range requiredBookFields {
+        if _, ok := m[field]; !ok {
+            missing = append(missing, field)
+        }
+    }
+    return missing
+}
+
+// O(1) лист по настройкам: названный, "Книги" или первый
+func excelSheet(f *excelize.File, sheet string) (string, error) {
+    sheets := f.GetSheetList()
+    if sheet != "" {
+        for _, name := range sheets {
+            if name == sheet {
+                return sheet, nil
+            }
+        }
+        return "", fmt.Errorf("в файле нет листа %q", sheet)
+    }
+    for _, name := range sheets {
+        if name == "Книги" {
+            return name, nil
+        }
+    }
+    if len(sheets) == 0 {
+        return "", fmt.Errorf("в файле нет листов")
+    }
+    return sheets[0], nil
+}
+
+// O(n) листы файла, заголовки выбранного листа и найденные по ним столбцы
+func InspectExcel(filename, sheet string) (*ExcelLayout, error) {
+    f, err := excelize.OpenFile(filename)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer f.Close()
+
+    sheet, err = excelSheet(f, sheet)
+    if err != nil {
+        return nil, err
+    }
+    rows, err := f.Rows(sheet)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения листа: %v", err)
+    }
+    defer rows.Close()
+
+    layout := &ExcelLayout{Sheets: f.GetSheetList(), Sheet: sheet}
+    if rows.Next() {
+        if layout.Headers, err = rows.Columns(); err != nil {
+            return nil, fmt.Errorf("ошибка чтения листа: %v", err)
+        }
+    }
+    layout.Mapping = DetectExcelMapping(layout.Headers)
+    return layout, nil
+}
+
+// O(1) целое из ячейки: "5", "5.0" и "5,0" - это 5. Пустая ячейка - 0.
+// Поля книги - int32, поэтому число вне его диапазона - ошибка, а не переполнение
+func parseExcelInt(cell string) (int, error) {
+    cell = strings.TrimSpace(cell)
+    if cell == "" {
+        return 0, nil
+    }
+    value, err := strconv.ParseInt(cell, 10, 32)
+    if err == nil {
+        return int(value), nil
+    }
+    if errors.Is(err, strconv.ErrRange) {
+        return 0, fmt.Errorf("число вне диапазона от %d до %d", math.MinInt32, math.MaxInt32)
+    }
+    number, err := strconv.ParseFloat(strings.Replace(cell, ",", ".", 1), 64)
+    if err != nil || number != math.Trunc(number) {
+        return 0, fmt.Errorf("не целое число")
+    }
+    if number > math.MaxInt32 || number < math.MinInt32 {
+        return 0, fmt.Errorf("число вне диапазона от %d до %d", math.MinInt32, math.MaxInt32)
+    }
+    return int(number), nil
+}
+
+// O(1) год из ячейки: число, дата Excel (порядковый номер дня в ячейке
+// с форматом даты, isDate) или дата текстом
+func parseExcelYear(cell string, isDate bool) (int, error) {
+    cell = strings.TrimSpace(cell)
+    if value, err := strconv.ParseFloat(strings.Replace(cell, ",", ".", 1), 64); err == nil {
+        if isDate {
+            date, err := excelize.ExcelDateToTime(value, false)
+            if err != nil {
+                return 0, fmt.Errorf("не дата")
+            }
+            return date.Year(), nil
+        }
+        if value != math.Trunc(value) {
+            return 0, fmt.Errorf("не целое число")
+        }
+        if value > math.MaxInt32 || value < math.MinInt32 {
+            return 0, fmt.Errorf("число вне диапазона от %d до %d", math.MinInt32, math.MaxInt32)
+        }
+        return int(value), nil
+    }
+    if year, ok := firstYear(cell); ok {
+        return int(year), nil
+    }
+    return 0, fmt.Errorf("не год и не дата")
+}
+
+// Встроенные форматы Excel с датой или временем: 14-22 и 45-47 общие,
+// 27-36 и 50-58 - региональные (китайский, японский, корейский)
+func builtInDateFormat(id int) bool {
+    return (id >= 14 && id <= 22) || (id >= 27 && id <= 36) || (id >= 45 && id <= 47) || (id >= 50 && id <= 58)
+}
+
+// O(m) m - длина кода формата. Собственный формат - дата, если вне кавычек,
+// квадратных скобок ([$-419], [Red]) и экранирования в нем есть d, m, y или h
+func customDateFormat(code string) bool {
+    quoted, bracket := false, false
+    for i := 0; i < len(code); i++ {
+        c := code[i]
+        switch {
+        case c == '"':
+            quoted = !quoted
+        case quoted:
+        case c == '[':
+            bracket = true
+        case c == ']':
+            bracket = false
+        case bracket:
+        case c == '\\' || c == '_' || c == '*':
+            // следующий символ - литерал или заполнитель
+            i++
+        case strings.ContainsRune("dDmMyYhH", rune(c)):
+            return true
+        }
+    }
+    return false
+}
+
+// Формат даты у ячеек по их стилю; стили повторяются, поэтому ответ
+// запоминается по номеру стиля
+type excelDateStyles struct {
+    f      *excelize.File
+    sheet  string
+    styles map[int]bool
+}
+
+// O(m) в первый раз для стиля, дальше O(1). Ячейка с неизвестным стилем - не дата
+func (d *excelDateStyles) isDate(cell string) bool {
+    styleID, err := d.f.GetCellStyle(d.sheet, cell)
+    if err != nil {
+        return false
+    }
+    if isDate, known := d.styles[styleID]; known {
+        return isDate
+    }
+    isDate := false
+    if style, err := d.f.GetStyle(styleID); err == nil {
+        isDate = builtInDateFormat(style.NumFmt)
+        if style.CustomNumFmt != nil {
+            isDate = customDateFormat(*style.CustomNumFmt)
+        }
+    }
+    d.styles[styleID] = isDate
+    return isDate
+}
+
+// O(1) книга из строки листа по сопоставлению столбцов; yearIsDate - у ячейки
+// года формат даты. При ошибке возвращает и название поля, в котором она найдена
+func excelBook(cells []string, mapping ExcelMapping, yearIsDate bool) (BookView, string, error) {
+    cell := func(field string) string {
+        column, ok := mapping[field]
+        if !ok || column >= len(cells) {
+            return ""
+        }
+        return cells[column]
+    }
+
+    var book BookView
+    id, err := parseExcelInt(cell("ID"))
+    if err != nil {
+        return book, "ID", fmt.Errorf("неверный ID %q: %v", cell("ID"), err)
+    }
+    year, err := parseExcelYear(cell("Год издания"), yearIsDate)
+    if err != nil {
+        return book, "Год издания", fmt.Errorf("неверный год %q: %v", cell("Год издания"), err)
+    }
+    copies, err := parseExcelInt(cell("Тираж"))
+    if err != nil {
+        return book, "Тираж", fmt.Errorf("неверный тираж %q: %v", cell("Тираж"), err)
+    }
+    return BookView{
+        ID:     int32(id),
+        Title:  strings.TrimSpace(cell("Название")),
+        Author: strings.TrimSpace(cell("Автор")),
+        Year:   int32(year),
+        Copies: int32(copies),
+    }, "", nil
+}
+
+// O(n) строки листа без заголовка; полностью пустые строки пропускаются.
+// Ячейки читаются без форматирования: числа с дробной частью и даты
+// приходят как числа, а не как отображаемый текст
+func readExcelRows(filename string, opts ExcelOptions) ([]importRow, error) {
+    f, err := excelize.OpenFile(filename)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer f.Close()
+
+    sheet, err := excelSheet(f, opts.Sheet)
+    if err != nil {
+        return nil, err
+    }
+    cells, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения листа: %v", err)
+    }
+    if len(cells) < 2 {
+        return nil, fmt.Errorf("файл не содержит данных")
+    }
+    // для отчета об ошибках - строки в том виде, в каком их видно в Excel
+    shown, err := f.GetRows(sheet)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения листа: %v", err)
+    }
+
+    mapping := opts.Mapping
+    if mapping == nil {
+        mapping = DetectExcelMapping(cells[0])
+    }
+    if missing := mapping.Missing(); len(missing) > 0 {
+        return nil, fmt.Errorf("на листе %q не найдены столбцы: %s", sheet, strings.Join(missing, ", "))
+    }
+
+    // дату от года отличает формат ячейки, а не величина числа
+    dates := &excelDateStyles{f: f, sheet: sheet, styles: make(map[int]bool)}
+    var rows []importRow
+    for i, row := range cells[1:] {
+        if strings.TrimSpace(strings.Join(row, "")) == "" {
+            continue
+        }
+        raw := row
+        if i+1 < len(shown) {
+            raw = shown[i+1]
+        }
+        yearCell, _ := excelize.CoordinatesToCellName(mapping["Год издания"]+1, i+2)
+        book, column, err := excelBook(row, mapping, dates.isDate(yearCell))
+        rows = append(rows, importRow{Line: i + 2, Raw: strings.Join(raw, " | "), Book: book, Column: column, Err: err})
+    }
+    return rows, nil
+}
+
+// Настройки экспорта книг в Excel
+type ExcelExportOptions struct {
+    // лист "Сводка": итоги, книги и тираж по авторам и по десятилетиям
+    Summary bool
+}
+
+// O(1) колонки листа книг; год и тираж проверяются по правилам базы
+func (db *Database) bookExcelColumns() []excelColumn {
+    limits, ok := db.validator.(*BookValidator)
+    if !ok {
+        limits = DefaultValidator()
+    }
+    return []excelColumn{
+        {Title: "ID", Width: 10, Min: 1, Max: math.MaxInt32},
+        {Title: "Название", Width: 40},
+        {Title: "Автор", Width: 25},
+        {Title: "Год издания", Width: 12, Min: int(limits.MinYear), Max: int(limits.MaxYear)},
+        {Title: "Тираж", Width: 12, Min: int(limits.MinCopies), Max: math.MaxInt32},
+    }
+}
+
+// Строка сводной таблицы: книги и тираж одной группы
+type excelPivotRow struct {
+    Name   string
+    Books  int
+    Copies int64
+}
+
+// O(n log n) книги по ключу; порядок задает less
+func excelPivot(books []BookView, key func(BookView) string, less func(a, b excelPivotRow) bool) [][]interface{} {
+    groups := make(map[string]*excelPivotRow)
+    for _, book := range books {
+        name := key(book)
+        group, ok := groups[name]
+        if !ok {
+            group = &excelPivotRow{Name: name}
+            groups[name] = group
+        }
+        group.Books++
+        group.Copies += int64(book.Copies)
+    }
+
+    pivot := make([]excelPivotRow, 0, len(groups))
+    for _, group := range groups {
+        pivot = append(pivot, *group)
+    }
+    sort.Slice(pivot, func(i, j int) bool {
+        return less(pivot[i], pivot[j])
+    })
+
+    rows := make([][]interface{}, len(pivot))
+    for i, row := range pivot {
+        rows[i] = []interface{}{row.Name, row.Books, row.Copies}
+    }
+    return rows
+}
+
+// O(n log n) лист "Сводка": итоги сверху, под ними по авторам (чаще
+// встречающиеся первыми) и по десятилетиям (по порядку)
+func addExcelSummary(f *excelize.File, style int, books []BookView) error {
+    const sheet = "Сводка"
+    if _, err := f.NewSheet(sheet); err != nil {
+        return fmt.Errorf("ошибка создания листа: %v", err)
+    }
+
+    byAuthor := excelPivot(books, func(book BookView) string {
+        return book.Author
+    }, func(a, b excelPivotRow) bool {
+        if a.Books != b.Books {
+            return a.Books > b.Books
+        }
+        return a.Name < b.Name
+    })
+    // "1860-е": у положительных чисел короткая запись - меньшее число
+    byDecade := excelPivot(books, func(book BookView) string {
+        return fmt.Sprintf("%d-е", book.Year/10*10)
+    }, func(a, b excelPivotRow) bool {
+        if len(a.Name) != len(b.Name) {
+            return len(a.Name) < len(b.Name)
+        }
+        return a.Name < b.Name
+    })
+
+    var copies int64
+    for _, book := range books {
+        copies += int64(book.Copies)
+    }
+    totals := [][]interface{}{
+        {"Книг", len(books)},
+        {"Экземпляров (тираж)", copies},
+        {"Авторов", len(byAuthor)},
+    }
+    f.SetCellValue(sheet, "A1", "Итого")
+    f.SetCellStyle(sheet, "A1", "B1", style)
+    for i, row := range totals {
+        f.SetSheetRow(sheet, fmt.Sprintf("A%d", i+2), &row)
+    }
+
+    // две таблицы рядом, начиная со строки 6
+    const firstRow = 6
+    tables := []struct {
+        Column string
+        Header []interface{}
+        Rows   [][]interface{}
+    }{
+        {"A", []interface{}{"Автор", "Книг", "Тираж"}, byAuthor},
+        {"E", []interface{}{"Десятилетие", "Книг", "Тираж"}, byDecade},
+    }
+    for _, table := range tables {
+        start, _ := excelize.ColumnNameToNumber(table.Column)
+        last, _ := excelize.ColumnNumberToName(start + len(table.Header) - 1)
+        f.SetSheetRow(sheet, fmt.Sprintf("%s%d", table.Column, firstRow), &table.Header)
+        f.SetCellStyle(sheet, fmt.Sprintf("%s%d", table.Column, firstRow), fmt.Sprintf("%s%d", last, firstRow), style)
+        for i, row := range table.Rows {
+            f.SetSheetRow(sheet, fmt.Sprintf("%s%d", table.Column, firstRow+1+i), &row)
+        }
+    }
+
+    f.SetColWidth(sheet, "A", "A", 30)
+    f.SetColWidth(sheet, "B", "C", 12)
+    f.SetColWidth(sheet, "E", "E", 14)
+    f.SetColWidth(sheet, "F", "G", 12)
+    if err := f.SetPanes(sheet, &excelize.Panes{
+        Freeze:      true,
+        YSplit:      firstRow,
+        TopLeftCell: fmt.Sprintf("A%d", firstRow+1),
+        ActivePane:  "bottomLeft",
+    }); err != nil {
+        return fmt.Errorf("ошибка закрепления шапки: %v", err)
+    }
+    return nil
+}
+
+// O(n log n) книги на листе "Книги" (закрепленная шапка, автофильтр, проверка
+// года и тиража) и, если нужно, сводка. books - вся база или результаты поиска
+func (db *Database) ExportBooksToExcel(filename string, books []BookView, opts ExcelExportOptions) error {
+    f := excelize.NewFile()
+    defer f.Close()
+
+    style, err := excelHeaderStyle(f)
+    if err != nil {
+        return err
+    }
+
+    rows := make([][]interface{}, len(books))
+    for i, book := range books {
+        rows[i] = []interface{}{book.ID, book.Title, book.Author, book.Year, book.Copies}
+    }
+    if err := addExcelTable(f, "Книги", style, db.bookExcelColumns(), rows); err != nil {
+        return err
+    }
+    if opts.Summary {
+        if err := addExcelSummary(f, style, books); err != nil {
+            return err
+        }
+    }
+    return saveExcelFile(f, filename)
+}
\ No newline at end of file
@@ -0,0 +1,199 @@
+package database
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strings"
+
+    "github.com/xuri/excelize/v2"
+)
+
+type excelColumn struct {
+    Title string
+    Width float64
+    // целое от Min до Max: Excel не даст ввести другое значение.
+    // Если Max не больше Min, проверки нет
+    Min, Max int
+}
+
+// Экранирование в TXT: "\\" - обратная косая, "\|" - вертикальная черта,
+// "\n" и "\r" - переводы строки. Так любая строка укладывается в одно поле
+// одной строки файла. Файл с экранированием помечен txtEscapedMarker в шапке,
+// старые файлы без метки (например, с путями "C:\new") читаются как есть
+var txtEscaper = strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", `\n`, "\r", `\r`)
+
+// Последнее поле шапки файлов, записанных с экранированием
+const txtEscapedMarker = "#escaped"
+
+// O(m) m - длина строки
+func escapeTxtField(s string) string {
+    return txtEscaper.Replace(s)
+}
+
+// O(m) делит строку по неэкранированным "|" и снимает экранирование
+func splitTxtLine(line string) []string {
+    var fields []string
+    var field strings.Builder
+    for i := 0; i < len(line); i++ {
+        c := line[i]
+        if c == '|' {
+            fields = append(fields, field.String())
+            field.Reset()
+            continue
+        }
+        if c == '\\' && i+1 < len(line) {
+            switch line[i+1] {
+            case '\\', '|':
+                field.WriteByte(line[i+1])
+                i++
+                continue
+            case 'n':
+                field.WriteByte('\n')
+                i++
+                continue
+            case 'r':
+                field.WriteByte('\r')
+                i++
+                continue
+            }
+        }
+        field.WriteByte(c)
+    }
+    return append(fields, field.String())
+}
+
+// O(m) поля строки TXT: с экранированием или, для файлов без метки, простым делением по "|"
+func splitTxtFields(line string, escaped bool) []string {
+    if escaped {
+        return splitTxtLine(line)
+    }
+    return strings.Split(line, "|")
+}
+
+// O(n) строка TXT из полей с экранированием
+func joinTxtLine(fields []string) string {
+    escaped := make([]string, len(fields))
+    for i, field := range fields {
+        escaped[i] = escapeTxtField(field)
+    }
+    return strings.Join(escaped, "|")
+}
+
+// O(n) общий писатель TXT: шапка с меткой экранирования и строки, поля через "|"
+func writeTxtTable(filename string, header []string, rows [][]string) error {
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    writer := bufio.NewWriter(file)
+
+    header = append(append([]string(nil), header...), txtEscapedMarker)
+    if _, err := writer.WriteString(joinTxtLine(header) + "\n"); err != nil {
+        return fmt.Errorf("ошибка записи заголовка: %v", err)
+    }
+
+    for _, row := range rows {
+        if _, err := writer.WriteString(joinTxtLine(row) + "\n"); err != nil {
+            return fmt.Errorf("ошибка записи данных: %v", err)
+        }
+    }
+
+    if err := writer.Flush(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+
+    return nil
+}
+
+// O(1) жирная шапка с заливкой; один стиль на файл, а не на ячейку
+func excelHeaderStyle(f *excelize.File) (int, error) {
+    style, err := f.NewStyle(&excelize.Style{
+        Font: &excelize.Font{Bold: true},
+        Fill: excelize.Fill{Type: "pattern", Color: []string{"#f5f7ddff"}, Pattern: 1},
+    })
+    if err != nil {
+        return 0, fmt.Errorf("ошибка создания стиля: %v", err)
+    }
+    return style, nil
+}
+
+// O(n) лист-таблица: шапка закреплена и с автофильтром, ширина колонок,
+// проверка целых чисел в колонках с диапазоном
+func addExcelTable(f *excelize.File, sheet string, style int, columns []excelColumn, rows [][]interface{}) error {
+    if _, err := f.NewSheet(sheet); err != nil {
+        return fmt.Errorf("ошибка создания листа: %v", err)
+    }
+
+    titles := make([]interface{}, len(columns))
+    for i, column := range columns {
+        titles[i] = column.Title
+        name, _ := excelize.ColumnNumberToName(i + 1)
+        f.SetColWidth(sheet, name, name, column.Width)
+    }
+    lastColumn, _ := excelize.ColumnNumberToName(len(columns))
+    f.SetSheetRow(sheet, "A1", &titles)
+    f.SetCellStyle(sheet, "A1", lastColumn+"1", style)
+
+    for i, row := range rows {
+        cell, _ := excelize.CoordinatesToCellName(1, i+2)
+        f.SetSheetRow(sheet, cell, &row)
+    }
+
+    if err := f.SetPanes(sheet, &excelize.Panes{
+        Freeze:      true,
+        YSplit:      1,
+        TopLeftCell: "A2",
+        ActivePane:  "bottomLeft",
+    }); err != nil {
+        return fmt.Errorf("ошибка закрепления шапки: %v", err)
+    }
+    if err := f.AutoFilter(sheet, fmt.Sprintf("A1:%s%d", lastColumn, len(rows)+1), nil); err != nil {
+        return fmt.Errorf("ошибка установки фильтра: %v", err)
+    }
+
+    for i, column := range columns {
+        if column.Max <= column.Min {
+            continue
+        }
+        name, _ := excelize.ColumnNumberToName(i + 1)
+        validation := excelize.NewDataValidation(true)
+        validation.Sqref = fmt.Sprintf("%s2:%s%d", name, name, excelize.TotalRows)
+        if err := validation.SetRange(column.Min, column.Max, excelize.DataValidationTypeWhole, excelize.DataValidationOperatorBetween); err != nil {
+            return fmt.Errorf("ошибка проверки колонки %s: %v", column.Title, err)
+        }
+        validation.SetError(excelize.DataValidationErrorStyleStop, column.Title,
+            fmt.Sprintf("Нужно целое число от %d до %d", column.Min, column.Max))
+        if err := f.AddDataValidation(sheet, validation); err != nil {
+            return fmt.Errorf("ошибка проверки колонки %s: %v", column.Title, err)
+        }
+    }
+    return nil
+}
+
+// O(1) сохраняет книгу Excel: первый лист активен, пустой лист по умолчанию удаляется
+func saveExcelFile(f *excelize.File, filename string) error {
+    f.DeleteSheet("Sheet1")
+    f.SetActiveSheet(0)
+    if err := f.SaveAs(filename); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(n) общий писатель Excel: один лист-таблица
+func writeExcelTable(filename, sheet string, columns []excelColumn, rows [][]interface{}) error {
+    f := excelize.NewFile()
+    defer f.Close()
+
+    style, err := excelHeaderStyle(f)
+    if err != nil {
+        return err
+    }
+    if err := addExcelTable(f, sheet, style, columns, rows); err != nil {
+        return err
+    }
+    return saveExcelFile(f, filename)
+}
\ No newline at end of file
@@ -0,0 +1,348 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "time"
+)
+
+// Запись в fines.db - штраф, начисленный при возврате с просрочкой.
+// Сумма фиксируется в момент возврата и дальше не зависит от FinePolicy
+// LoanID int32 - 4 байта
+// Amount int64 - 8 байт, копейки
+// Paid   int64 - 8 байт, unix-время оплаты, 0 - не оплачен
+const fineRecordSize = 20
+
+// Настройки штрафов в fine_policy.db - одна запись. Magic отличает
+// сохраненную политику "без штрафов" (все нули) от пустого слота
+// Magic     [4]byte - 4 байта ("FINE")
+// DailyFine int64   - 8 байт
+// GraceDays int32   - 4 байта
+// MaxFine   int64   - 8 байт
+const (
+    finePolicyRecordSize = 24
+    finePolicyMagic      = "FINE"
+)
+
+// Штраф по закрытой выдаче
+type AccruedFine struct {
+    LoanID int32
+    Amount int64
+    Paid   time.Time
+}
+
+// O(1)
+func fineToBytes(fine AccruedFine) []byte {
+    buf := make([]byte, fineRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(fine.LoanID))
+    binary.LittleEndian.PutUint64(buf[4:12], uint64(fine.Amount))
+    binary.LittleEndian.PutUint64(buf[12:20], uint64(timeToUnix(fine.Paid)))
+    return buf
+}
+
+// O(1)
+func bytesToFine(data []byte) AccruedFine {
+    return AccruedFine{
+        LoanID: int32(binary.LittleEndian.Uint32(data[0:4])),
+        Amount: int64(binary.LittleEndian.Uint64(data[4:12])),
+        Paid:   unixToTime(int64(binary.LittleEndian.Uint64(data[12:20]))),
+    }
+}
+
+// O(n) n - количество начисленных штрафов
+func (db *Database) loadFines() error {
+    db.fineIndex = make(map[int32]int64)
+    return db.fines.scan(func(position int64, data []byte) {
+        db.fineIndex[bytesToFine(data).LoanID] = position
+    })
+}
+
+// Правила штрафов. Суммы в копейках, чтобы не связываться с float
+type FinePolicy struct {
+    DailyFine int64 // за каждый день просрочки после льготного периода
+    GraceDays int   // столько дней просрочки не штрафуются
+    MaxFine   int64 // потолок штрафа за одну выдачу, 0 - без ограничения
+}
+
+// 10 рублей в день, три льготных дня, не больше 500 рублей
+func DefaultFinePolicy() FinePolicy {
+    return FinePolicy{
+        DailyFine: 1000,
+        GraceDays: 3,
+        MaxFine:   50000,
+    }
+}
+
+// O(1)
+func (p FinePolicy) Fine(daysOverdue int) int64 {
+    days := daysOverdue - p.GraceDays
+    if days <= 0 {
+        return 0
+    }
+    fine := int64(days) * p.DailyFine
+    if p.MaxFine > 0 && fine > p.MaxFine {
+        fine = p.MaxFine
+    }
+    return fine
+}
+
+// O(1) политика переживает перезапуск; ClearDatabase ее не трогает - это настройка, а не данные
+func (db *Database) openFinePolicy(dir string) error {
+    file, err := openRecordFile(filepath.Join(dir, "fine_policy.db"), finePolicyRecordSize)
+    if err != nil {
+        return err
+    }
+    db.finePolicyFile = file
+
+    data, err := file.read(0)
+    if err != nil {
+        // файла еще нет или он пустой - остается политика по умолчанию
+        return nil
+    }
+    if string(data[0:4]) != finePolicyMagic {
+        return fmt.Errorf("поврежден файл fine_policy.db")
+    }
+    db.finePolicy = FinePolicy{
+        DailyFine: int64(binary.LittleEndian.Uint64(data[4:12])),
+        GraceDays: int(int32(binary.LittleEndian.Uint32(data[12:16]))),
+        MaxFine:   int64(binary.LittleEndian.Uint64(data[16:24])),
+    }
+    return nil
+}
+
+// O(1)
+func (db *Database) SetFinePolicy(policy FinePolicy) error {
+    if policy.DailyFine < 0 || policy.GraceDays < 0 || policy.MaxFine < 0 {
+        return fmt.Errorf("параметры штрафа не могут быть отрицательными")
+    }
+
+    buf := make([]byte, finePolicyRecordSize)
+    copy(buf[0:4], finePolicyMagic)
+    binary.LittleEndian.PutUint64(buf[4:12], uint64(policy.DailyFine))
+    binary.LittleEndian.PutUint32(buf[12:16], uint32(int32(policy.GraceDays)))
+    binary.LittleEndian.PutUint64(buf[16:24], uint64(policy.MaxFine))
+    if err := db.finePolicyFile.write(0, buf); err != nil {
+        return fmt.Errorf("ошибка записи правил штрафов: %v", err)
+    }
+
+    db.finePolicy = policy
+    return nil
+}
+
+// O(1)
+func (db *Database) FinePolicy() FinePolicy {
+    return db.finePolicy
+}
+
+// O(1) полные сутки просрочки на момент at; для возвращенной книги - на момент возврата
+func OverdueDays(loan LoanView, at time.Time) int {
+    end := at
+    if !loan.Active() {
+        end = loan.Returned
+    }
+    if !end.After(loan.Due) {
+        return 0
+    }
+    return int(end.Sub(loan.Due) / (24 * time.Hour))
+}
+
+// O(1) в среднем, начисленный штраф по выдаче (false - штрафа нет)
+func (db *Database) FindFine(loanID int32) (AccruedFine, bool) {
+    position, exists := db.fineIndex[loanID]
+    if !exists {
+        return AccruedFine{}, false
+    }
+    data, err := db.fines.read(position)
+    if err != nil {
+        return AccruedFine{}, false
+    }
+    return bytesToFine(data), true
+}
+
+// O(1) в среднем. Штраф по выдаче: для невозвращенной книги - растущий по
+// текущей политике на момент at, для возвращенной - начисленный при возврате
+func (db *Database) LoanFine(loan LoanView, at time.Time) int64 {
+    if !loan.Active() {
+        fine, _ := db.FindFine(loan.ID)
+        return fine.Amount
+    }
+    return db.finePolicy.Fine(OverdueDays(loan, at))
+}
+
+// O(1) в среднем, вызывается из ReturnLoan до записи возврата.
+// Возвращает отмену на случай, если записать возврат не удастся
+func (db *Database) accrueFine(loan LoanView) (func() error, error) {
+    amount := db.finePolicy.Fine(OverdueDays(loan, loan.Returned))
+    if amount == 0 {
+        return func() error { return nil }, nil
+    }
+
+    position, err := db.fines.insert(fineToBytes(AccruedFine{LoanID: loan.ID, Amount: amount}))
+    if err != nil {
+        return nil, fmt.Errorf("ошибка записи штрафа: %v", err)
+    }
+    db.fineIndex[loan.ID] = position
+    return func() error {
+        delete(db.fineIndex, loan.ID)
+        return db.fines.remove(position)
+    }, nil
+}
+
+// O(1) в среднем, отмечает начисленный штраф оплаченным - он уходит из отчета
+func (db *Database) PayFine(loanID int32) error {
+    fine, exists := db.FindFine(loanID)
+    if !exists {
+        return fmt.Errorf("по выдаче %d штраф не начислен", loanID)
+    }
+    if !fine.Paid.IsZero() {
+        return fmt.Errorf("штраф по выдаче %d уже оплачен", loanID)
+    }
+    fine.Paid = time.Now()
+    if err := db.fines.write(db.fineIndex[loanID], fineToBytes(fine)); err != nil {
+        return fmt.Errorf("ошибка записи оплаты штрафа: %v", err)
+    }
+    return nil
+}
+
+// Строка отчета о просрочках
+type OverdueItem struct {
+    Loan        LoanView
+    BookTitle   string
+    MemberName  string
+    DaysOverdue int
+    Fine        int64
+}
+
+// O(n log n) n - количество выдач. Невозвращенные книги со сроком раньше at
+// и возвращенные с опозданием, штраф за которые еще не оплачен;
+// самые давние просрочки первыми
+func (db *Database) OverdueReport(at time.Time) ([]OverdueItem, error) {
+    loans, err := db.GetLoans(false)
+    if err != nil {
+        return nil, err
+    }
+
+    var items []OverdueItem
+    for _, loan := range loans {
+        if loan.Active() {
+            if !at.After(loan.Due) {
+                continue
+            }
+        } else if fine, exists := db.FindFine(loan.ID); !exists || !fine.Paid.IsZero() {
+            continue
+        }
+
+        item := OverdueItem{
+            Loan:        loan,
+            DaysOverdue: OverdueDays(loan, at),
+            Fine:        db.LoanFine(loan, at),
+        }
+
+        if book, err := db.FindByID(loan.BookID); err == nil {
+            item.BookTitle = book.ToView().Title
+        }
+        if member, err := db.FindMember(loan.MemberID); err == nil {
+            item.MemberName = member.Name
+        }
+
+        items = append(items, item)
+    }
+
+    sort.Slice(items, func(i, j int) bool {
+        return items[i].Loan.Due.Before(items[j].Loan.Due)
+    })
+    return items, nil
+}
+
+// O(n log n) книги и читатели с просрочкой: ID -> количество невозвращенных просроченных выдач
+func (db *Database) OverdueCounts(at time.Time) (map[int32]int, map[int32]int, error) {
+    items, err := db.OverdueReport(at)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    books := make(map[int32]int)
+    members := make(map[int32]int)
+    for _, item := range items {
+        // неоплаченный штраф по возвращенной книге - уже не просрочка
+        if !item.Loan.Active() {
+            continue
+        }
+        books[item.Loan.BookID]++
+        members[item.Loan.MemberID]++
+    }
+    return books, members, nil
+}
+
+// 1234 -> "12.34"
+func FormatMoney(kopecks int64) string {
+    return fmt.Sprintf("%d.%02d", kopecks/100, kopecks%100)
+}
+
+// Дата возврата для отчета, пусто - книга еще на руках
+func formatReturned(loan LoanView) string {
+    if loan.Active() {
+        return ""
+    }
+    return loan.Returned.Format("02.01.2006")
+}
+
+// O(n log n)
+func (db *Database) ExportOverdueToTxt(filename string, at time.Time) error {
+    items, err := db.OverdueReport(at)
+    if err != nil {
+        return fmt.Errorf("ошибка получения просрочек: %v", err)
+    }
+
+    rows := make([][]string, len(items))
+    for i, item := range items {
+        rows[i] = []string{
+            fmt.Sprintf("%d", item.Loan.ID),
+            fmt.Sprintf("%d", item.Loan.BookID), item.BookTitle,
+            fmt.Sprintf("%d", item.Loan.MemberID), item.MemberName,
+            item.Loan.Due.Format("02.01.2006"),
+            fmt.Sprintf("%d", item.DaysOverdue),
+            FormatMoney(item.Fine),
+            formatReturned(item.Loan),
+        }
+    }
+
+    header := []string{"Выдача", "ID книги", "Название", "ID читателя", "Читатель", "Срок", "Дней просрочки", "Штраф", "Возвращена"}
+    return writeTxtTable(filename, header, rows)
+}
+
+// O(n log n)
+func (db *Database) ExportOverdueToExcel(filename string, at time.Time) error {
+    items, err := db.OverdueReport(at)
+    if err != nil {
+        return fmt.Errorf("ошибка получения просрочек: %v", err)
+    }
+
+    columns := []excelColumn{
+        {Title: "Выдача", Width: 10},
+        {Title: "ID книги", Width: 10},
+        {Title: "Название", Width: 40},
+        {Title: "ID читателя", Width: 12},
+        {Title: "Читатель", Width: 25},
+        {Title: "Срок", Width: 12},
+        {Title: "Дней просрочки", Width: 16},
+        {Title: "Штраф, руб.", Width: 12},
+        {Title: "Возвращена", Width: 12},
+    }
+
+    rows := make([][]interface{}, len(items))
+    for i, item := range items {
+        rows[i] = []interface{}{
+            item.Loan.ID, item.Loan.BookID, item.BookTitle,
+            item.Loan.MemberID, item.MemberName,
+            item.Loan.Due.Format("02.01.2006"),
+            item.DaysOverdue,
+            float64(item.Fine) / 100,
+            formatReturned(item.Loan),
+        }
+    }
+
+    return writeExcelTable(filename, "Просрочки", columns, rows)
+}
\ No newline at end of file
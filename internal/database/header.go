@@ -0,0 +1,169 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "io"
+    "os"
+)
+
+// Заголовок books.db занимает ровно один слот записи, поэтому записи
+// по-прежнему лежат по позициям, кратным recordSize
+// Magic   [4]byte - 4 байта ("BKDB")
+// Version uint32  - 4 байта
+// NextID  int32   - 4 байта, следующий ID для автоматического назначения
+// остальное - нули
+const (
+    headerSize    int64 = 152
+    headerMagic         = "BKDB"
+    headerVersion       = 1
+)
+
+// O(1)
+func (db *Database) writeHeader() error {
+    buf := make([]byte, headerSize)
+    copy(buf[0:4], headerMagic)
+    binary.LittleEndian.PutUint32(buf[4:8], headerVersion)
+    binary.LittleEndian.PutUint32(buf[8:12], uint32(db.nextID))
+
+    if _, err := db.file.WriteAt(buf, 0); err != nil {
+        return fmt.Errorf("ошибка записи заголовка: %v", err)
+    }
+    return nil
+}
+
+// O(1) для файла с заголовком, O(n) для старого файла без него
+func (db *Database) loadHeader() error {
+    stat, err := db.file.Stat()
+    if err != nil {
+        return err
+    }
+
+    if stat.Size() == 0 {
+        db.nextID = 1
+        return db.writeHeader()
+    }
+
+    buf := make([]byte, headerSize)
+    n, err := db.file.ReadAt(buf, 0)
+    if err != nil && err != io.EOF {
+        return err
+    }
+
+    if n < 4 || string(buf[0:4]) != headerMagic {
+        return db.migrateLegacyFile()
+    }
+    if int64(n) < headerSize {
+        return fmt.Errorf("поврежден заголовок файла")
+    }
+
+    version := binary.LittleEndian.Uint32(buf[4:8])
+    if version > headerVersion {
+        return fmt.Errorf("неподдерживаемая версия файла: %d", version)
+    }
+
+    db.nextID = int32(binary.LittleEndian.Uint32(buf[8:12]))
+    if db.nextID < 1 {
+        db.nextID = 1
+    }
+    return nil
+}
+
+// O(n) файлы, созданные до появления заголовка, начинаются сразу с записей.
+// Переписываем их с заголовком через временный файл, чтобы не потерять данные при сбое.
+// ID 0 теперь означает свободный слот, поэтому такие книги получают новый ID
+func (db *Database) migrateLegacyFile() error {
+    if _, err := db.file.Seek(0, 0); err != nil {
+        return err
+    }
+    data, err := io.ReadAll(db.file)
+    if err != nil {
+        return fmt.Errorf("ошибка чтения старого файла: %v", err)
+    }
+
+    var books []*Book
+    var maxID int32
+    for offset := int64(0); offset+db.recordSize <= int64(len(data)); offset += db.recordSize {
+        book := bytesToBook(data[offset : offset+db.recordSize])
+        if book.ID > maxID {
+            maxID = book.ID
+        }
+        books = append(books, book)
+    }
+
+    db.nextID = maxID + 1
+    for _, book := range books {
+        if book.ID == 0 {
+            book.ID = db.nextID
+            db.nextID++
+        }
+    }
+
+    return db.rewriteBooksFile(books)
+}
+
+// O(n) записывает заголовок и книги подряд во временный файл и подменяет им books.db.
+// Индексы после этого нужно перестроить: позиции записей меняются
+func (db *Database) rewriteBooksFile(books []*Book) error {
+    tmpPath := db.filePath + ".tmp"
+    tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+    if err != nil {
+        return fmt.Errorf("ошибка создания временного файла: %v", err)
+    }
+
+    oldFile := db.file
+    db.file = tmp
+    if err := db.writeHeader(); err != nil {
+        db.file = oldFile
+        tmp.Close()
+        os.Remove(tmpPath)
+        return err
+    }
+    for i, book := range books {
+        if err := db.writeRecord(book, headerSize+int64(i)*db.recordSize); err != nil {
+            db.file = oldFile
+            tmp.Close()
+            os.Remove(tmpPath)
+            return fmt.Errorf("ошибка переноса записей: %v", err)
+        }
+    }
+
+    oldFile.Close()
+    if err := tmp.Close(); err != nil {
+        return fmt.Errorf("ошибка сохранения временного файла: %v", err)
+    }
+    if err := os.Rename(tmpPath, db.filePath); err != nil {
+        return fmt.Errorf("ошибка замены файла: %v", err)
+    }
+
+    db.file, err = os.OpenFile(db.filePath, os.O_RDWR, 0666)
+    if err != nil {
+        return fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    return nil
+}
+
+// O(1) выдаем следующий ID последовательности и сохраняем ее в заголовок
+func (db *Database) allocateID() (int32, error) {
+    for {
+        id := db.nextID
+        db.nextID++
+        if _, exists := db.idIndex[id]; !exists && !db.InTrash(id) {
+            return id, db.writeHeader()
+        }
+    }
+}
+
+// O(1) если ID введен вручную, последовательность сдвигается за него
+func (db *Database) advanceSequence(id int32) error {
+    if id < db.nextID {
+        return nil
+    }
+    db.nextID = id + 1
+    return db.writeHeader()
+}
+
+// O(1)
+func (db *Database) NextID() int32 {
+    return db.nextID
+}
\ No newline at end of file
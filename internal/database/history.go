@@ -0,0 +1,277 @@
+package database
+
+import (
+    "fmt"
+    "sort"
+    "time"
+)
+
+// История строится по журналу audit.log: каждая запись хранит книгу
+// до и после изменения, поэтому состояние на любой момент - это After
+// последней записи не позже этого момента
+
+// O(n) n - записей в журнале. Все версии книги от старых к новым.
+// Смена ID прослеживается назад: история книги 7, бывшей книгой 3,
+// включает и записи книги 3 до переименования
+func (db *Database) History(id int32) ([]AuditEntry, error) {
+    entries, err := db.GetAudit(AuditQuery{})
+    if err != nil {
+        return nil, err
+    }
+
+    // идем от новых к старым, current - номер книги на момент записи
+    current := id
+    var history []AuditEntry
+    for i := len(entries) - 1; i >= 0; i-- {
+        entry := entries[i]
+        if entry.Op == AuditChangeID {
+            if entry.After != nil && entry.After.ID == current {
+                history = append(history, entry)
+                current = entry.Before.ID
+                continue
+            }
+            // номер освободила другая книга, более старые записи - ее
+            if entry.Before != nil && entry.Before.ID == current {
+                break
+            }
+            continue
+        }
+        if entry.touches(current) {
+            history = append(history, entry)
+        }
+    }
+
+    // O(n) разворачиваем в хронологический порядок
+    for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+        history[i], history[j] = history[j], history[i]
+    }
+    return history, nil
+}
+
+// O(n) книга на момент at по ее истории; nil - книги тогда не было.
+// Если до at записей нет, книга тогда была такой, как в Before первой
+// записи после at: у книги, заведенной до появления журнала, он не пуст
+func stateAt(history []AuditEntry, at time.Time) *BookView {
+    var state *BookView
+    for i, entry := range history {
+        if entry.Time.After(at) {
+            if i == 0 {
+                return entry.Before
+            }
+            break
+        }
+        state = entry.After
+    }
+    return state
+}
+
+// O(n) состояние книги id на момент at без изменения базы
+func (db *Database) StateAt(id int32, at time.Time) (*BookView, error) {
+    history, err := db.History(id)
+    if err != nil {
+        return nil, err
+    }
+    return stateAt(history, at), nil
+}
+
+// Шаг отката: книга id приводится к состоянию target, nil - книги быть не должно
+type restoreStep struct {
+    id     int32
+    target *BookView
+}
+
+// O(1) в среднем. Шаг ничего не меняет: книга уже в нужном состоянии
+func (db *Database) restoreNoop(step restoreStep) bool {
+    book, err := db.FindByID(step.id)
+    if step.target == nil {
+        return err != nil
+    }
+    if err != nil {
+        return false
+    }
+    restored := *step.target
+    restored.ID = step.id
+    return book.ToView() == restored
+}
+
+// O(k) k - экземпляров книги. Те же проверки, что сделают DeleteBook, AddBook
+// и UpdateBook, но до первой записи, чтобы не откатывать базу наполовину
+func (db *Database) checkRestoreStep(step restoreStep) error {
+    if step.target == nil {
+        if active := db.activeByBook[step.id]; active > 0 {
+            return fmt.Errorf("книгу с ID %d нельзя удалить: не возвращено экземпляров: %d", step.id, active)
+        }
+        if holds := db.ActiveHoldsOfBook(step.id); holds > 0 {
+            return fmt.Errorf("книгу с ID %d нельзя удалить: активных броней: %d", step.id, holds)
+        }
+        return nil
+    }
+
+    restored := *step.target
+    restored.ID = step.id
+    restored.Author = db.CanonicalAuthor(restored.Author)
+    if db.HasItems(step.id) {
+        count, err := db.countedItems(step.id)
+        if err != nil {
+            return err
+        }
+        restored.Copies = count
+    }
+    if err := db.validate(restored); err != nil {
+        return fmt.Errorf("книгу с ID %d нельзя вернуть: %v", step.id, err)
+    }
+    if active := int32(db.activeByBook[step.id]); restored.Copies < active {
+        return fmt.Errorf("книгу с ID %d нельзя вернуть: тираж %d меньше числа выданных экземпляров: %d", step.id, restored.Copies, active)
+    }
+    return nil
+}
+
+// O(1) в среднем. Выполняет шаг и возвращает обратный ему: удаленная книга
+// возвращается из корзины, добавленная заново - удаляется насовсем
+func (db *Database) applyRestoreStep(step restoreStep) (func() error, error) {
+    book, err := db.FindByID(step.id)
+    exists := err == nil
+
+    if step.target == nil {
+        if err := db.DeleteBook(step.id); err != nil {
+            return nil, err
+        }
+        return func() error { return db.RestoreFromTrash(step.id) }, nil
+    }
+
+    restored := *step.target
+    restored.ID = step.id
+    if exists {
+        previous := book.ToView()
+        if err := db.UpdateBook(restored); err != nil {
+            return nil, err
+        }
+        return func() error { return db.UpdateBook(previous) }, nil
+    }
+
+    // книга еще в корзине - возвращаем ее вместе с тегами и экземплярами
+    if db.InTrash(step.id) {
+        if err := db.RestoreFromTrash(step.id); err != nil {
+            return nil, err
+        }
+        if err := db.UpdateBook(restored); err != nil {
+            db.DeleteBook(step.id)
+            return nil, err
+        }
+        return func() error { return db.DeleteBook(step.id) }, nil
+    }
+
+    if _, err := db.AddBook(restored); err != nil {
+        return nil, err
+    }
+    return func() error {
+        if err := db.DeleteBook(step.id); err != nil {
+            return err
+        }
+        return db.PurgeBook(step.id)
+    }, nil
+}
+
+// O(s) s - шагов. Все шаги проверяются заранее и выполняются по возрастанию ID;
+// если шаг все же не удался, уже сделанные отменяются в обратном порядке.
+// Возвращает число измененных книг
+func (db *Database) applyRestore(steps []restoreStep) (int, error) {
+    for _, step := range steps {
+        if err := db.checkRestoreStep(step); err != nil {
+            return 0, err
+        }
+    }
+
+    defer db.auditAs(AuditRestore)()
+
+    undo := make([]func() error, 0, len(steps))
+    for _, step := range steps {
+        revert, err := db.applyRestoreStep(step)
+        if err != nil {
+            for i := len(undo) - 1; i >= 0; i-- {
+                undo[i]()
+            }
+            return 0, err
+        }
+        undo = append(undo, revert)
+    }
+    return len(steps), nil
+}
+
+// O(n) возвращает книгу id к состоянию на момент at. Книга сохраняет
+// текущий ID, даже если тогда называлась иначе; если книги тогда не было,
+// она удаляется. Сам откат тоже попадает в журнал
+func (db *Database) RestoreAt(id int32, at time.Time) error {
+    history, err := db.History(id)
+    if err != nil {
+        return err
+    }
+    if len(history) == 0 {
+        return fmt.Errorf("в журнале нет записей о книге с ID %d", id)
+    }
+
+    step := restoreStep{id: id, target: stateAt(history, at)}
+    if db.restoreNoop(step) {
+        return nil
+    }
+    _, err = db.applyRestore([]restoreStep{step})
+    return err
+}
+
+// O(n + m log m) n - записей в журнале, m - книг. Возвращает всю базу к моменту at.
+// Книги, о которых в журнале нет записей (заведены до его появления), не трогаются;
+// такая книга, впервые измененная после at, возвращается к Before этой записи.
+// Возвращает число измененных книг
+func (db *Database) RestoreDatabaseAt(at time.Time) (int, error) {
+    entries, err := db.GetAudit(AuditQuery{})
+    if err != nil {
+        return 0, err
+    }
+
+    // прогоняем журнал до момента at; known - ID, уже встреченные в журнале
+    state := make(map[int32]BookView)
+    known := make(map[int32]bool)
+    for _, entry := range entries {
+        if entry.Time.After(at) {
+            // первая запись о книге после at: Before - ее состояние на at
+            // (nil у новой книги - ее тогда не было)
+            if entry.Before != nil && !known[entry.Before.ID] {
+                state[entry.Before.ID] = *entry.Before
+            }
+        } else {
+            if entry.Before != nil {
+                delete(state, entry.Before.ID)
+            }
+            if entry.After != nil {
+                state[entry.After.ID] = *entry.After
+            }
+        }
+        if entry.Before != nil {
+            known[entry.Before.ID] = true
+        }
+        if entry.After != nil {
+            known[entry.After.ID] = true
+        }
+    }
+
+    // порядок шагов не зависит от обхода карты
+    ids := make([]int32, 0, len(known))
+    for id := range known {
+        ids = append(ids, id)
+    }
+    sort.Slice(ids, func(i, j int) bool {
+        return ids[i] < ids[j]
+    })
+
+    var steps []restoreStep
+    for _, id := range ids {
+        step := restoreStep{id: id}
+        if target, keep := state[id]; keep {
+            step.target = &target
+        }
+        if !db.restoreNoop(step) {
+            steps = append(steps, step)
+        }
+    }
+    return db.applyRestore(steps)
+}
\ No newline at end of file
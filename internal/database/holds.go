@@ -0,0 +1,420 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "time"
+)
+
+// Запись в holds.db (даты - unix-время в секундах, 0 - не задана):
+// ID        int32 - 4 байта
+// BookID    int32 - 4 байта
+// MemberID  int32 - 4 байта
+// Placed    int64 - 8 байт
+// ReadyAt   int64 - 8 байт
+// ExpiresAt int64 - 8 байт
+// Status    int32 - 4 байта
+const holdRecordSize = 40
+
+// Срок хранения отложенного экземпляра в hold_policy.db - одна запись
+// Magic [4]byte - 4 байта ("HOLD")
+// Days  int32   - 4 байта
+const (
+    holdPolicyRecordSize = 8
+    holdPolicyMagic      = "HOLD"
+)
+
+type HoldStatus int32
+
+const (
+    HoldWaiting   HoldStatus = 1 // в очереди
+    HoldReady     HoldStatus = 2 // экземпляр отложен, ждет читателя
+    HoldFulfilled HoldStatus = 3 // книга выдана
+    HoldExpired   HoldStatus = 4 // читатель не пришел вовремя
+    HoldCancelled HoldStatus = 5
+)
+
+func (s HoldStatus) String() string {
+    switch s {
+    case HoldWaiting:
+        return "В очереди"
+    case HoldReady:
+        return "Готова к выдаче"
+    case HoldFulfilled:
+        return "Выдана"
+    case HoldExpired:
+        return "Истекла"
+    case HoldCancelled:
+        return "Отменена"
+    }
+    return "?"
+}
+
+type HoldView struct {
+    ID        int32      `json:"id"`
+    BookID    int32      `json:"book_id"`
+    MemberID  int32      `json:"member_id"`
+    Placed    time.Time  `json:"placed"`
+    ReadyAt   time.Time  `json:"ready_at"`
+    ExpiresAt time.Time  `json:"expires_at"`
+    Status    HoldStatus `json:"status"`
+}
+
+// O(1) бронь еще ждет книгу или читателя
+func (h HoldView) Active() bool {
+    return h.Status == HoldWaiting || h.Status == HoldReady
+}
+
+// O(1)
+func holdToBytes(hold HoldView) []byte {
+    buf := make([]byte, holdRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(hold.ID))
+    binary.LittleEndian.PutUint32(buf[4:8], uint32(hold.BookID))
+    binary.LittleEndian.PutUint32(buf[8:12], uint32(hold.MemberID))
+    binary.LittleEndian.PutUint64(buf[12:20], uint64(timeToUnix(hold.Placed)))
+    binary.LittleEndian.PutUint64(buf[20:28], uint64(timeToUnix(hold.ReadyAt)))
+    binary.LittleEndian.PutUint64(buf[28:36], uint64(timeToUnix(hold.ExpiresAt)))
+    binary.LittleEndian.PutUint32(buf[36:40], uint32(hold.Status))
+    return buf
+}
+
+// O(1)
+func bytesToHold(data []byte) HoldView {
+    return HoldView{
+        ID:        int32(binary.LittleEndian.Uint32(data[0:4])),
+        BookID:    int32(binary.LittleEndian.Uint32(data[4:8])),
+        MemberID:  int32(binary.LittleEndian.Uint32(data[8:12])),
+        Placed:    unixToTime(int64(binary.LittleEndian.Uint64(data[12:20]))),
+        ReadyAt:   unixToTime(int64(binary.LittleEndian.Uint64(data[20:28]))),
+        ExpiresAt: unixToTime(int64(binary.LittleEndian.Uint64(data[28:36]))),
+        Status:    HoldStatus(binary.LittleEndian.Uint32(data[36:40])),
+    }
+}
+
+// O(n log n) n - количество броней. Очередь каждой книги упорядочена по ID,
+// а ID выдаются по возрастанию, так что это и есть порядок постановки
+func (db *Database) loadHolds() error {
+    db.holdIndex = make(map[int32]int64)
+    db.holdQueues = make(map[int32][]int32)
+    db.readyByBook = make(map[int32]int)
+    db.nextHoldID = 1
+
+    err := db.holds.scan(func(position int64, data []byte) {
+        hold := bytesToHold(data)
+        db.holdIndex[hold.ID] = position
+        if hold.Active() {
+            db.holdQueues[hold.BookID] = append(db.holdQueues[hold.BookID], hold.ID)
+        }
+        if hold.Status == HoldReady {
+            db.readyByBook[hold.BookID]++
+        }
+        if hold.ID >= db.nextHoldID {
+            db.nextHoldID = hold.ID + 1
+        }
+        db.reserveMemberID(hold.MemberID)
+    })
+
+    for _, queue := range db.holdQueues {
+        sort.Slice(queue, func(i, j int) bool {
+            return queue[i] < queue[j]
+        })
+    }
+    return err
+}
+
+// O(1) срок переживает перезапуск; ClearDatabase его не трогает - это настройка, а не данные
+func (db *Database) openHoldPolicy(dir string) error {
+    file, err := openRecordFile(filepath.Join(dir, "hold_policy.db"), holdPolicyRecordSize)
+    if err != nil {
+        return err
+    }
+    db.holdPolicyFile = file
+
+    data, err := file.read(0)
+    if err != nil {
+        // файла еще нет или он пустой - остается срок по умолчанию
+        return nil
+    }
+    days := int(int32(binary.LittleEndian.Uint32(data[4:8])))
+    if string(data[0:4]) != holdPolicyMagic || days <= 0 {
+        return fmt.Errorf("поврежден файл hold_policy.db")
+    }
+    db.holdPickupDays = days
+    return nil
+}
+
+// O(1) сколько дней отложенный экземпляр ждет читателя
+func (db *Database) SetHoldPickupDays(days int) error {
+    if days <= 0 {
+        return fmt.Errorf("срок хранения брони должен быть положительным")
+    }
+
+    buf := make([]byte, holdPolicyRecordSize)
+    copy(buf[0:4], holdPolicyMagic)
+    binary.LittleEndian.PutUint32(buf[4:8], uint32(int32(days)))
+    if err := db.holdPolicyFile.write(0, buf); err != nil {
+        return fmt.Errorf("ошибка записи срока хранения брони: %v", err)
+    }
+
+    db.holdPickupDays = days
+    return nil
+}
+
+// O(1)
+func (db *Database) HoldPickupDays() int {
+    return db.holdPickupDays
+}
+
+// O(1) в среднем
+func (db *Database) FindHold(id int32) (HoldView, error) {
+    position, exists := db.holdIndex[id]
+    if !exists {
+        return HoldView{}, fmt.Errorf("бронь %d не найдена", id)
+    }
+    data, err := db.holds.read(position)
+    if err != nil {
+        return HoldView{}, err
+    }
+    return bytesToHold(data), nil
+}
+
+// O(1)
+func (db *Database) writeHold(hold HoldView) error {
+    if err := db.holds.write(db.holdIndex[hold.ID], holdToBytes(hold)); err != nil {
+        return fmt.Errorf("ошибка записи брони: %v", err)
+    }
+    return nil
+}
+
+// O(q) q - длина очереди книги. Бронь возможна, только когда свободных экземпляров нет
+func (db *Database) PlaceHold(bookID, memberID int32) (int32, error) {
+    available, err := db.AvailableCopies(bookID)
+    if err != nil {
+        return 0, err
+    }
+    if _, exists := db.memberIndex[memberID]; !exists {
+        return 0, fmt.Errorf("читатель с ID %d не найден", memberID)
+    }
+    if available > 0 {
+        return 0, fmt.Errorf("у книги с ID %d есть свободные экземпляры: %d, бронь не нужна", bookID, available)
+    }
+
+    for _, holdID := range db.holdQueues[bookID] {
+        hold, err := db.FindHold(holdID)
+        if err != nil {
+            return 0, err
+        }
+        if hold.MemberID == memberID {
+            return 0, fmt.Errorf("читатель с ID %d уже стоит в очереди на книгу с ID %d", memberID, bookID)
+        }
+    }
+
+    hold := HoldView{
+        ID:       db.nextHoldID,
+        BookID:   bookID,
+        MemberID: memberID,
+        Placed:   time.Now(),
+        Status:   HoldWaiting,
+    }
+
+    position, err := db.holds.insert(holdToBytes(hold))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка записи брони: %v", err)
+    }
+
+    db.holdIndex[hold.ID] = position
+    db.holdQueues[bookID] = append(db.holdQueues[bookID], hold.ID)
+    db.nextHoldID++
+    return hold.ID, nil
+}
+
+// O(q)
+func (db *Database) CancelHold(id int32) error {
+    return db.closeHold(id, HoldCancelled)
+}
+
+// O(q) закрываем бронь с указанным статусом и отдаем экземпляр следующему в очереди
+func (db *Database) closeHold(id int32, status HoldStatus) error {
+    hold, err := db.FindHold(id)
+    if err != nil {
+        return err
+    }
+    if !hold.Active() {
+        return fmt.Errorf("бронь %d уже закрыта", id)
+    }
+
+    wasReady := hold.Status == HoldReady
+    hold.Status = status
+    if err := db.writeHold(hold); err != nil {
+        return err
+    }
+
+    db.holdQueues[hold.BookID] = removeID(db.holdQueues[hold.BookID], id)
+    if len(db.holdQueues[hold.BookID]) == 0 {
+        delete(db.holdQueues, hold.BookID)
+    }
+    if wasReady {
+        db.readyByBook[hold.BookID]--
+        if db.readyByBook[hold.BookID] == 0 {
+            delete(db.readyByBook, hold.BookID)
+        }
+    }
+
+    // выданная книга экземпляр не освобождает, остальные статусы - освобождают
+    if status != HoldFulfilled {
+        return db.promoteHolds(hold.BookID)
+    }
+    return nil
+}
+
+// O(q) пока есть свободные экземпляры, первые в очереди получают статус "готова к выдаче"
+func (db *Database) promoteHolds(bookID int32) error {
+    for _, holdID := range db.holdQueues[bookID] {
+        available, err := db.AvailableCopies(bookID)
+        if err != nil {
+            return err
+        }
+        if available <= 0 {
+            return nil
+        }
+
+        hold, err := db.FindHold(holdID)
+        if err != nil {
+            return err
+        }
+        if hold.Status != HoldWaiting {
+            continue
+        }
+
+        hold.Status = HoldReady
+        hold.ReadyAt = time.Now()
+        hold.ExpiresAt = hold.ReadyAt.AddDate(0, 0, db.holdPickupDays)
+        if err := db.writeHold(hold); err != nil {
+            return err
+        }
+        db.readyByBook[bookID]++
+    }
+    return nil
+}
+
+// O(h) h - активные брони. Отложенные экземпляры, за которыми не пришли до at,
+// возвращаются в оборот, возвращает количество истекших броней
+func (db *Database) ExpireHolds(at time.Time) (int, error) {
+    var expired []int32
+    for _, queue := range db.holdQueues {
+        for _, holdID := range queue {
+            hold, err := db.FindHold(holdID)
+            if err != nil {
+                return 0, err
+            }
+            if hold.Status == HoldReady && at.After(hold.ExpiresAt) {
+                expired = append(expired, holdID)
+            }
+        }
+    }
+
+    for _, holdID := range expired {
+        if err := db.closeHold(holdID, HoldExpired); err != nil {
+            return 0, err
+        }
+    }
+    return len(expired), nil
+}
+
+// O(q) готовая бронь этого читателя на эту книгу, если есть
+func (db *Database) readyHoldOf(bookID, memberID int32) (int32, bool) {
+    for _, holdID := range db.holdQueues[bookID] {
+        hold, err := db.FindHold(holdID)
+        if err == nil && hold.Status == HoldReady && hold.MemberID == memberID {
+            return holdID, true
+        }
+    }
+    return 0, false
+}
+
+// O(q) место в очереди, начиная с 1; 0 - бронь не в очереди
+func (db *Database) HoldPosition(id int32) int {
+    hold, err := db.FindHold(id)
+    if err != nil || !hold.Active() {
+        return 0
+    }
+    for i, holdID := range db.holdQueues[hold.BookID] {
+        if holdID == id {
+            return i + 1
+        }
+    }
+    return 0
+}
+
+// O(1)
+func (db *Database) ActiveHoldsOfBook(bookID int32) int {
+    return len(db.holdQueues[bookID])
+}
+
+// O(n log n) n - количество броней, activeOnly - только ожидающие и готовые
+func (db *Database) GetHolds(activeOnly bool) ([]HoldView, error) {
+    holds := make([]HoldView, 0, len(db.holdIndex))
+    for _, position := range db.holdIndex {
+        data, err := db.holds.read(position)
+        if err != nil {
+            return nil, err
+        }
+        hold := bytesToHold(data)
+        if activeOnly && !hold.Active() {
+            continue
+        }
+        holds = append(holds, hold)
+    }
+
+    sort.Slice(holds, func(i, j int) bool {
+        return holds[i].ID < holds[j].ID
+    })
+    return holds, nil
+}
+
+// O(h) у читателя есть активные брони
+func (db *Database) activeHoldsOfMember(memberID int32) int {
+    count := 0
+    for _, queue := range db.holdQueues {
+        for _, holdID := range queue {
+            if hold, err := db.FindHold(holdID); err == nil && hold.MemberID == memberID {
+                count++
+            }
+        }
+    }
+    return count
+}
+
+// O(n) при смене ID книги ее брони переписываются на новый ID.
+// Возвращает функцию отката переезда
+func (db *Database) moveHolds(oldID, newID int32) (func() error, error) {
+    var rewrites []recordRewrite
+    for _, position := range db.holdIndex {
+        data, err := db.holds.read(position)
+        if err != nil {
+            return nil, err
+        }
+        hold := bytesToHold(data)
+        if hold.BookID != oldID {
+            continue
+        }
+        hold.BookID = newID
+        rewrites = append(rewrites, recordRewrite{position: position, old: data, new: holdToBytes(hold)})
+    }
+    if err := db.holds.rewriteAll(rewrites); err != nil {
+        return nil, fmt.Errorf("ошибка записи брони: %v", err)
+    }
+
+    moveKey(db.holdQueues, oldID, newID)
+    moveKey(db.readyByBook, oldID, newID)
+    return func() error {
+        if err := db.holds.revertAll(rewrites); err != nil {
+            return err
+        }
+        moveKey(db.holdQueues, newID, oldID)
+        moveKey(db.readyByBook, newID, oldID)
+        return nil
+    }, nil
+}
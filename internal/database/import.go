@@ -0,0 +1,347 @@
+package database
+
+import (
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "strings"
+)
+
+// Что делать, если книга из файла уже есть в базе (совпал ID)
+type ConflictPolicy int
+
+const (
+    ConflictOverwrite ConflictPolicy = iota // заменить книгу в базе книгой из файла
+    ConflictSkip                            // оставить книгу в базе как есть
+    ConflictMerge                           // взять из файла только заполненные поля
+    ConflictFail                            // прервать импорт с ошибкой
+    ConflictRenumber                        // добавить книгу из файла под новым ID
+)
+
+func (p ConflictPolicy) String() string {
+    switch p {
+    case ConflictOverwrite:
+        return "Перезаписать"
+    case ConflictSkip:
+        return "Пропустить"
+    case ConflictMerge:
+        return "Объединить (только заполненные поля)"
+    case ConflictFail:
+        return "Прервать импорт"
+    case ConflictRenumber:
+        return "Добавить с новым ID"
+    }
+    return "?"
+}
+
+var AllConflictPolicies = []ConflictPolicy{
+    ConflictOverwrite, ConflictSkip, ConflictMerge, ConflictFail, ConflictRenumber,
+}
+
+// Настройки, общие для всех импортов. Нулевое значение - прежнее поведение:
+// новые книги добавляются, книги с существующим ID перезаписываются,
+// импорт прерывается на первой ошибочной строке
+type ImportOptions struct {
+    OnConflict ConflictPolicy
+    // не прерываться на ошибках: импортировать все верные строки,
+    // а ошибочные собрать в ImportSummary.Errors
+    Lenient bool
+}
+
+// Чем закончился импорт одной книги
+type ImportOutcome int
+
+const (
+    OutcomeAdded ImportOutcome = iota + 1
+    OutcomeUpdated
+    OutcomeUnchanged  // книга есть и совпадает с файлом - записывать нечего
+    OutcomeSkipped    // книга есть, политика ConflictSkip
+    OutcomeRenumbered // ID занят, книга добавлена под новым
+)
+
+// Конфликт ID при политике ConflictFail: импорт прерывается.
+// InFile - ID повторяется в самом файле
+type ConflictError struct {
+    ID     int32
+    InFile bool
+}
+
+func (e *ConflictError) Error() string {
+    if e.InFile {
+        return fmt.Sprintf("ID %d повторяется в файле", e.ID)
+    }
+    return fmt.Sprintf("книга с ID %d уже существует", e.ID)
+}
+
+// Поиск конфликтов ID до первой записи при ConflictFail: иначе книги
+// до конфликтной строки успели бы записаться. Повтор ID в файле - тоже
+// конфликт, первая копия заняла бы ID
+type conflictScan struct {
+    db   *Database
+    seen map[int32]bool
+}
+
+func (db *Database) newConflictScan() *conflictScan {
+    return &conflictScan{db: db, seen: make(map[int32]bool)}
+}
+
+// O(1) в среднем. Книга без ID конфликтов не дает
+func (s *conflictScan) check(id int32) error {
+    if id == 0 {
+        return nil
+    }
+    if s.seen[id] {
+        return &ConflictError{ID: id, InFile: true}
+    }
+    s.seen[id] = true
+    if _, exists := s.db.idIndex[id]; exists || s.db.InTrash(id) {
+        return &ConflictError{ID: id}
+    }
+    return nil
+}
+
+// O(1) в среднем. Конфликт ID строки файла; ошибочная строка пропускается
+func (s *conflictScan) checkRow(row importRow) error {
+    if row.Err != nil {
+        return nil
+    }
+    if err := s.check(row.Book.ID); err != nil {
+        return &ImportError{Line: row.Line, Reason: err.Error(), Raw: row.Raw, err: err}
+    }
+    return nil
+}
+
+// O(r) r - строк. При ConflictFail ошибка для первой строки с конфликтом ID
+func (db *Database) checkConflicts(rows []importRow, opts ImportOptions) error {
+    if opts.OnConflict != ConflictFail {
+        return nil
+    }
+    scan := db.newConflictScan()
+    for _, row := range rows {
+        if err := scan.checkRow(row); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Ошибочная строка файла. Column - название поля ("" - строка целиком),
+// Raw - строка в том виде, в каком она записана в файле
+type ImportError struct {
+    Line   int
+    Column string
+    Reason string
+    Raw    string
+    err    error
+}
+
+func (e *ImportError) Error() string {
+    if e.Column == "" {
+        return fmt.Sprintf("ошибка в строке %d: %s", e.Line, e.Reason)
+    }
+    return fmt.Sprintf("ошибка в строке %d (%s): %s", e.Line, e.Column, e.Reason)
+}
+
+func (e *ImportError) Unwrap() error {
+    return e.err
+}
+
+// O(1) поле, к которому относится ошибка записи: для ошибки проверки -
+// поля через запятую, иначе ""
+func errorColumn(err error) string {
+    var validationErr *ValidationError
+    if !errors.As(err, &validationErr) {
+        return ""
+    }
+    fields := make([]string, len(validationErr.Errors))
+    for i, fieldErr := range validationErr.Errors {
+        fields[i] = fieldErr.Field
+    }
+    return strings.Join(fields, ", ")
+}
+
+// Итоги импорта по исходам. Errors заполняется только в мягком режиме,
+// Notes - замечания к записанным книгам (например, обрезанные поля)
+type ImportSummary struct {
+    Added      int
+    Updated    int
+    Unchanged  int
+    Skipped    int
+    Renumbered int
+    Errors     []ImportError
+    Notes      []ImportError
+}
+
+// O(1)
+func (s *ImportSummary) add(outcome ImportOutcome) {
+    switch outcome {
+    case OutcomeAdded:
+        s.Added++
+    case OutcomeUpdated:
+        s.Updated++
+    case OutcomeUnchanged:
+        s.Unchanged++
+    case OutcomeSkipped:
+        s.Skipped++
+    case OutcomeRenumbered:
+        s.Renumbered++
+    }
+}
+
+// O(1) количество записанных книг
+func (s ImportSummary) Total() int {
+    return s.Added + s.Updated + s.Renumbered
+}
+
+func (s ImportSummary) String() string {
+    var parts []string
+    add := func(name string, count int) {
+        if count > 0 {
+            parts = append(parts, fmt.Sprintf("%s: %d", name, count))
+        }
+    }
+    add("Добавлено", s.Added)
+    add("Обновлено", s.Updated)
+    add("Добавлено с новым ID", s.Renumbered)
+    add("Без изменений", s.Unchanged)
+    add("Пропущено", s.Skipped)
+    add("Строк с ошибками", len(s.Errors))
+    add("Записей с замечаниями", len(s.Notes))
+    if len(parts) == 0 {
+        return "Книг в файле нет"
+    }
+    return strings.Join(parts, "\n")
+}
+
+// O(1) ошибка строки: в мягком режиме она попадает в отчет и импорт
+// продолжается (nil), иначе возвращается. ConflictFail прерывает импорт всегда
+func (s *ImportSummary) reject(opts ImportOptions, importErr ImportError) error {
+    var conflict *ConflictError
+    if !opts.Lenient || errors.As(importErr.err, &conflict) {
+        return &importErr
+    }
+    s.Errors = append(s.Errors, importErr)
+    return nil
+}
+
+// O(1) ошибка записи книги из строки line
+func (s *ImportSummary) rejectBook(opts ImportOptions, line int, raw string, err error) error {
+    return s.reject(opts, ImportError{Line: line, Column: errorColumn(err), Reason: err.Error(), Raw: raw, err: err})
+}
+
+// O(e) отчет об ошибках импорта в CSV (UTF-8 с BOM, разделитель ";"),
+// чтобы его можно было открыть в Excel и исправить строки
+func WriteImportErrors(filename string, importErrors []ImportError) error {
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    file.WriteString("\uFEFF")
+    writer := csv.NewWriter(file)
+    writer.Comma = ';'
+    writer.Write([]string{"Строка", "Поле", "Причина", "Содержимое строки"})
+    for _, importErr := range importErrors {
+        writer.Write([]string{strconv.Itoa(importErr.Line), importErr.Column, importErr.Reason, importErr.Raw})
+    }
+    writer.Flush()
+    if err := writer.Error(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(1) заполненные поля книги из файла поверх книги из базы.
+// Для чисел "не заполнено" - ноль
+func mergeBook(old, book BookView) BookView {
+    merged := old
+    if strings.TrimSpace(book.Title) != "" {
+        merged.Title = book.Title
+    }
+    if strings.TrimSpace(book.Author) != "" {
+        merged.Author = book.Author
+    }
+    if book.Year != 0 {
+        merged.Year = book.Year
+    }
+    if book.Copies != 0 {
+        merged.Copies = book.Copies
+    }
+    return merged
+}
+
+// O(k) k - экземпляры книги. Книга в том виде, в каком ее запишет UpdateBook:
+// с каноническим автором и тиражом по учтенным экземплярам
+func (db *Database) storedForm(book BookView) (BookView, error) {
+    book.Author = db.CanonicalAuthor(book.Author)
+    if db.HasItems(book.ID) {
+        count, err := db.countedItems(book.ID)
+        if err != nil {
+            return book, err
+        }
+        book.Copies = count
+    }
+    return book, nil
+}
+
+// O(1) в среднем + проверка книги. Книга без ID или с новым ID добавляется,
+// конфликт с существующей книгой (или книгой в корзине) решается по opts.
+// Возвращает ID, под которым книга оказалась в базе (0 - пропущена).
+// Ошибки AddBook и UpdateBook оборачиваются через %w: errorColumn достает
+// из них *ValidationError для столбца отчета. ConflictError возвращается как есть
+func (db *Database) importBook(book BookView, opts ImportOptions, summary *ImportSummary) (int32, error) {
+    _, exists := db.idIndex[book.ID]
+    conflict := book.ID != 0 && (exists || db.InTrash(book.ID))
+    if !conflict {
+        id, err := db.AddBook(book)
+        if err != nil {
+            return 0, fmt.Errorf("ошибка добавления книги: %w", err)
+        }
+        summary.add(OutcomeAdded)
+        return id, nil
+    }
+
+    switch opts.OnConflict {
+    case ConflictSkip:
+        summary.add(OutcomeSkipped)
+        return 0, nil
+    case ConflictFail:
+        return 0, &ConflictError{ID: book.ID}
+    case ConflictRenumber:
+        book.ID = 0
+        id, err := db.AddBook(book)
+        if err != nil {
+            return 0, fmt.Errorf("ошибка добавления книги: %w", err)
+        }
+        summary.add(OutcomeRenumbered)
+        return id, nil
+    }
+
+    if !exists {
+        return 0, fmt.Errorf("книга с ID %d лежит в корзине", book.ID)
+    }
+    current, err := db.FindByID(book.ID)
+    if err != nil {
+        return 0, err
+    }
+    old := current.ToView()
+    if opts.OnConflict == ConflictMerge {
+        book = mergeBook(old, book)
+    }
+    stored, err := db.storedForm(book)
+    if err != nil {
+        return 0, err
+    }
+    if stored == old {
+        summary.add(OutcomeUnchanged)
+        return book.ID, nil
+    }
+    if err := db.UpdateBook(book); err != nil {
+        return 0, fmt.Errorf("ошибка обновления книги: %w", err)
+    }
+    summary.add(OutcomeUpdated)
+    return book.ID, nil
+}
\ No newline at end of file
@@ -0,0 +1,161 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "strings"
+)
+
+// ISBN издания хранится отдельно от books.db, чтобы не менять формат записи книги.
+// Запись в isbn.db:
+// ID   int32    - 4 байта
+// ISBN [13]byte - 13 байт, без дефисов
+const isbnRecordSize = 17
+
+// O(1)
+func isbnToBytes(id int32, isbn string) []byte {
+    buf := make([]byte, isbnRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(id))
+    copy(buf[4:17], isbn)
+    return buf
+}
+
+// O(1)
+func bytesToISBN(data []byte) (int32, string) {
+    return int32(binary.LittleEndian.Uint32(data[0:4])), bytesToString(data[4:17])
+}
+
+// O(n) n - книг с ISBN
+func (db *Database) loadISBNs() error {
+    db.isbnIndex = make(map[int32]string)
+    db.isbnPositions = make(map[int32]int64)
+    return db.isbns.scan(func(position int64, data []byte) {
+        id, isbn := bytesToISBN(data)
+        db.isbnIndex[id] = isbn
+        db.isbnPositions[id] = position
+    })
+}
+
+// O(1) ISBN-10 или ISBN-13 без дефисов и пробелов, X - заглавная.
+// Контрольная цифра проверяется
+func NormalizeISBN(isbn string) (string, error) {
+    code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(isbn)))
+    digits := func(s string) bool {
+        for _, c := range s {
+            if c < '0' || c > '9' {
+                return false
+            }
+        }
+        return true
+    }
+
+    switch len(code) {
+    case 10:
+        if !digits(code[:9]) || !(digits(code[9:]) || code[9] == 'X') {
+            return "", fmt.Errorf("неверный ISBN-10: %s", isbn)
+        }
+        sum := 0
+        for i := 0; i < 9; i++ {
+            sum += int(code[i]-'0') * (10 - i)
+        }
+        check := 10
+        if code[9] != 'X' {
+            check = int(code[9] - '0')
+        }
+        if (sum+check)%11 != 0 {
+            return "", fmt.Errorf("неверная контрольная цифра ISBN-10: %s", isbn)
+        }
+        return code, nil
+    case 13:
+        if !digits(code) || (!strings.HasPrefix(code, "978") && !strings.HasPrefix(code, "979")) {
+            return "", fmt.Errorf("ISBN-13 - 13 цифр, начинается с 978 или 979: %s", isbn)
+        }
+        sum := 0
+        for i := 0; i < 12; i++ {
+            weight := 1
+            if i%2 == 1 {
+                weight = 3
+            }
+            sum += int(code[i]-'0') * weight
+        }
+        if int(code[12]-'0') != (10-sum%10)%10 {
+            return "", fmt.Errorf("неверная контрольная цифра ISBN-13: %s", isbn)
+        }
+        return code, nil
+    }
+    return "", fmt.Errorf("ISBN должен содержать 10 или 13 символов: %s", isbn)
+}
+
+// O(1) пустой ISBN убирает его у книги
+func (db *Database) SetISBN(id int32, isbn string) error {
+    if _, exists := db.idIndex[id]; !exists {
+        return fmt.Errorf("книга с ID %d не найдена", id)
+    }
+    if strings.TrimSpace(isbn) == "" {
+        return db.removeISBN(id)
+    }
+    code, err := NormalizeISBN(isbn)
+    if err != nil {
+        return err
+    }
+
+    record := isbnToBytes(id, code)
+    if position, exists := db.isbnPositions[id]; exists {
+        if err := db.isbns.write(position, record); err != nil {
+            return fmt.Errorf("ошибка записи ISBN: %v", err)
+        }
+    } else {
+        position, err := db.isbns.insert(record)
+        if err != nil {
+            return fmt.Errorf("ошибка записи ISBN: %v", err)
+        }
+        db.isbnPositions[id] = position
+    }
+
+    db.isbnIndex[id] = code
+    return nil
+}
+
+// O(1) "" - ISBN у книги не указан
+func (db *Database) GetISBN(id int32) string {
+    return db.isbnIndex[id]
+}
+
+// O(1)
+func (db *Database) removeISBN(id int32) error {
+    position, exists := db.isbnPositions[id]
+    if !exists {
+        return nil
+    }
+    if err := db.isbns.remove(position); err != nil {
+        return fmt.Errorf("ошибка удаления ISBN: %v", err)
+    }
+    delete(db.isbnPositions, id)
+    delete(db.isbnIndex, id)
+    return nil
+}
+
+// O(1) при смене ID ISBN переезжает вместе с книгой.
+// Возвращает функцию отката переезда
+func (db *Database) moveISBN(oldID, newID int32) (func() error, error) {
+    isbn, exists := db.isbnIndex[oldID]
+    if !exists {
+        return func() error { return nil }, nil
+    }
+
+    rewrite := []recordRewrite{{position: db.isbnPositions[oldID], old: isbnToBytes(oldID, isbn), new: isbnToBytes(newID, isbn)}}
+    if err := db.isbns.rewriteAll(rewrite); err != nil {
+        return nil, fmt.Errorf("ошибка записи ISBN: %v", err)
+    }
+
+    moveKey(db.isbnIndex, oldID, newID)
+    moveKey(db.isbnPositions, oldID, newID)
+    return func() error {
+        if err := db.isbns.revertAll(rewrite); err != nil {
+            return err
+        }
+        moveKey(db.isbnIndex, newID, oldID)
+        moveKey(db.isbnPositions, newID, oldID)
+        return nil
+    }, nil
+}
\ No newline at end of file
@@ -0,0 +1,512 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Запись в items.db - один физический экземпляр книги:
+// Barcode   [24]byte - 24 байта, ключ
+// BookID    int32    - 4 байта
+// Shelf     [28]byte - 28 байт
+// Condition int32    - 4 байта
+// Status    int32    - 4 байта
+const itemRecordSize = 64
+
+// Автоматические штрихкоды: 12 цифр с префиксом 2 (внутренний диапазон EAN),
+// контрольную цифру добавляет печать этикетки
+const generatedBarcodePrefix = "2"
+const generatedBarcodeLength = 12
+
+type ItemCondition int32
+
+const (
+    ConditionNew     ItemCondition = 1
+    ConditionGood    ItemCondition = 2
+    ConditionWorn    ItemCondition = 3
+    ConditionDamaged ItemCondition = 4
+)
+
+func (c ItemCondition) String() string {
+    switch c {
+    case ConditionNew:
+        return "Новый"
+    case ConditionGood:
+        return "Хороший"
+    case ConditionWorn:
+        return "Потрепанный"
+    case ConditionDamaged:
+        return "Поврежден"
+    }
+    return "?"
+}
+
+type ItemStatus int32
+
+const (
+    ItemInCirculation ItemStatus = 1 // в фонде, можно выдавать
+    ItemInRepair      ItemStatus = 2
+    ItemLost          ItemStatus = 3
+    ItemWithdrawn     ItemStatus = 4 // списан
+)
+
+func (s ItemStatus) String() string {
+    switch s {
+    case ItemInCirculation:
+        return "В фонде"
+    case ItemInRepair:
+        return "На ремонте"
+    case ItemLost:
+        return "Утерян"
+    case ItemWithdrawn:
+        return "Списан"
+    }
+    return "?"
+}
+
+var AllItemConditions = []ItemCondition{ConditionNew, ConditionGood, ConditionWorn, ConditionDamaged}
+var AllItemStatuses = []ItemStatus{ItemInCirculation, ItemInRepair, ItemLost, ItemWithdrawn}
+
+type ItemView struct {
+    Barcode   string        `json:"barcode"`
+    BookID    int32         `json:"book_id"`
+    Shelf     string        `json:"shelf"`
+    Condition ItemCondition `json:"condition"`
+    Status    ItemStatus    `json:"status"`
+}
+
+// O(1)
+func itemToBytes(item ItemView) []byte {
+    buf := make([]byte, itemRecordSize)
+    copyStringToBytes(item.Barcode, buf[0:24])
+    binary.LittleEndian.PutUint32(buf[24:28], uint32(item.BookID))
+    copyStringToBytes(item.Shelf, buf[28:56])
+    binary.LittleEndian.PutUint32(buf[56:60], uint32(item.Condition))
+    binary.LittleEndian.PutUint32(buf[60:64], uint32(item.Status))
+    return buf
+}
+
+// O(1)
+func bytesToItem(data []byte) ItemView {
+    return ItemView{
+        Barcode:   bytesToString(data[0:24]),
+        BookID:    int32(binary.LittleEndian.Uint32(data[24:28])),
+        Shelf:     bytesToString(data[28:56]),
+        Condition: ItemCondition(binary.LittleEndian.Uint32(data[56:60])),
+        Status:    ItemStatus(binary.LittleEndian.Uint32(data[60:64])),
+    }
+}
+
+// O(n) n - количество экземпляров
+func (db *Database) loadItems() error {
+    db.itemIndex = make(map[string]int64)
+    db.bookItems = make(map[int32][]string)
+    db.nextBarcode = 1
+    return db.items.scan(func(position int64, data []byte) {
+        item := bytesToItem(data)
+        db.itemIndex[item.Barcode] = position
+        db.bookItems[item.BookID] = append(db.bookItems[item.BookID], item.Barcode)
+
+        if number, ok := parseGeneratedBarcode(item.Barcode); ok && number >= db.nextBarcode {
+            db.nextBarcode = number + 1
+        }
+    })
+}
+
+// "200000000042" -> 42
+func parseGeneratedBarcode(barcode string) (int64, bool) {
+    if len(barcode) != generatedBarcodeLength || !strings.HasPrefix(barcode, generatedBarcodePrefix) {
+        return 0, false
+    }
+    number, err := strconv.ParseInt(barcode[len(generatedBarcodePrefix):], 10, 64)
+    if err != nil {
+        return 0, false
+    }
+    return number, true
+}
+
+// O(1) следующий свободный автоматический штрихкод
+func (db *Database) generateBarcode() string {
+    for {
+        barcode := fmt.Sprintf("%s%0*d", generatedBarcodePrefix,
+            generatedBarcodeLength-len(generatedBarcodePrefix), db.nextBarcode)
+        db.nextBarcode++
+        if _, exists := db.itemIndex[barcode]; !exists {
+            return barcode
+        }
+    }
+}
+
+// O(1)
+func validateItem(item ItemView) error {
+    if item.Barcode == "" {
+        return fmt.Errorf("штрихкод не может быть пустым")
+    }
+    if len([]byte(item.Barcode)) > 24 {
+        return fmt.Errorf("штрихкод '%s' длиннее 24 байт", item.Barcode)
+    }
+    if strings.ContainsAny(item.Barcode, " \t") {
+        return fmt.Errorf("штрихкод '%s' не должен содержать пробелов", item.Barcode)
+    }
+    if len([]byte(item.Shelf)) > 28 {
+        return fmt.Errorf("место на полке длиннее 28 байт")
+    }
+    if item.Condition < ConditionNew || item.Condition > ConditionDamaged {
+        return fmt.Errorf("неизвестное состояние экземпляра: %d", item.Condition)
+    }
+    if item.Status < ItemInCirculation || item.Status > ItemWithdrawn {
+        return fmt.Errorf("неизвестный статус экземпляра: %d", item.Status)
+    }
+    return nil
+}
+
+// O(k) k - экземпляры книги. Тираж книги с экземплярами - это число экземпляров в фонде
+func (db *Database) countedItems(bookID int32) (int32, error) {
+    var count int32
+    for _, barcode := range db.bookItems[bookID] {
+        item, err := db.FindItem(barcode)
+        if err != nil {
+            return 0, err
+        }
+        if item.Status == ItemInCirculation {
+            count++
+        }
+    }
+    return count, nil
+}
+
+// O(1) выдать экземпляров больше, чем останется в фонде, нельзя
+func (db *Database) checkItemCount(bookID int32, count int32) error {
+    if active := int32(db.activeByBook[bookID]); count < active {
+        return fmt.Errorf("в фонде останется %d экземпляров, а выдано %d", count, active)
+    }
+    return nil
+}
+
+// O(k) переписываем тираж книги по ее экземплярам
+func (db *Database) syncCopies(bookID int32) error {
+    position, exists := db.idIndex[bookID]
+    if !exists {
+        return nil
+    }
+    count, err := db.countedItems(bookID)
+    if err != nil {
+        return err
+    }
+
+    book, err := db.readRecord(position)
+    if err != nil {
+        return err
+    }
+    if book.Copies == count {
+        return nil
+    }
+
+    before := viewRef(book)
+    grew := count > book.Copies
+    book.Copies = count
+    if err := db.writeRecord(book, position); err != nil {
+        return err
+    }
+    if err := db.logAudit(AuditUpdate, bookID, before, viewRef(book)); err != nil {
+        return err
+    }
+    if grew {
+        return db.promoteHolds(bookID)
+    }
+    return nil
+}
+
+// O(k) пустой штрихкод - сгенерировать, возвращает штрихкод экземпляра
+func (db *Database) AddItem(item ItemView) (string, error) {
+    barcodes, err := db.AddItems([]ItemView{item})
+    if err != nil {
+        return "", err
+    }
+    return barcodes[0], nil
+}
+
+// O(m + k) m - новых экземпляров. Экземпляры добавляются вместе: первые
+// экземпляры заменяют тираж книги их числом, поэтому книгу, которой выдано
+// несколько экземпляров, можно перевести на учет экземпляров только разом.
+// Возвращает штрихкоды в порядке items
+func (db *Database) AddItems(items []ItemView) ([]string, error) {
+    items = append([]ItemView(nil), items...)
+    // будущий фонд каждой книги
+    counts := make(map[int32]int32)
+    barcodes := make([]string, len(items))
+    fresh := make(map[string]bool)
+    for i := range items {
+        item := &items[i]
+        if _, exists := db.idIndex[item.BookID]; !exists {
+            return nil, fmt.Errorf("книга с ID %d не найдена", item.BookID)
+        }
+
+        item.Barcode = strings.TrimSpace(item.Barcode)
+        item.Shelf = strings.TrimSpace(item.Shelf)
+        if item.Barcode == "" {
+            item.Barcode = db.generateBarcode()
+        }
+        if item.Condition == 0 {
+            item.Condition = ConditionNew
+        }
+        if item.Status == 0 {
+            item.Status = ItemInCirculation
+        }
+        if err := validateItem(*item); err != nil {
+            return nil, err
+        }
+        if _, exists := db.itemIndex[item.Barcode]; exists || fresh[item.Barcode] {
+            return nil, fmt.Errorf("экземпляр со штрихкодом %s уже существует", item.Barcode)
+        }
+        fresh[item.Barcode] = true
+        barcodes[i] = item.Barcode
+
+        if _, counted := counts[item.BookID]; !counted {
+            count, err := db.countedItems(item.BookID)
+            if err != nil {
+                return nil, err
+            }
+            counts[item.BookID] = count
+        }
+        if item.Status == ItemInCirculation {
+            counts[item.BookID]++
+        }
+    }
+    for bookID, count := range counts {
+        if err := db.checkItemCount(bookID, count); err != nil {
+            return nil, fmt.Errorf("книга с ID %d: %v - добавьте экземпляры, которые сейчас у читателей, вместе с этими", bookID, err)
+        }
+    }
+
+    for i, item := range items {
+        position, err := db.items.insert(itemToBytes(item))
+        if err != nil {
+            // убираем уже записанные, тираж еще не менялся
+            for _, added := range items[:i] {
+                db.items.remove(db.itemIndex[added.Barcode])
+                delete(db.itemIndex, added.Barcode)
+                db.bookItems[added.BookID] = removeString(db.bookItems[added.BookID], added.Barcode)
+                if len(db.bookItems[added.BookID]) == 0 {
+                    delete(db.bookItems, added.BookID)
+                }
+            }
+            return nil, fmt.Errorf("ошибка записи экземпляра: %v", err)
+        }
+        db.itemIndex[item.Barcode] = position
+        db.bookItems[item.BookID] = append(db.bookItems[item.BookID], item.Barcode)
+    }
+
+    for _, item := range items {
+        if _, pending := counts[item.BookID]; !pending {
+            continue
+        }
+        delete(counts, item.BookID)
+        if err := db.syncCopies(item.BookID); err != nil {
+            return barcodes, err
+        }
+    }
+    return barcodes, nil
+}
+
+// O(k) штрихкод - ключ, остальные поля (включая книгу) можно менять
+func (db *Database) UpdateItem(item ItemView) error {
+    item.Shelf = strings.TrimSpace(item.Shelf)
+    old, err := db.FindItem(item.Barcode)
+    if err != nil {
+        return err
+    }
+    if _, exists := db.idIndex[item.BookID]; !exists {
+        return fmt.Errorf("книга с ID %d не найдена", item.BookID)
+    }
+    if err := validateItem(item); err != nil {
+        return err
+    }
+
+    // экземпляр уходит из фонда старой книги
+    if old.Status == ItemInCirculation && (item.Status != ItemInCirculation || item.BookID != old.BookID) {
+        count, err := db.countedItems(old.BookID)
+        if err != nil {
+            return err
+        }
+        if err := db.checkItemCount(old.BookID, count-1); err != nil {
+            return err
+        }
+    }
+
+    // первый экземпляр другой книги заменяет ее тираж
+    if item.BookID != old.BookID {
+        count, err := db.countedItems(item.BookID)
+        if err != nil {
+            return err
+        }
+        if item.Status == ItemInCirculation {
+            count++
+        }
+        if err := db.checkItemCount(item.BookID, count); err != nil {
+            return err
+        }
+    }
+
+    if err := db.items.write(db.itemIndex[item.Barcode], itemToBytes(item)); err != nil {
+        return fmt.Errorf("ошибка записи экземпляра: %v", err)
+    }
+
+    if item.BookID != old.BookID {
+        db.bookItems[old.BookID] = removeString(db.bookItems[old.BookID], item.Barcode)
+        if len(db.bookItems[old.BookID]) == 0 {
+            delete(db.bookItems, old.BookID)
+        }
+        db.bookItems[item.BookID] = append(db.bookItems[item.BookID], item.Barcode)
+        if err := db.syncCopies(old.BookID); err != nil {
+            return err
+        }
+    }
+    return db.syncCopies(item.BookID)
+}
+
+// O(k)
+func (db *Database) DeleteItem(barcode string) error {
+    item, err := db.FindItem(barcode)
+    if err != nil {
+        return err
+    }
+    if item.Status == ItemInCirculation {
+        count, err := db.countedItems(item.BookID)
+        if err != nil {
+            return err
+        }
+        if err := db.checkItemCount(item.BookID, count-1); err != nil {
+            return err
+        }
+    }
+
+    if err := db.items.remove(db.itemIndex[barcode]); err != nil {
+        return fmt.Errorf("ошибка удаления экземпляра: %v", err)
+    }
+    delete(db.itemIndex, barcode)
+    db.bookItems[item.BookID] = removeString(db.bookItems[item.BookID], barcode)
+    if len(db.bookItems[item.BookID]) == 0 {
+        delete(db.bookItems, item.BookID)
+    }
+    return db.syncCopies(item.BookID)
+}
+
+// O(1) в среднем
+func (db *Database) FindItem(barcode string) (ItemView, error) {
+    position, exists := db.itemIndex[strings.TrimSpace(barcode)]
+    if !exists {
+        return ItemView{}, fmt.Errorf("экземпляр со штрихкодом %s не найден", barcode)
+    }
+    data, err := db.items.read(position)
+    if err != nil {
+        return ItemView{}, err
+    }
+    return bytesToItem(data), nil
+}
+
+// O(1) в среднем. Поиск книги по штрихкоду экземпляра
+func (db *Database) FindByBarcode(barcode string) (ItemView, BookView, error) {
+    item, err := db.FindItem(barcode)
+    if err != nil {
+        return ItemView{}, BookView{}, err
+    }
+    book, err := db.FindByID(item.BookID)
+    if err != nil {
+        return ItemView{}, BookView{}, err
+    }
+    return item, book.ToView(), nil
+}
+
+// O(k log k) экземпляры книги по штрихкоду
+func (db *Database) GetItems(bookID int32) ([]ItemView, error) {
+    items := make([]ItemView, 0, len(db.bookItems[bookID]))
+    for _, barcode := range db.bookItems[bookID] {
+        item, err := db.FindItem(barcode)
+        if err != nil {
+            return nil, err
+        }
+        items = append(items, item)
+    }
+
+    sort.Slice(items, func(i, j int) bool {
+        return items[i].Barcode < items[j].Barcode
+    })
+    return items, nil
+}
+
+// O(1) у книги есть учтенные экземпляры, и тираж считается по ним
+func (db *Database) HasItems(bookID int32) bool {
+    return len(db.bookItems[bookID]) > 0
+}
+
+// O(n) исправляет тираж книг, у которых он разошелся с экземплярами
+// (например, после правки файлов вручную), возвращает число исправленных книг
+func (db *Database) ReconcileCopies() (int, error) {
+    fixed := 0
+    for bookID := range db.bookItems {
+        position, exists := db.idIndex[bookID]
+        if !exists {
+            continue
+        }
+        book, err := db.readRecord(position)
+        if err != nil {
+            return fixed, err
+        }
+        count, err := db.countedItems(bookID)
+        if err != nil {
+            return fixed, err
+        }
+        if book.Copies == count {
+            continue
+        }
+        if err := db.syncCopies(bookID); err != nil {
+            return fixed, err
+        }
+        fixed++
+    }
+    return fixed, nil
+}
+
+// O(k) при удалении книги удаляются и ее экземпляры
+func (db *Database) removeBookItems(bookID int32) error {
+    for _, barcode := range append([]string(nil), db.bookItems[bookID]...) {
+        if err := db.items.remove(db.itemIndex[barcode]); err != nil {
+            return fmt.Errorf("ошибка удаления экземпляра: %v", err)
+        }
+        delete(db.itemIndex, barcode)
+    }
+    delete(db.bookItems, bookID)
+    return nil
+}
+
+// O(k) экземпляры переезжают вместе с книгой при смене ID.
+// Возвращает функцию отката переезда
+func (db *Database) moveItems(oldID, newID int32) (func() error, error) {
+    var rewrites []recordRewrite
+    for _, barcode := range db.bookItems[oldID] {
+        position := db.itemIndex[barcode]
+        data, err := db.items.read(position)
+        if err != nil {
+            return nil, err
+        }
+        item := bytesToItem(data)
+        item.BookID = newID
+        rewrites = append(rewrites, recordRewrite{position: position, old: data, new: itemToBytes(item)})
+    }
+    if err := db.items.rewriteAll(rewrites); err != nil {
+        return nil, fmt.Errorf("ошибка записи экземпляра: %v", err)
+    }
+
+    moveKey(db.bookItems, oldID, newID)
+    return func() error {
+        if err := db.items.revertAll(rewrites); err != nil {
+            return err
+        }
+        moveKey(db.bookItems, newID, oldID)
+        return nil
+    }, nil
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+)
+
+// Настройки импорта JSON. Поля книги - теги json у BookView
+// ("id", "title", "author", "year", "copies"); ID 0 или его отсутствие -
+// назначить автоматически
+type JSONOptions struct {
+    // незнакомые поля - ошибка; иначе они пропускаются
+    Strict bool
+    ImportOptions
+}
+
+// Максимальная длина одной строки JSONL
+const maxJSONLine = 1 << 20
+
+// O(n) пишет книги по одной, не собирая весь документ в памяти.
+// JSON - массив объектов с отступами, JSONL - по объекту на строку
+func (db *Database) writeJSON(filename string, lines bool) error {
+    books, err := db.GetAllBooks()
+    if err != nil {
+        return fmt.Errorf("ошибка получения книг: %v", err)
+    }
+
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    writer := bufio.NewWriter(file)
+    if !lines {
+        writer.WriteString("[")
+    }
+    for i, book := range books {
+        var data []byte
+        if lines {
+            data, err = json.Marshal(book)
+        } else {
+            data, err = json.MarshalIndent(book, "  ", "  ")
+        }
+        if err != nil {
+            return fmt.Errorf("ошибка записи книги %d: %v", book.ID, err)
+        }
+
+        if !lines {
+            if i > 0 {
+                writer.WriteString(",")
+            }
+            writer.WriteString("\n  ")
+        }
+        writer.Write(data)
+        if lines {
+            writer.WriteString("\n")
+        }
+    }
+    if !lines {
+        writer.WriteString("\n]\n")
+    }
+
+    if err := writer.Flush(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(n)
+func (db *Database) ExportToJSON(filename string) error {
+    return db.writeJSON(filename, false)
+}
+
+// O(n)
+func (db *Database) ExportToJSONL(filename string) error {
+    return db.writeJSON(filename, true)
+}
+
+// O(m) m - длина записи. Книга из одного JSON-объекта; при ошибке типа
+// возвращает и поле, в котором она найдена
+func decodeJSONBook(data []byte, strict bool) (BookView, string, error) {
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    if strict {
+        decoder.DisallowUnknownFields()
+    }
+    var book BookView
+    if err := decoder.Decode(&book); err != nil {
+        var typeErr *json.UnmarshalTypeError
+        if errors.As(err, &typeErr) {
+            return book, typeErr.Field, err
+        }
+        return book, "", err
+    }
+    if decoder.More() {
+        return book, "", fmt.Errorf("больше одного объекта в строке")
+    }
+    return book, "", nil
+}
+
+// O(m) запись в одну строку для отчета об ошибках
+func compactJSON(data []byte) string {
+    var buffer bytes.Buffer
+    if err := json.Compact(&buffer, data); err != nil {
+        return string(data)
+    }
+    return buffer.String()
+}
+
+// O(n) массив читается потоково: в памяти всегда одна книга, она передается
+// в fn. Line - номер книги в массиве. Синтаксическая ошибка прерывает чтение
+func eachJSONBook(filename string, strict bool, fn func(row importRow) error) error {
+    file, err := os.Open(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer file.Close()
+
+    decoder := json.NewDecoder(bufio.NewReader(file))
+    if strict {
+        decoder.DisallowUnknownFields()
+    }
+
+    token, err := decoder.Token()
+    if err != nil {
+        return fmt.Errorf("ошибка разбора JSON: %v", err)
+    }
+    if delim, ok := token.(json.Delim); !ok || delim != '[' {
+        return fmt.Errorf("ошибка разбора JSON: ожидался массив книг")
+    }
+
+    for index := 1; decoder.More(); index++ {
+        // синтаксическая ошибка ломает разбор всего массива - дальше не читаем
+        var raw json.RawMessage
+        if err := decoder.Decode(&raw); err != nil {
+            return fmt.Errorf("ошибка в книге %d: %v", index, err)
+        }
+        book, column, err := decodeJSONBook(raw, strict)
+        if err := fn(importRow{Line: index, Raw: compactJSON(raw), Book: book, Column: column, Err: err}); err != nil {
+            return err
+        }
+    }
+
+    if _, err := decoder.Token(); err != nil {
+        return fmt.Errorf("ошибка разбора JSON: %v", err)
+    }
+    if strict {
+        if _, err := decoder.Token(); err != io.EOF {
+            return fmt.Errorf("ошибка разбора JSON: лишние данные после массива")
+        }
+    }
+    return nil
+}
+
+// O(n) массив читается потоково (при ConflictFail - дважды, конфликты ID
+// ищутся до первой записи). В отчете мягкого режима Line - номер книги в массиве
+func (db *Database) ImportFromJSON(filename string, opts JSONOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    if opts.OnConflict == ConflictFail {
+        scan := db.newConflictScan()
+        err := eachJSONBook(filename, opts.Strict, func(row importRow) error {
+            if row.Err != nil {
+                return nil
+            }
+            if err := scan.check(row.Book.ID); err != nil {
+                return fmt.Errorf("ошибка в книге %d: %v", row.Line, err)
+            }
+            return nil
+        })
+        if err != nil {
+            return summary, err
+        }
+    }
+    defer db.auditAs(AuditImport)()
+
+    err := eachJSONBook(filename, opts.Strict, func(row importRow) error {
+        if row.Err != nil {
+            if summary.reject(opts.ImportOptions, row.importError()) != nil {
+                return fmt.Errorf("ошибка в книге %d: %v", row.Line, row.Err)
+            }
+            return nil
+        }
+        if _, err := db.importBook(row.Book, opts.ImportOptions, &summary); err != nil {
+            if summary.rejectBook(opts.ImportOptions, row.Line, row.Raw, err) != nil {
+                return fmt.Errorf("ошибка в книге %d: %v", row.Line, err)
+            }
+        }
+        return nil
+    })
+    return summary, err
+}
+
+// O(n) по книге на строку, пустые строки пропускаются. Книги по очереди
+// передаются в fn
+func eachJSONLBook(filename string, strict bool, fn func(row importRow) error) error {
+    file, err := os.Open(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer file.Close()
+
+    scanner := bufio.NewScanner(file)
+    scanner.Buffer(make([]byte, 64*1024), maxJSONLine)
+
+    lineNumber := 0
+    for scanner.Scan() {
+        lineNumber++
+        line := bytes.TrimSpace(scanner.Bytes())
+        if len(line) == 0 {
+            continue
+        }
+
+        book, column, err := decodeJSONBook(line, strict)
+        if err := fn(importRow{Line: lineNumber, Raw: string(line), Book: book, Column: column, Err: err}); err != nil {
+            return err
+        }
+    }
+
+    if err := scanner.Err(); err != nil {
+        return fmt.Errorf("ошибка чтения файла: %v", err)
+    }
+    return nil
+}
+
+// O(n) по книге на строку, пустые строки пропускаются. В мягком режиме
+// ошибочные строки не прерывают импорт
+func (db *Database) ImportFromJSONL(filename string, opts JSONOptions) (ImportSummary, error) {
+    var summary ImportSummary
+    if opts.OnConflict == ConflictFail {
+        if err := eachJSONLBook(filename, opts.Strict, db.newConflictScan().checkRow); err != nil {
+            return summary, err
+        }
+    }
+    defer db.auditAs(AuditImport)()
+
+    err := eachJSONLBook(filename, opts.Strict, func(row importRow) error {
+        if row.Err != nil {
+            return summary.reject(opts.ImportOptions, row.importError())
+        }
+        if _, err := db.importBook(row.Book, opts.ImportOptions, &summary); err != nil {
+            return summary.rejectBook(opts.ImportOptions, row.Line, row.Raw, err)
+        }
+        return nil
+    })
+    return summary, err
+}
\ No newline at end of file
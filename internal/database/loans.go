@@ -0,0 +1,249 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "sort"
+    "time"
+)
+
+// Запись в loans.db (даты - unix-время в секундах, 0 - не задана):
+// ID       int32 - 4 байта
+// BookID   int32 - 4 байта
+// MemberID int32 - 4 байта
+// Issued   int64 - 8 байт
+// Due      int64 - 8 байт
+// Returned int64 - 8 байт
+const loanRecordSize = 36
+
+type LoanView struct {
+    ID       int32     `json:"id"`
+    BookID   int32     `json:"book_id"`
+    MemberID int32     `json:"member_id"`
+    Issued   time.Time `json:"issued"`
+    Due      time.Time `json:"due"`
+    Returned time.Time `json:"returned"`
+}
+
+// O(1)
+func (l LoanView) Active() bool {
+    return l.Returned.IsZero()
+}
+
+// O(1)
+func loanToBytes(loan LoanView) []byte {
+    buf := make([]byte, loanRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(loan.ID))
+    binary.LittleEndian.PutUint32(buf[4:8], uint32(loan.BookID))
+    binary.LittleEndian.PutUint32(buf[8:12], uint32(loan.MemberID))
+    binary.LittleEndian.PutUint64(buf[12:20], uint64(timeToUnix(loan.Issued)))
+    binary.LittleEndian.PutUint64(buf[20:28], uint64(timeToUnix(loan.Due)))
+    binary.LittleEndian.PutUint64(buf[28:36], uint64(timeToUnix(loan.Returned)))
+    return buf
+}
+
+// O(1)
+func bytesToLoan(data []byte) LoanView {
+    return LoanView{
+        ID:       int32(binary.LittleEndian.Uint32(data[0:4])),
+        BookID:   int32(binary.LittleEndian.Uint32(data[4:8])),
+        MemberID: int32(binary.LittleEndian.Uint32(data[8:12])),
+        Issued:   unixToTime(int64(binary.LittleEndian.Uint64(data[12:20]))),
+        Due:      unixToTime(int64(binary.LittleEndian.Uint64(data[20:28]))),
+        Returned: unixToTime(int64(binary.LittleEndian.Uint64(data[28:36]))),
+    }
+}
+
+// нулевое время храним как 0, а не как отрицательное unix-время 1 января 1 года
+func timeToUnix(t time.Time) int64 {
+    if t.IsZero() {
+        return 0
+    }
+    return t.Unix()
+}
+
+func unixToTime(seconds int64) time.Time {
+    if seconds == 0 {
+        return time.Time{}
+    }
+    return time.Unix(seconds, 0)
+}
+
+// O(n) n - количество выдач за все время
+func (db *Database) loadLoans() error {
+    db.loanIndex = make(map[int32]int64)
+    db.activeByBook = make(map[int32]int)
+    db.activeByMember = make(map[int32]int)
+    db.nextLoanID = 1
+    return db.loans.scan(func(position int64, data []byte) {
+        loan := bytesToLoan(data)
+        db.loanIndex[loan.ID] = position
+        if loan.Active() {
+            db.activeByBook[loan.BookID]++
+            db.activeByMember[loan.MemberID]++
+        }
+        if loan.ID >= db.nextLoanID {
+            db.nextLoanID = loan.ID + 1
+        }
+        db.reserveMemberID(loan.MemberID)
+    })
+}
+
+// O(1) в среднем. Доступно = тираж минус невозвращенные экземпляры
+// и экземпляры, отложенные по готовым броням
+func (db *Database) AvailableCopies(bookID int32) (int32, error) {
+    book, err := db.FindByID(bookID)
+    if err != nil {
+        return 0, err
+    }
+    return book.Copies - int32(db.activeByBook[bookID]) - int32(db.readyByBook[bookID]), nil
+}
+
+// O(1) в среднем
+func (db *Database) ActiveLoansOfBook(bookID int32) int {
+    return db.activeByBook[bookID]
+}
+
+// O(q) q - длина очереди броней книги, возвращает ID выдачи.
+// Читателю с готовой бронью выдается отложенный для него экземпляр
+func (db *Database) Checkout(bookID, memberID int32, due time.Time) (int32, error) {
+    available, err := db.AvailableCopies(bookID)
+    if err != nil {
+        return 0, err
+    }
+    holdID, hasHold := db.readyHoldOf(bookID, memberID)
+    if available <= 0 && !hasHold {
+        return 0, fmt.Errorf("нет свободных экземпляров книги с ID %d", bookID)
+    }
+    if _, exists := db.memberIndex[memberID]; !exists {
+        return 0, fmt.Errorf("читатель с ID %d не найден", memberID)
+    }
+
+    now := time.Now()
+    if !due.After(now) {
+        return 0, fmt.Errorf("срок возврата должен быть позже даты выдачи")
+    }
+
+    loan := LoanView{
+        ID:       db.nextLoanID,
+        BookID:   bookID,
+        MemberID: memberID,
+        Issued:   now,
+        Due:      due,
+    }
+
+    position, err := db.loans.insert(loanToBytes(loan))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка записи выдачи: %v", err)
+    }
+
+    db.loanIndex[loan.ID] = position
+    db.activeByBook[bookID]++
+    db.activeByMember[memberID]++
+    db.nextLoanID++
+
+    if hasHold {
+        if err := db.closeHold(holdID, HoldFulfilled); err != nil {
+            return 0, err
+        }
+    }
+    return loan.ID, nil
+}
+
+// O(q) q - длина очереди броней книги
+func (db *Database) ReturnLoan(loanID int32) error {
+    loan, err := db.FindLoan(loanID)
+    if err != nil {
+        return err
+    }
+    if !loan.Active() {
+        return fmt.Errorf("выдача %d уже закрыта", loanID)
+    }
+
+    // штраф фиксируем по правилам на момент возврата, иначе он пропадет из отчета
+    loan.Returned = time.Now()
+    undoFine, err := db.accrueFine(loan)
+    if err != nil {
+        return err
+    }
+    if err := db.loans.write(db.loanIndex[loanID], loanToBytes(loan)); err != nil {
+        undoFine()
+        return fmt.Errorf("ошибка записи возврата: %v", err)
+    }
+
+    db.activeByBook[loan.BookID]--
+    if db.activeByBook[loan.BookID] == 0 {
+        delete(db.activeByBook, loan.BookID)
+    }
+    db.activeByMember[loan.MemberID]--
+    if db.activeByMember[loan.MemberID] == 0 {
+        delete(db.activeByMember, loan.MemberID)
+    }
+
+    // вернувшийся экземпляр достается первому в очереди
+    return db.promoteHolds(loan.BookID)
+}
+
+// O(1) в среднем
+func (db *Database) FindLoan(loanID int32) (LoanView, error) {
+    position, exists := db.loanIndex[loanID]
+    if !exists {
+        return LoanView{}, fmt.Errorf("выдача %d не найдена", loanID)
+    }
+    data, err := db.loans.read(position)
+    if err != nil {
+        return LoanView{}, err
+    }
+    return bytesToLoan(data), nil
+}
+
+// O(n log n) n - количество выдач, activeOnly - только невозвращенные
+func (db *Database) GetLoans(activeOnly bool) ([]LoanView, error) {
+    loans := make([]LoanView, 0, len(db.loanIndex))
+    for _, position := range db.loanIndex {
+        data, err := db.loans.read(position)
+        if err != nil {
+            return nil, err
+        }
+        loan := bytesToLoan(data)
+        if activeOnly && !loan.Active() {
+            continue
+        }
+        loans = append(loans, loan)
+    }
+
+    sort.Slice(loans, func(i, j int) bool {
+        return loans[i].ID < loans[j].ID
+    })
+    return loans, nil
+}
+
+// O(n) при смене ID книги ее выдачи переписываются на новый ID.
+// Возвращает функцию отката переезда
+func (db *Database) moveLoans(oldID, newID int32) (func() error, error) {
+    var rewrites []recordRewrite
+    for _, position := range db.loanIndex {
+        data, err := db.loans.read(position)
+        if err != nil {
+            return nil, err
+        }
+        loan := bytesToLoan(data)
+        if loan.BookID != oldID {
+            continue
+        }
+        loan.BookID = newID
+        rewrites = append(rewrites, recordRewrite{position: position, old: data, new: loanToBytes(loan)})
+    }
+    if err := db.loans.rewriteAll(rewrites); err != nil {
+        return nil, fmt.Errorf("ошибка записи выдачи: %v", err)
+    }
+
+    moveKey(db.activeByBook, oldID, newID)
+    return func() error {
+        if err := db.loans.revertAll(rewrites); err != nil {
+            return err
+        }
+        moveKey(db.activeByBook, newID, oldID)
+        return nil
+    }, nil
+}
@@ -0,0 +1,536 @@
+package database
+
+import (
+    "github.com/nydeg/bd/internal/marc"
+    "bufio"
+    "errors"
+    "fmt"
+    "io"
+    "os"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+// Обменный формат MARC 21
+type MARCFormat int
+
+const (
+    MARCISO2709 MARCFormat = iota // двоичный ISO 2709 (.mrc)
+    MARCXML                       // MARCXML (.xml)
+)
+
+// Соответствие полей MARC 21 и книги:
+// 001        - ID (только с KeepIDs, иначе ID назначается автоматически;
+//              конфликты ID решаются по OnConflict)
+// 100 $a     - автор, "Фамилия, Имя" переворачивается в "Имя Фамилия"
+// 245 $a $b  - название
+// 264/260 $c - год издания (если нет - позиции 07-10 поля 008)
+// 650/653 $a - теги
+// 852        - экземпляры: $p штрихкод, $c место на полке. Книга без
+//              учтенных экземпляров выгружается одним 852 с тиражом в $t
+// 020 $a     - ISBN (первый верный)
+// Остальные поля сохранить негде - они попадают в отчет
+type MARCOptions struct {
+    Format  MARCFormat
+    KeepIDs bool
+    ImportOptions
+}
+
+// Отчет по одной записи: что не удалось перенести в книгу
+type MARCReport struct {
+    Record  int // номер записи в файле, с 1
+    BookID  int32
+    Title   string
+    Skipped bool // книга не добавлена, причина в Notes
+    Notes   []string
+}
+
+// O(1)
+func (r *MARCReport) note(format string, args ...interface{}) {
+    r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// Поля, которые переносятся в книгу или в отчет отдельным замечанием
+var marcMappedTags = map[string]bool{
+    "001": true, "003": true, "005": true, "008": true, "020": true,
+    "100": true, "110": true, "111": true, "700": true, "245": true,
+    "260": true, "264": true, "650": true, "651": true, "653": true,
+    "655": true, "852": true, "876": true,
+}
+
+// Книга, теги и экземпляры, разобранные из одной записи
+type marcBook struct {
+    book  BookView
+    isbn  string
+    tags  []string
+    items []ItemView
+}
+
+// O(n) n - длина строки, обрезка по границе символа
+func fitBytes(s string, size int) (string, bool) {
+    if len(s) <= size {
+        return s, false
+    }
+    for len(s) > size {
+        _, width := utf8.DecodeLastRuneInString(s)
+        s = s[:len(s)-width]
+    }
+    return strings.TrimSpace(s), true
+}
+
+// O(n) убирает пунктуацию ISBD в конце подполя: "Название /", "Толстой, Лев,".
+// Точка после инициала ("Иванов, И.") остается
+func trimISBD(s string) string {
+    s = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(s), " /:;,="))
+    if !strings.HasSuffix(s, ".") {
+        return s
+    }
+    rest := s[:len(s)-1]
+    _, width := utf8.DecodeLastRuneInString(rest)
+    before := rest[:len(rest)-width]
+    if before == "" || strings.HasSuffix(before, " ") || strings.HasSuffix(before, ".") {
+        return s
+    }
+    return strings.TrimSpace(rest)
+}
+
+// O(n) "Достоевский, Федор" -> "Федор Достоевский"
+func directName(name string) string {
+    surname, forenames, found := strings.Cut(name, ",")
+    if !found || strings.TrimSpace(forenames) == "" {
+        return strings.TrimSpace(name)
+    }
+    return strings.TrimSpace(forenames) + " " + strings.TrimSpace(surname)
+}
+
+// O(n) "Федор Достоевский" -> "Достоевский, Федор". Одно слово не переворачивается
+func invertName(name string) (string, bool) {
+    name = strings.TrimSpace(name)
+    index := strings.LastIndex(name, " ")
+    if index == -1 {
+        return name, false
+    }
+    return name[index+1:] + ", " + strings.TrimSpace(name[:index]), true
+}
+
+// O(n) первые четыре цифры подряд: "c1998.", "[1866]", "1866-1867"
+func firstYear(s string) (int32, bool) {
+    run := 0
+    for i := 0; i < len(s); i++ {
+        if s[i] >= '0' && s[i] <= '9' {
+            run++
+            if run == 4 && (i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9') {
+                year, _ := strconv.Atoi(s[i-3 : i+1])
+                return int32(year), year > 0
+            }
+            continue
+        }
+        run = 0
+    }
+    return 0, false
+}
+
+// O(1) поле 008 для монографии: дата ввода, тип даты s, год, язык не указан
+func marcFixedField(year int32, entered time.Time) string {
+    // позиции 11-14 - вторая дата, 15-17 - страна, 18-34 - признаки книги, 35-39 - язык и источник
+    return entered.Format("060102") + fmt.Sprintf("s%04d", year) + "    " + "xx " +
+        strings.Repeat(" ", 11) + "000 0 " + "und d"
+}
+
+// O(t + k) t - теги книги, k - ее экземпляры
+func (db *Database) bookToMARC(book BookView) (*marc.Record, error) {
+    record := marc.NewRecord()
+    record.AddControlField("001", strconv.Itoa(int(book.ID)))
+    record.AddControlField("008", marcFixedField(book.Year, time.Now()))
+    if isbn := db.GetISBN(book.ID); isbn != "" {
+        record.AddDataField("020", ' ', ' ', marc.Subfield{Code: 'a', Value: isbn})
+    }
+
+    author, inverted := invertName(book.Author)
+    nameType := byte('0')
+    if inverted {
+        nameType = '1'
+    }
+    record.AddDataField("100", nameType, ' ', marc.Subfield{Code: 'a', Value: author})
+    record.AddDataField("245", '1', '0', marc.Subfield{Code: 'a', Value: book.Title})
+    record.AddDataField("264", ' ', '1', marc.Subfield{Code: 'c', Value: strconv.Itoa(int(book.Year))})
+
+    for _, tag := range db.GetTags(book.ID) {
+        record.AddDataField("650", ' ', '4', marc.Subfield{Code: 'a', Value: tag})
+    }
+
+    items, err := db.GetItems(book.ID)
+    if err != nil {
+        return nil, err
+    }
+    for _, item := range items {
+        var subfields []marc.Subfield
+        if item.Shelf != "" {
+            subfields = append(subfields, marc.Subfield{Code: 'c', Value: item.Shelf})
+        }
+        subfields = append(subfields, marc.Subfield{Code: 'p', Value: item.Barcode})
+        record.AddDataField("852", ' ', ' ', subfields...)
+    }
+    if len(items) == 0 && book.Copies > 0 {
+        record.AddDataField("852", ' ', ' ', marc.Subfield{Code: 't', Value: strconv.Itoa(int(book.Copies))})
+    }
+    return record, nil
+}
+
+// O(n)
+func (db *Database) ExportToMARC(filename string, format MARCFormat) error {
+    books, err := db.GetAllBooks()
+    if err != nil {
+        return fmt.Errorf("ошибка получения книг: %v", err)
+    }
+
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    buffered := bufio.NewWriter(file)
+    var writer interface{ Write(*marc.Record) error }
+    var xmlWriter *marc.XMLWriter
+    if format == MARCXML {
+        xmlWriter = marc.NewXMLWriter(buffered)
+        writer = xmlWriter
+    } else {
+        writer = marc.NewWriter(buffered)
+    }
+
+    for _, book := range books {
+        record, err := db.bookToMARC(book)
+        if err != nil {
+            return fmt.Errorf("ошибка записи книги %d: %v", book.ID, err)
+        }
+        if err := writer.Write(record); err != nil {
+            return fmt.Errorf("ошибка записи книги %d: %v", book.ID, err)
+        }
+    }
+    if xmlWriter != nil {
+        if err := xmlWriter.Close(); err != nil {
+            return fmt.Errorf("ошибка записи данных: %v", err)
+        }
+    }
+
+    if err := buffered.Flush(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(f) f - количество полей записи. Ошибка - запись нельзя превратить в книгу
+func marcToBook(record *marc.Record, opts MARCOptions, report *MARCReport) (marcBook, error) {
+    var mapped marcBook
+    book := &mapped.book
+
+    if !record.IsUnicode() {
+        replaced := false
+        for i := range record.Fields {
+            field := &record.Fields[i]
+            replaced = replaced || !utf8.ValidString(field.Value)
+            field.Value = strings.ToValidUTF8(field.Value, "?")
+            for j := range field.Subfields {
+                replaced = replaced || !utf8.ValidString(field.Subfields[j].Value)
+                field.Subfields[j].Value = strings.ToValidUTF8(field.Subfields[j].Value, "?")
+            }
+        }
+        if replaced {
+            report.note("запись в кодировке MARC-8: символы вне ASCII заменены на '?'")
+        }
+    }
+
+    if opts.KeepIDs {
+        if control := strings.TrimSpace(record.ControlField("001")); control != "" {
+            id, err := strconv.Atoi(control)
+            if err != nil || id <= 0 || id > 1<<31-1 {
+                report.note("001: контрольный номер %q не подходит как ID, ID назначен автоматически", control)
+            } else {
+                book.ID = int32(id)
+            }
+        }
+    }
+
+    // название: основное заглавие и продолжение
+    for _, field := range record.DataFields("245") {
+        parts := []string{trimISBD(field.Subfield('a'))}
+        if rest := trimISBD(field.Subfield('b')); rest != "" {
+            parts = append(parts, rest)
+        }
+        book.Title = strings.TrimSpace(strings.Join(parts, ": "))
+        break
+    }
+    if book.Title == "" {
+        return mapped, fmt.Errorf("нет названия (245 $a)")
+    }
+    report.Title = book.Title
+    if title, cut := fitBytes(book.Title, 100); cut {
+        book.Title = title
+        report.note("245: название обрезано до 100 байт")
+    }
+
+    // автор: личное имя, иначе организация, мероприятие или первый из 700
+    var others []string
+    for _, tag := range []string{"100", "110", "111", "700"} {
+        for _, field := range record.DataFields(tag) {
+            name := trimISBD(field.Subfield('a'))
+            if name == "" {
+                continue
+            }
+            if (tag == "100" || tag == "700") && field.Ind1 == '1' {
+                name = directName(name)
+            }
+            if book.Author == "" {
+                book.Author = name
+                if tag != "100" {
+                    report.note("нет поля 100, автор взят из поля %s", tag)
+                }
+            } else if tag == "700" {
+                others = append(others, name)
+            }
+        }
+    }
+    if book.Author == "" {
+        return mapped, fmt.Errorf("нет автора (100, 110, 111 или 700 $a)")
+    }
+    if len(others) > 0 {
+        report.note("700: соавторы не перенесены: %s", strings.Join(others, "; "))
+    }
+    if author, cut := fitBytes(book.Author, 40); cut {
+        book.Author = author
+        report.note("100: имя автора обрезано до 40 байт")
+    }
+
+    // год: сначала сведения о публикации (264 со вторым индикатором 1)
+    var dates []marc.Field
+    for _, field := range record.DataFields("264") {
+        if field.Ind2 == '1' {
+            dates = append([]marc.Field{field}, dates...)
+        } else {
+            dates = append(dates, field)
+        }
+    }
+    dates = append(dates, record.DataFields("260")...)
+    for _, field := range dates {
+        if year, ok := firstYear(field.Subfield('c')); ok {
+            book.Year = year
+            break
+        }
+    }
+    if book.Year == 0 {
+        fixed := record.ControlField("008")
+        if len(fixed) >= 11 {
+            if year, ok := firstYear(fixed[7:11]); ok {
+                book.Year = year
+                report.note("нет года в 264/260 $c, год взят из поля 008")
+            }
+        }
+    }
+    if book.Year == 0 {
+        return mapped, fmt.Errorf("нет года издания (264/260 $c или 008/07-10)")
+    }
+
+    // темы и ключевые слова
+    for _, tag := range []string{"650", "651", "653", "655"} {
+        for _, field := range record.DataFields(tag) {
+            for _, value := range field.SubfieldValues('a') {
+                if value = trimISBD(value); value != "" {
+                    mapped.tags = append(mapped.tags, value)
+                }
+            }
+        }
+    }
+
+    // экземпляры: штрихкоды из 852/876 $p, без штрихкодов - по экземпляру на поле
+    holdings := append(record.DataFields("852"), record.DataFields("876")...)
+    copies := 0
+    seen := make(map[string]bool)
+    for _, field := range holdings {
+        barcode := strings.TrimSpace(field.Subfield('p'))
+        if barcode == "" {
+            if field.Tag == "852" {
+                copies++
+            }
+            continue
+        }
+        if seen[barcode] {
+            continue
+        }
+        seen[barcode] = true
+
+        shelf := strings.TrimSpace(field.Subfield('c'))
+        if shelf == "" {
+            shelf = strings.TrimSpace(field.Subfield('j'))
+        }
+        if fitted, cut := fitBytes(shelf, 28); cut {
+            shelf = fitted
+            report.note("852: место на полке экземпляра %s обрезано до 28 байт", barcode)
+        }
+        mapped.items = append(mapped.items, ItemView{Barcode: barcode, Shelf: shelf})
+    }
+    if len(holdings) == 1 && len(mapped.items) == 0 {
+        if count, err := strconv.Atoi(strings.TrimSpace(holdings[0].Subfield('t'))); err == nil && count > 0 {
+            copies = count
+        }
+    }
+    book.Copies = int32(copies)
+    if copies > 0 && len(mapped.items) > 0 {
+        report.note("852 без штрихкода (%d) не учтены: тираж считается по экземплярам", copies)
+    }
+    if len(holdings) == 0 {
+        report.note("нет данных об экземплярах (852/876), тираж 0")
+    }
+
+    for _, field := range record.DataFields("020") {
+        // после номера бывает уточнение: "5-17-012345-6 (в пер.)"
+        words := strings.Fields(trimISBD(field.Subfield('a')))
+        if len(words) == 0 {
+            continue
+        }
+        code, err := NormalizeISBN(words[0])
+        switch {
+        case err != nil:
+            report.note("020: %v", err)
+        case mapped.isbn != "":
+            report.note("020: ISBN %s не сохранен - у книги уже есть ISBN %s", code, mapped.isbn)
+        default:
+            mapped.isbn = code
+        }
+    }
+
+    var lost []string
+    lostSeen := make(map[string]bool)
+    for _, field := range record.Fields {
+        if !marcMappedTags[field.Tag] && !lostSeen[field.Tag] {
+            lostSeen[field.Tag] = true
+            lost = append(lost, field.Tag)
+        }
+    }
+    if len(lost) > 0 {
+        sort.Strings(lost)
+        report.note("не перенесены поля: %s", strings.Join(lost, ", "))
+    }
+    return mapped, nil
+}
+
+// O(t + k) книга добавляется (конфликт ID из 001 решается по opts),
+// затем ей дописываются теги и экземпляры. Ошибка - книга не сохранена
+func (db *Database) saveMARCBook(mapped marcBook, opts ImportOptions, report *MARCReport, summary *ImportSummary) error {
+    book := mapped.book
+    id, err := db.importBook(book, opts, summary)
+    if err != nil {
+        return err
+    }
+    if id == 0 {
+        report.note("книга с ID %d уже есть в базе - пропущена", book.ID)
+        return nil
+    }
+    book.ID = id
+    report.BookID = book.ID
+
+    if mapped.isbn != "" {
+        if err := db.SetISBN(book.ID, mapped.isbn); err != nil {
+            report.note("ISBN %s не сохранен: %v", mapped.isbn, err)
+        }
+    }
+
+    for _, tag := range mapped.tags {
+        if err := db.AddTag(book.ID, tag); err != nil {
+            report.note("тема %q не добавлена в теги: %v", tag, err)
+        }
+    }
+    for _, item := range mapped.items {
+        if existing, err := db.FindItem(item.Barcode); err == nil {
+            if existing.BookID != book.ID {
+                report.note("экземпляр %s не добавлен: штрихкод уже у книги с ID %d", item.Barcode, existing.BookID)
+            }
+            continue
+        }
+        item.BookID = book.ID
+        if _, err := db.AddItem(item); err != nil {
+            report.note("экземпляр %s не добавлен: %v", item.Barcode, err)
+        }
+    }
+    return nil
+}
+
+// O(n) записи файла читаются потоково и по очереди передаются в fn
+// (index - номер записи с 1). Ошибка разбора прерывает чтение
+func eachMARCRecord(filename string, format MARCFormat, fn func(index int, record *marc.Record) error) error {
+    file, err := os.Open(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer file.Close()
+
+    var reader interface{ Read() (*marc.Record, error) }
+    if format == MARCXML {
+        reader = marc.NewXMLReader(file)
+    } else {
+        reader = marc.NewReader(file)
+    }
+
+    for index := 1; ; index++ {
+        record, err := reader.Read()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return fmt.Errorf("ошибка разбора MARC: %v", err)
+        }
+        if err := fn(index, record); err != nil {
+            return err
+        }
+    }
+}
+
+// O(n) записи читаются потоково (при ConflictFail - дважды, конфликты ID
+// из 001 ищутся до первой записи). Запись, которую нельзя превратить в книгу,
+// пропускается; отчет содержит только записи с замечаниями
+func (db *Database) ImportFromMARC(filename string, opts MARCOptions) (ImportSummary, []MARCReport, error) {
+    var summary ImportSummary
+    if opts.OnConflict == ConflictFail {
+        scan := db.newConflictScan()
+        err := eachMARCRecord(filename, opts.Format, func(index int, record *marc.Record) error {
+            mapped, err := marcToBook(record, opts, &MARCReport{Record: index})
+            if err != nil {
+                return nil
+            }
+            if err := scan.check(mapped.book.ID); err != nil {
+                return fmt.Errorf("запись %d: %v", index, err)
+            }
+            return nil
+        })
+        if err != nil {
+            return summary, nil, err
+        }
+    }
+    defer db.auditAs(AuditImport)()
+
+    var reports []MARCReport
+    err := eachMARCRecord(filename, opts.Format, func(index int, record *marc.Record) error {
+        report := MARCReport{Record: index}
+        mapped, err := marcToBook(record, opts, &report)
+        if err == nil {
+            err = db.saveMARCBook(mapped, opts.ImportOptions, &report, &summary)
+        }
+        var conflict *ConflictError
+        if errors.As(err, &conflict) {
+            return fmt.Errorf("запись %d: %v", index, err)
+        }
+        if err != nil {
+            report.Skipped = true
+            report.note("книга не добавлена: %v", err)
+        }
+        if len(report.Notes) > 0 {
+            reports = append(reports, report)
+        }
+        return nil
+    })
+    return summary, reports, err
+}
\ No newline at end of file
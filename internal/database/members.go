@@ -0,0 +1,168 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Запись в members.db:
+// ID      int32    - 4 байта
+// Name    [60]byte - 60 байт
+// Contact [60]byte - 60 байт
+const memberRecordSize = 124
+
+type MemberView struct {
+    ID      int32  `json:"id"`
+    Name    string `json:"name"`
+    Contact string `json:"contact"`
+}
+
+// O(1)
+func memberToBytes(member MemberView) []byte {
+    buf := make([]byte, memberRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(member.ID))
+    copyStringToBytes(member.Name, buf[4:64])
+    copyStringToBytes(member.Contact, buf[64:124])
+    return buf
+}
+
+// O(1)
+func bytesToMember(data []byte) MemberView {
+    return MemberView{
+        ID:      int32(binary.LittleEndian.Uint32(data[0:4])),
+        Name:    bytesToString(data[4:64]),
+        Contact: bytesToString(data[64:124]),
+    }
+}
+
+// O(n) n - количество читателей. Следующий ID пока только по живым читателям,
+// loadLoans и loadHolds (они грузятся позже) поднимут его выше ID удаленных
+// читателей, оставшихся в истории, чтобы новый читатель не унаследовал чужие выдачи
+func (db *Database) loadMembers() error {
+    db.memberIndex = make(map[int32]int64)
+    db.nextMemberID = 1
+    return db.members.scan(func(position int64, data []byte) {
+        member := bytesToMember(data)
+        db.memberIndex[member.ID] = position
+        db.reserveMemberID(member.ID)
+    })
+}
+
+// O(1) ID читателя занят (живым читателем или историей выдач и броней)
+// и автоматически больше не назначается
+func (db *Database) reserveMemberID(id int32) {
+    if id >= db.nextMemberID {
+        db.nextMemberID = id + 1
+    }
+}
+
+// O(m) m - длина полей
+func validateMember(member MemberView) error {
+    if strings.TrimSpace(member.Name) == "" {
+        return fmt.Errorf("имя читателя не может быть пустым")
+    }
+    if len([]byte(member.Name)) > 60 {
+        return fmt.Errorf("имя читателя длиннее 60 байт")
+    }
+    if len([]byte(member.Contact)) > 60 {
+        return fmt.Errorf("контакты читателя длиннее 60 байт")
+    }
+    return nil
+}
+
+// O(1) в среднем, ID 0 - назначить следующий
+func (db *Database) AddMember(member MemberView) (int32, error) {
+    if err := validateMember(member); err != nil {
+        return 0, err
+    }
+    if member.ID < 0 {
+        return 0, fmt.Errorf("ID читателя не может быть отрицательным")
+    }
+    if member.ID == 0 {
+        member.ID = db.nextMemberID
+    }
+    if _, exists := db.memberIndex[member.ID]; exists {
+        return 0, fmt.Errorf("читатель с ID %d уже существует", member.ID)
+    }
+
+    position, err := db.members.insert(memberToBytes(member))
+    if err != nil {
+        return 0, fmt.Errorf("ошибка записи читателя: %v", err)
+    }
+
+    db.memberIndex[member.ID] = position
+    db.reserveMemberID(member.ID)
+    return member.ID, nil
+}
+
+// O(1) в среднем
+func (db *Database) UpdateMember(member MemberView) error {
+    position, exists := db.memberIndex[member.ID]
+    if !exists {
+        return fmt.Errorf("читатель с ID %d не найден", member.ID)
+    }
+    if err := validateMember(member); err != nil {
+        return err
+    }
+    if err := db.members.write(position, memberToBytes(member)); err != nil {
+        return fmt.Errorf("ошибка записи читателя: %v", err)
+    }
+    return nil
+}
+
+// O(1) в среднем. Читателя с невозвращенными книгами или бронями удалить нельзя,
+// история его выдач остается в loans.db
+func (db *Database) DeleteMember(id int32) error {
+    position, exists := db.memberIndex[id]
+    if !exists {
+        return fmt.Errorf("читатель с ID %d не найден", id)
+    }
+    if db.activeByMember[id] > 0 {
+        return fmt.Errorf("у читателя с ID %d есть невозвращенные книги: %d", id, db.activeByMember[id])
+    }
+    if holds := db.activeHoldsOfMember(id); holds > 0 {
+        return fmt.Errorf("у читателя с ID %d есть активные брони: %d", id, holds)
+    }
+    if err := db.members.remove(position); err != nil {
+        return fmt.Errorf("ошибка удаления читателя: %v", err)
+    }
+    delete(db.memberIndex, id)
+    return nil
+}
+
+// O(1) в среднем
+func (db *Database) FindMember(id int32) (MemberView, error) {
+    position, exists := db.memberIndex[id]
+    if !exists {
+        return MemberView{}, fmt.Errorf("читатель с ID %d не найден", id)
+    }
+    data, err := db.members.read(position)
+    if err != nil {
+        return MemberView{}, err
+    }
+    return bytesToMember(data), nil
+}
+
+// O(n log n)
+func (db *Database) GetAllMembers() ([]MemberView, error) {
+    members := make([]MemberView, 0, len(db.memberIndex))
+    for _, position := range db.memberIndex {
+        data, err := db.members.read(position)
+        if err != nil {
+            continue
+        }
+        members = append(members, bytesToMember(data))
+    }
+
+    sort.Slice(members, func(i, j int) bool {
+        return members[i].ID < members[j].ID
+    })
+    return members, nil
+}
+
+// O(1)
+func (db *Database) ActiveLoansOfMember(id int32) int {
+    return db.activeByMember[id]
+}
\ No newline at end of file
@@ -0,0 +1,414 @@
+package database
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "math"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+)
+
+// Что произойдет со строкой файла при импорте
+type PlanAction int
+
+const (
+    PlanNew       PlanAction = iota + 1 // книги с таким ID нет - будет добавлена
+    PlanChanged                         // книга есть и отличается - будет обновлена
+    PlanUnchanged                       // книга есть и совпадает - ничего не произойдет
+    PlanInvalid                         // строку нельзя импортировать, причина в Reason
+    PlanSkipped                         // книга есть, политика ConflictSkip
+)
+
+func (a PlanAction) String() string {
+    switch a {
+    case PlanNew:
+        return "Новая"
+    case PlanChanged:
+        return "Изменена"
+    case PlanUnchanged:
+        return "Без изменений"
+    case PlanInvalid:
+        return "Ошибка"
+    case PlanSkipped:
+        return "Пропуск"
+    }
+    return "?"
+}
+
+// Изменение одного поля. Field - то же название поля, что и в FindBooks
+type FieldDiff struct {
+    Field string
+    Old   string
+    New   string
+}
+
+// Строка плана. Book - книга в том виде, в каком она будет записана
+// (с каноническим автором и тиражом по экземплярам), Old - текущая книга
+type PlanRow struct {
+    Line     int
+    Raw      string
+    Action   PlanAction
+    Book     BookView
+    Old      BookView
+    Diffs    []FieldDiff
+    Column   string // поле с ошибкой, "" - строка целиком
+    Reason   string // причина ошибки или пояснение к действию
+    Renumber bool   // ID занят, книга будет добавлена под новым (ConflictRenumber)
+    Selected bool   // применять ли строку; по умолчанию выбраны новые и измененные
+}
+
+// Результат пробного импорта: база не меняется, пока план не применен
+type ImportPlan struct {
+    Filename string
+    Options  ImportOptions
+    Rows     []PlanRow
+}
+
+// O(r) количество строк с действием action
+func (p *ImportPlan) Count(action PlanAction) int {
+    count := 0
+    for _, row := range p.Rows {
+        if row.Action == action {
+            count++
+        }
+    }
+    return count
+}
+
+// O(r) количество строк, которые будут применены
+func (p *ImportPlan) SelectedCount() int {
+    count := 0
+    for _, row := range p.Rows {
+        if row.Selected {
+            count++
+        }
+    }
+    return count
+}
+
+// Строка файла импорта: книга или ошибка разбора в поле Column
+type importRow struct {
+    Line   int
+    Raw    string
+    Book   BookView
+    Column string
+    Err    error
+}
+
+// O(1) ошибка разбора строки в виде ImportError
+func (row importRow) importError() ImportError {
+    return ImportError{Line: row.Line, Column: row.Column, Reason: row.Err.Error(), Raw: row.Raw, err: row.Err}
+}
+
+// O(1) ID, название, автор, год, тираж из полей строки.
+// При ошибке возвращает и название поля, в котором она найдена
+func parseBookFields(fields []string) (BookView, string, error) {
+    if len(fields) != 5 {
+        return BookView{}, "", fmt.Errorf("ожидалось 5 полей, получено %d", len(fields))
+    }
+    id, err := parseInt32Field("ID", fields[0])
+    if err != nil {
+        return BookView{}, "ID", err
+    }
+    year, err := parseInt32Field("год", fields[3])
+    if err != nil {
+        return BookView{}, "Год издания", err
+    }
+    copies, err := parseInt32Field("тираж", fields[4])
+    if err != nil {
+        return BookView{}, "Тираж", err
+    }
+    return BookView{
+        ID:     id,
+        Title:  fields[1],
+        Author: fields[2],
+        Year:   year,
+        Copies: copies,
+    }, "", nil
+}
+
+// O(1) число вне int32 - ошибка строки, а не переполнение при приведении
+func parseInt32Field(name, value string) (int32, error) {
+    number, err := strconv.ParseInt(strings.TrimSpace(value), 10, 32)
+    if errors.Is(err, strconv.ErrRange) {
+        return 0, fmt.Errorf("%s вне диапазона от %d до %d: %q", name, math.MinInt32, math.MaxInt32, value)
+    }
+    if err != nil {
+        return 0, fmt.Errorf("неверный %s: %q", name, value)
+    }
+    return int32(number), nil
+}
+
+// Шапка TXT-выгрузки книг
+const txtBooksHeader = "ID|Название|Автор|Год|Тираж"
+
+// O(n) строки TXT-файла; ошибки разбора остаются в строках.
+// Экранирование снимается, только если шапка помечена txtEscapedMarker
+func readTxtRows(filename string) ([]importRow, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    defer file.Close()
+
+    var rows []importRow
+    escaped := false
+    scanner := bufio.NewScanner(file)
+    lineNumber := 0
+    for scanner.Scan() {
+        lineNumber++
+        line := strings.TrimSpace(scanner.Text())
+        if line == "" || line == txtBooksHeader {
+            continue
+        }
+        if line == txtBooksHeader+"|"+txtEscapedMarker {
+            escaped = true
+            continue
+        }
+        book, column, err := parseBookFields(splitTxtFields(line, escaped))
+        rows = append(rows, importRow{Line: lineNumber, Raw: line, Book: book, Column: column, Err: err})
+    }
+    if err := scanner.Err(); err != nil {
+        return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+    }
+    return rows, nil
+}
+
+// O(1)
+func bookDiffs(old, new BookView) []FieldDiff {
+    var diffs []FieldDiff
+    add := func(field, oldValue, newValue string) {
+        if oldValue != newValue {
+            diffs = append(diffs, FieldDiff{Field: field, Old: oldValue, New: newValue})
+        }
+    }
+    add("Название", old.Title, new.Title)
+    add("Автор", old.Author, new.Author)
+    add("Год издания", strconv.Itoa(int(old.Year)), strconv.Itoa(int(new.Year)))
+    add("Тираж", strconv.Itoa(int(old.Copies)), strconv.Itoa(int(new.Copies)))
+    return diffs
+}
+
+// O(1) в среднем + проверка книги. Те же проверки, что в importBook,
+// но без записи. seen - ID, уже встреченные в файле, и их строки
+func (db *Database) planRow(row importRow, opts ImportOptions, seen map[int32]int) PlanRow {
+    plan := PlanRow{Line: row.Line, Raw: row.Raw, Book: row.Book, Action: PlanInvalid}
+    if row.Err != nil {
+        plan.Column = row.Column
+        plan.Reason = row.Err.Error()
+        return plan
+    }
+
+    book := &plan.Book
+    if book.ID < 0 {
+        plan.Column = "ID"
+        plan.Reason = "ID книги не может быть отрицательным"
+        return plan
+    }
+    if book.ID != 0 {
+        if line, duplicate := seen[book.ID]; duplicate {
+            plan.Column = "ID"
+            plan.Reason = fmt.Sprintf("ID %d уже встречался в строке %d", book.ID, line)
+            return plan
+        }
+        seen[book.ID] = row.Line
+    }
+
+    position, exists := db.idIndex[book.ID]
+    inTrash := db.InTrash(book.ID)
+    if book.ID != 0 && (exists || inTrash) {
+        switch opts.OnConflict {
+        case ConflictSkip:
+            plan.Action = PlanSkipped
+            plan.Reason = "книга с таким ID уже есть в базе"
+            return plan
+        case ConflictFail:
+            plan.Column = "ID"
+            plan.Reason = fmt.Sprintf("книга с ID %d уже существует", book.ID)
+            return plan
+        case ConflictRenumber:
+            plan.Reason = fmt.Sprintf("ID %d занят, будет назначен новый", book.ID)
+            plan.Renumber = true
+            book.ID = 0
+            exists = false
+        default:
+            if inTrash {
+                plan.Reason = fmt.Sprintf("книга с ID %d лежит в корзине", book.ID)
+                return plan
+            }
+        }
+    }
+
+    if exists {
+        old, err := db.readRecord(position)
+        if err != nil {
+            plan.Reason = err.Error()
+            return plan
+        }
+        plan.Old = old.ToView()
+        if opts.OnConflict == ConflictMerge {
+            *book = mergeBook(plan.Old, *book)
+        }
+        stored, err := db.storedForm(*book)
+        if err != nil {
+            plan.Reason = err.Error()
+            return plan
+        }
+        *book = stored
+    } else {
+        book.Author = db.CanonicalAuthor(book.Author)
+    }
+
+    if err := db.validate(*book); err != nil {
+        plan.Column = errorColumn(err)
+        plan.Reason = err.Error()
+        return plan
+    }
+    if !exists {
+        plan.Action = PlanNew
+        plan.Selected = true
+        return plan
+    }
+
+    if active := int32(db.activeByBook[book.ID]); book.Copies < active {
+        plan.Column = "Тираж"
+        plan.Reason = fmt.Sprintf("тираж не может быть меньше числа выданных экземпляров: %d", active)
+        return plan
+    }
+    plan.Diffs = bookDiffs(plan.Old, *book)
+    if len(plan.Diffs) == 0 {
+        plan.Action = PlanUnchanged
+        return plan
+    }
+    plan.Action = PlanChanged
+    plan.Selected = true
+    return plan
+}
+
+// O(n) пробный импорт TXT или Excel (по расширению файла): для каждой строки
+// план показывает, будет ли книга добавлена, обновлена (и какие поля изменятся),
+// останется как есть, пропущена или не может быть импортирована. База не меняется.
+// Excel читается с листа и столбцов по умолчанию, см. PlanImportExcel
+func (db *Database) PlanImport(filename string, opts ImportOptions) (*ImportPlan, error) {
+    var rows []importRow
+    var err error
+    switch strings.ToLower(filepath.Ext(filename)) {
+    case ".txt":
+        rows, err = readTxtRows(filename)
+    case ".xlsx":
+        rows, err = readExcelRows(filename, ExcelOptions{})
+    default:
+        return nil, fmt.Errorf("предпросмотр импорта поддерживается для файлов .txt и .xlsx")
+    }
+    if err != nil {
+        return nil, err
+    }
+    return db.planRows(filename, rows, opts), nil
+}
+
+// O(n) пробный импорт Excel с выбранного листа и по выбранным столбцам
+func (db *Database) PlanImportExcel(filename string, opts ExcelOptions) (*ImportPlan, error) {
+    rows, err := readExcelRows(filename, opts)
+    if err != nil {
+        return nil, err
+    }
+    return db.planRows(filename, rows, opts.ImportOptions), nil
+}
+
+// O(n)
+func (db *Database) planRows(filename string, rows []importRow, opts ImportOptions) *ImportPlan {
+    plan := &ImportPlan{Filename: filename, Options: opts}
+    seen := make(map[int32]int)
+    for _, row := range rows {
+        plan.Rows = append(plan.Rows, db.planRow(row, opts, seen))
+    }
+    return plan
+}
+
+// O(s) выбранные строки плана все еще применимы: новой книги нет в базе
+// и в корзине, обновляемая книга не менялась с предпросмотра
+func (db *Database) checkPlanFresh(plan *ImportPlan) error {
+    for _, row := range plan.Rows {
+        if !row.Selected {
+            continue
+        }
+        switch row.Action {
+        case PlanNew:
+            if row.Book.ID == 0 {
+                continue
+            }
+            if _, exists := db.idIndex[row.Book.ID]; exists || db.InTrash(row.Book.ID) {
+                return fmt.Errorf("ошибка в строке %d: книга с ID %d появилась после предпросмотра, постройте план заново", row.Line, row.Book.ID)
+            }
+        case PlanChanged:
+            current, err := db.FindByID(row.Book.ID)
+            if err != nil || current.ToView() != row.Old {
+                return fmt.Errorf("ошибка в строке %d: книга с ID %d изменилась после предпросмотра, постройте план заново", row.Line, row.Book.ID)
+            }
+        }
+    }
+    return nil
+}
+
+// O(s) применяет выбранные строки плана: новые книги добавляются,
+// измененные - обновляются, снятые считаются пропущенными, ошибочные
+// попадают в отчет summary.Errors. При ConflictFail строка с занятым ID
+// (или ID, повторяющимся в файле) прерывает импорт до записи. План сверяется
+// с базой: если после предпросмотра книги появились или изменились, ничего
+// не записывается. Книги без ID добавляются последними, чтобы назначенный
+// им ID не занял ID из следующих строк
+func (db *Database) ApplyImportPlan(plan *ImportPlan) (ImportSummary, error) {
+    var summary ImportSummary
+    // при ConflictFail конфликт хотя бы в одной строке прерывает весь импорт
+    if plan.Options.OnConflict == ConflictFail {
+        scan := db.newConflictScan()
+        for _, row := range plan.Rows {
+            if err := scan.check(row.Book.ID); err != nil {
+                return summary, fmt.Errorf("ошибка в строке %d: %v", row.Line, err)
+            }
+        }
+    }
+    if err := db.checkPlanFresh(plan); err != nil {
+        return summary, err
+    }
+
+    defer db.auditAs(AuditImport)()
+    for _, row := range plan.Rows {
+        switch {
+        case row.Action == PlanUnchanged:
+            summary.add(OutcomeUnchanged)
+        case row.Action == PlanInvalid:
+            summary.Errors = append(summary.Errors, ImportError{Line: row.Line, Column: row.Column, Reason: row.Reason, Raw: row.Raw})
+        case row.Action == PlanSkipped || (row.Action != PlanInvalid && !row.Selected):
+            summary.add(OutcomeSkipped)
+        }
+    }
+
+    for _, autoID := range []bool{false, true} {
+        for _, row := range plan.Rows {
+            if !row.Selected || (row.Book.ID == 0) != autoID {
+                continue
+            }
+            switch row.Action {
+            case PlanNew:
+                if _, err := db.AddBook(row.Book); err != nil {
+                    return summary, fmt.Errorf("ошибка в строке %d: ошибка добавления книги: %v", row.Line, err)
+                }
+                if row.Renumber {
+                    summary.add(OutcomeRenumbered)
+                } else {
+                    summary.add(OutcomeAdded)
+                }
+            case PlanChanged:
+                if err := db.UpdateBook(row.Book); err != nil {
+                    return summary, fmt.Errorf("ошибка в строке %d: ошибка обновления книги: %v", row.Line, err)
+                }
+                summary.add(OutcomeUpdated)
+            }
+        }
+    }
+    return summary, nil
+}
\ No newline at end of file
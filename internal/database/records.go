@@ -0,0 +1,262 @@
+package database
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+)
+
+// Файл с записями фиксированной длины, устроен так же, как books.db.
+// Используется для вспомогательных хранилищ рядом с основной базой.
+// Свободный слот - запись, целиком заполненная нулями
+type recordFile struct {
+    file       *os.File
+    filePath   string
+    recordSize int64
+    freeList   []int64
+}
+
+// O(1)
+func openRecordFile(filePath string, recordSize int64) (*recordFile, error) {
+    file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE, 0666)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка открытия файла %s: %v", filePath, err)
+    }
+
+    return &recordFile{
+        file:       file,
+        filePath:   filePath,
+        recordSize: recordSize,
+        freeList:   []int64{},
+    }, nil
+}
+
+// O(1)
+func (rf *recordFile) Close() error {
+    if rf.file != nil {
+        return rf.file.Close()
+    }
+    return nil
+}
+
+// O(n) проходим по всем слотам, занятые отдаем в fn, пустые запоминаем во freeList
+func (rf *recordFile) scan(fn func(position int64, data []byte)) error {
+    stat, err := rf.file.Stat()
+    if err != nil {
+        return err
+    }
+
+    rf.freeList = []int64{}
+    fileSize := stat.Size()
+
+    for position := int64(0); position+rf.recordSize <= fileSize; position += rf.recordSize {
+        data, err := rf.read(position)
+        if err != nil {
+            return err
+        }
+        if isEmptyRecord(data) {
+            rf.freeList = append(rf.freeList, position)
+            continue
+        }
+        fn(position, data)
+    }
+
+    return nil
+}
+
+// O(1)
+func (rf *recordFile) read(position int64) ([]byte, error) {
+    buffer := make([]byte, rf.recordSize)
+    n, err := rf.file.ReadAt(buffer, position)
+    if err != nil {
+        return nil, err
+    }
+    if n != int(rf.recordSize) {
+        return nil, fmt.Errorf("неполная запись")
+    }
+    return buffer, nil
+}
+
+// O(1)
+func (rf *recordFile) write(position int64, data []byte) error {
+    if int64(len(data)) != rf.recordSize {
+        return fmt.Errorf("неверный размер записи: %d вместо %d", len(data), rf.recordSize)
+    }
+    _, err := rf.file.WriteAt(data, position)
+    return err
+}
+
+// Перезапись занятого слота: прежние данные хранятся для отката
+type recordRewrite struct {
+    position int64
+    old      []byte
+    new      []byte
+}
+
+// O(k) пишет все записи или ни одной: если запись не удалась,
+// уже переписанные слоты возвращаются к прежним данным
+func (rf *recordFile) rewriteAll(rewrites []recordRewrite) error {
+    for i, rewrite := range rewrites {
+        if err := rf.write(rewrite.position, rewrite.new); err != nil {
+            rf.revertAll(rewrites[:i])
+            return err
+        }
+    }
+    return nil
+}
+
+// O(k) откат rewriteAll в обратном порядке
+func (rf *recordFile) revertAll(rewrites []recordRewrite) error {
+    var firstErr error
+    for i := len(rewrites) - 1; i >= 0; i-- {
+        if err := rf.write(rewrites[i].position, rewrites[i].old); err != nil && firstErr == nil {
+            firstErr = err
+        }
+    }
+    return firstErr
+}
+
+// O(1) значение переезжает на другой ключ карты
+func moveKey[K comparable, V any](m map[K]V, from, to K) {
+    if value, exists := m[from]; exists {
+        m[to] = value
+        delete(m, from)
+    }
+}
+
+// O(1) занимаем свободный слот или дописываем в конец
+func (rf *recordFile) insert(data []byte) (int64, error) {
+    var position int64
+    if len(rf.freeList) > 0 {
+        position = rf.freeList[len(rf.freeList)-1]
+    } else {
+        stat, err := rf.file.Stat()
+        if err != nil {
+            return 0, err
+        }
+        position = stat.Size()
+    }
+
+    if err := rf.write(position, data); err != nil {
+        return 0, err
+    }
+
+    if len(rf.freeList) > 0 && rf.freeList[len(rf.freeList)-1] == position {
+        rf.freeList = rf.freeList[:len(rf.freeList)-1]
+    }
+    return position, nil
+}
+
+// O(1) затираем слот нулями, чтобы после перезапуска он считался свободным
+func (rf *recordFile) remove(position int64) error {
+    if err := rf.write(position, make([]byte, rf.recordSize)); err != nil {
+        return err
+    }
+    rf.freeList = append(rf.freeList, position)
+    return nil
+}
+
+// O(n) переписывает занятые слоты подряд через временный файл,
+// возвращает количество освобожденных слотов. Позиции записей меняются,
+// поэтому после вызова хранилище нужно перечитать через scan
+func (rf *recordFile) compact() (int, error) {
+    var live [][]byte
+    if err := rf.scan(func(position int64, data []byte) {
+        live = append(live, data)
+    }); err != nil {
+        return 0, err
+    }
+    reclaimed := len(rf.freeList)
+    if reclaimed == 0 {
+        return 0, nil
+    }
+
+    tmpPath := rf.filePath + ".tmp"
+    tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка создания временного файла: %v", err)
+    }
+    for i, data := range live {
+        if _, err := tmp.WriteAt(data, int64(i)*rf.recordSize); err != nil {
+            tmp.Close()
+            os.Remove(tmpPath)
+            return 0, err
+        }
+    }
+    if err := tmp.Close(); err != nil {
+        return 0, err
+    }
+
+    rf.file.Close()
+    if err := os.Rename(tmpPath, rf.filePath); err != nil {
+        rf.file, _ = os.OpenFile(rf.filePath, os.O_RDWR, 0666)
+        os.Remove(tmpPath)
+        return 0, fmt.Errorf("ошибка замены файла: %v", err)
+    }
+    rf.file, err = os.OpenFile(rf.filePath, os.O_RDWR, 0666)
+    if err != nil {
+        return 0, fmt.Errorf("ошибка открытия файла: %v", err)
+    }
+    rf.freeList = []int64{}
+    return reclaimed, nil
+}
+
+// O(1)
+func (rf *recordFile) clear() error {
+    if err := rf.file.Truncate(0); err != nil {
+        return err
+    }
+    rf.freeList = []int64{}
+    return nil
+}
+
+// Вспомогательное хранилище рядом с books.db: имя файла, размер записи
+// и функция, которая заново строит его индексы в памяти.
+// bookData - данные самих книг, ClearDatabase стирает их вместе с books.db
+type sidecar struct {
+    name       string
+    file       **recordFile
+    recordSize int64
+    load       func() error
+    bookData   bool
+}
+
+func (db *Database) sidecars() []sidecar {
+    return []sidecar{
+        // корзина первой: остальные загрузчики уже видят индексы без удаленных книг
+        {"trash.db", &db.trash, trashRecordSize, db.loadTrash, true},
+        {"authors.db", &db.authors, authorAliasSize, db.loadAuthorAliases, false},
+        {"covers.db", &db.covers, coverRefSize, db.loadCovers, true},
+        {"isbn.db", &db.isbns, isbnRecordSize, db.loadISBNs, true},
+        {"tags.db", &db.tags, tagRecordSize, db.loadTags, true},
+        {"members.db", &db.members, memberRecordSize, db.loadMembers, false},
+        {"loans.db", &db.loans, loanRecordSize, db.loadLoans, false},
+        {"fines.db", &db.fines, fineRecordSize, db.loadFines, false},
+        {"holds.db", &db.holds, holdRecordSize, db.loadHolds, false},
+        {"items.db", &db.items, itemRecordSize, db.loadItems, true},
+    }
+}
+
+// O(n) открываем все вспомогательные файлы и строим их индексы
+func (db *Database) openSidecars(dir string) error {
+    for _, s := range db.sidecars() {
+        rf, err := openRecordFile(filepath.Join(dir, s.name), s.recordSize)
+        if err != nil {
+            return err
+        }
+        *s.file = rf
+        if err := s.load(); err != nil {
+            return fmt.Errorf("ошибка загрузки %s: %v", s.name, err)
+        }
+    }
+    return nil
+}
+
+func isEmptyRecord(data []byte) bool {
+    for _, b := range data {
+        if b != 0 {
+            return false
+        }
+    }
+    return true
+}
\ No newline at end of file
@@ -0,0 +1,135 @@
+package database
+
+import (
+    "bufio"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// Строка RIS: тег из двух символов, два пробела, дефис, пробел, значение
+const risSeparator = "  - "
+
+// O(n) значение RIS всегда занимает одну строку
+func risValue(s string) string {
+    return strings.Join(strings.Fields(s), " ")
+}
+
+// O(n) книги как записи TY - BOOK. ID - ключ цитирования, теги - KW
+func (db *Database) ExportToRIS(filename string, books []BookView) error {
+    keys, err := db.exportCitationKeys(books)
+    if err != nil {
+        return err
+    }
+
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    writer := bufio.NewWriter(file)
+    // строки в RIS заканчиваются CRLF, как в файлах EndNote и Zotero
+    writeTag := func(tag, value string) {
+        writer.WriteString(tag + risSeparator + value + "\r\n")
+    }
+    for _, book := range books {
+        author, _ := invertName(book.Author)
+        writeTag("TY", "BOOK")
+        writeTag("ID", keys[book.ID])
+        writeTag("AU", risValue(author))
+        writeTag("TI", risValue(book.Title))
+        writeTag("PY", fmt.Sprintf("%d", book.Year))
+        for _, tag := range db.GetTags(book.ID) {
+            writeTag("KW", risValue(tag))
+        }
+        writeTag("ER", "")
+        writer.WriteString("\r\n")
+    }
+
+    if err := writer.Flush(); err != nil {
+        return fmt.Errorf("ошибка сохранения файла: %v", err)
+    }
+    return nil
+}
+
+// O(1) разбор строки "TI  - Название"; у ER значения может не быть
+func parseRISLine(line string) (string, string, bool) {
+    if len(line) < 5 || line[2:5] != "  -" {
+        return "", "", false
+    }
+    tag := line[:2]
+    for i := 0; i < 2; i++ {
+        if !(tag[i] >= 'A' && tag[i] <= 'Z') && !(tag[i] >= '0' && tag[i] <= '9') {
+            return "", "", false
+        }
+    }
+    return tag, strings.TrimSpace(line[5:]), true
+}
+
+// O(n) записи любого типа добавляются новыми книгами: автор - первый из
+// AU/A1, название - TI/T1, год - PY/Y1/DA, ключевые слова KW - в теги.
+// Книга, которая уже есть в базе, решается по opts.OnConflict
+func (db *Database) ImportFromRIS(filename string, opts ImportOptions) (ImportSummary, error) {
+    data, err := readBibliography(filename)
+    if err != nil {
+        return ImportSummary{}, err
+    }
+
+    var records []bibliographyRecord
+    var fields map[string][]string
+    var lastTag string
+    startLine := 0
+
+    scanner := bufio.NewScanner(strings.NewReader(data))
+    scanner.Buffer(make([]byte, 64*1024), maxJSONLine)
+    lineNumber := 0
+    for scanner.Scan() {
+        lineNumber++
+        line := strings.TrimRight(scanner.Text(), "\r")
+        tag, value, ok := parseRISLine(line)
+        if !ok {
+            // перенос длинного значения на следующую строку
+            if fields != nil && lastTag != "" && strings.TrimSpace(line) != "" {
+                values := fields[lastTag]
+                values[len(values)-1] += " " + strings.TrimSpace(line)
+            }
+            continue
+        }
+
+        switch {
+        case tag == "TY":
+            if fields != nil {
+                return ImportSummary{}, fmt.Errorf("ошибка в строке %d: запись со строки %d не закрыта ER", lineNumber, startLine)
+            }
+            fields = make(map[string][]string)
+            startLine = lineNumber
+        case fields == nil:
+            return ImportSummary{}, fmt.Errorf("ошибка в строке %d: тег %s вне записи (нет TY)", lineNumber, tag)
+        case tag == "ER":
+            first := func(tags ...string) string {
+                for _, tag := range tags {
+                    if values := fields[tag]; len(values) > 0 {
+                        return values[0]
+                    }
+                }
+                return ""
+            }
+            title := first("TI", "T1", "CT", "BT")
+            book, err := bibliographyBook(title, first("AU", "A1", "A2"), first("PY", "Y1", "DA"))
+            records = append(records, newBibliographyRecord(startLine, "TI  - "+title, book, fields["KW"], err))
+            fields = nil
+        default:
+            fields[tag] = append(fields[tag], value)
+        }
+        lastTag = tag
+    }
+
+    if err := scanner.Err(); err != nil {
+        return ImportSummary{}, fmt.Errorf("ошибка чтения файла: %v", err)
+    }
+    if fields != nil {
+        return ImportSummary{}, fmt.Errorf("ошибка в записи со строки %d: нет ER в конце записи", startLine)
+    }
+    return db.importBibliography(records, opts)
+}
\ No newline at end of file
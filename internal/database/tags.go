@@ -0,0 +1,280 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// Запись в tags.db - одна пара книга-тег:
+// ID  int32    - 4 байта
+// Tag [40]byte - 40 байт
+const tagRecordSize = 44
+
+type tagKey struct {
+    id  int32
+    tag string
+}
+
+// Комбинация тегов для поиска: книга должна иметь все теги из All,
+// хотя бы один из Any (если он не пуст) и ни одного из None
+type TagQuery struct {
+    All  []string
+    Any  []string
+    None []string
+}
+
+// O(1)
+func tagToBytes(id int32, tag string) []byte {
+    buf := make([]byte, tagRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(id))
+    copyStringToBytes(tag, buf[4:44])
+    return buf
+}
+
+// O(1)
+func bytesToTag(data []byte) (int32, string) {
+    return int32(binary.LittleEndian.Uint32(data[0:4])), bytesToString(data[4:44])
+}
+
+// Теги храним в нижнем регистре, чтобы "Классика" и "классика" были одним тегом
+func normalizeTag(tag string) string {
+    return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// O(n) n - количество пар книга-тег
+func (db *Database) loadTags() error {
+    db.tagIndex = make(map[string][]int32)
+    db.bookTags = make(map[int32][]string)
+    db.tagPositions = make(map[tagKey]int64)
+    return db.tags.scan(func(position int64, data []byte) {
+        id, tag := bytesToTag(data)
+        db.tagPositions[tagKey{id, tag}] = position
+        db.tagIndex[tag] = append(db.tagIndex[tag], id)
+        db.bookTags[id] = append(db.bookTags[id], tag)
+    })
+}
+
+// O(1) в среднем
+func (db *Database) AddTag(id int32, tag string) error {
+    if _, exists := db.idIndex[id]; !exists {
+        return fmt.Errorf("книга с ID %d не найдена", id)
+    }
+
+    tag = normalizeTag(tag)
+    if tag == "" {
+        return fmt.Errorf("тег не может быть пустым")
+    }
+    if len([]byte(tag)) > 40 {
+        return fmt.Errorf("тег '%s' длиннее 40 байт", tag)
+    }
+    if _, exists := db.tagPositions[tagKey{id, tag}]; exists {
+        return nil
+    }
+
+    position, err := db.tags.insert(tagToBytes(id, tag))
+    if err != nil {
+        return fmt.Errorf("ошибка записи тега: %v", err)
+    }
+
+    db.tagPositions[tagKey{id, tag}] = position
+    db.tagIndex[tag] = append(db.tagIndex[tag], id)
+    db.bookTags[id] = append(db.bookTags[id], tag)
+    return nil
+}
+
+// O(k) k - количество книг с этим тегом
+func (db *Database) RemoveTag(id int32, tag string) error {
+    tag = normalizeTag(tag)
+    position, exists := db.tagPositions[tagKey{id, tag}]
+    if !exists {
+        return fmt.Errorf("у книги с ID %d нет тега '%s'", id, tag)
+    }
+
+    if err := db.tags.remove(position); err != nil {
+        return fmt.Errorf("ошибка удаления тега: %v", err)
+    }
+
+    delete(db.tagPositions, tagKey{id, tag})
+    db.tagIndex[tag] = removeID(db.tagIndex[tag], id)
+    if len(db.tagIndex[tag]) == 0 {
+        delete(db.tagIndex, tag)
+    }
+    db.bookTags[id] = removeString(db.bookTags[id], tag)
+    if len(db.bookTags[id]) == 0 {
+        delete(db.bookTags, id)
+    }
+    return nil
+}
+
+// O(t) t - количество тегов книги. Приводит набор тегов книги к tags
+func (db *Database) SetTags(id int32, tags []string) error {
+    wanted := make(map[string]bool)
+    for _, tag := range tags {
+        if tag = normalizeTag(tag); tag != "" {
+            wanted[tag] = true
+        }
+    }
+
+    for _, tag := range db.GetTags(id) {
+        if !wanted[tag] {
+            if err := db.RemoveTag(id, tag); err != nil {
+                return err
+            }
+        }
+    }
+    for tag := range wanted {
+        if err := db.AddTag(id, tag); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// O(t log t)
+func (db *Database) GetTags(id int32) []string {
+    tags := append([]string(nil), db.bookTags[id]...)
+    sort.Strings(tags)
+    return tags
+}
+
+// O(s) s - суммарный размер списков по тегам, возвращает тег -> количество книг.
+// Книги в корзине не считаются (их теги хранятся до восстановления), тег только
+// с такими книгами в результат не попадает
+func (db *Database) GetAllTags() map[string]int {
+    counts := make(map[string]int, len(db.tagIndex))
+    for tag, ids := range db.tagIndex {
+        count := 0
+        for _, id := range ids {
+            if !db.InTrash(id) {
+                count++
+            }
+        }
+        if count > 0 {
+            counts[tag] = count
+        }
+    }
+    return counts
+}
+
+// O(s + m log m) s - суммарный размер списков по тегам запроса, m - размер результата
+// Пустой результат - не ошибка, это обычное состояние фильтра
+func (db *Database) FindByTags(query TagQuery) ([]BookView, error) {
+    var candidates map[int32]bool
+
+    // сужаем множество по каждому обязательному тегу
+    for _, tag := range query.All {
+        ids := make(map[int32]bool)
+        for _, id := range db.tagIndex[normalizeTag(tag)] {
+            if candidates == nil || candidates[id] {
+                ids[id] = true
+            }
+        }
+        candidates = ids
+    }
+
+    if len(query.Any) > 0 {
+        ids := make(map[int32]bool)
+        for _, tag := range query.Any {
+            for _, id := range db.tagIndex[normalizeTag(tag)] {
+                if candidates == nil || candidates[id] {
+                    ids[id] = true
+                }
+            }
+        }
+        candidates = ids
+    }
+
+    // только исключения - начинаем со всех книг
+    if candidates == nil {
+        candidates = make(map[int32]bool, len(db.idIndex))
+        for id := range db.idIndex {
+            candidates[id] = true
+        }
+    }
+
+    for _, tag := range query.None {
+        for _, id := range db.tagIndex[normalizeTag(tag)] {
+            delete(candidates, id)
+        }
+    }
+
+    result := make([]BookView, 0, len(candidates))
+    for id := range candidates {
+        book, err := db.FindByID(id)
+        if err != nil {
+            continue
+        }
+        result = append(result, book.ToView())
+    }
+
+    sort.Slice(result, func(i, j int) bool {
+        return result[i].ID < result[j].ID
+    })
+    return result, nil
+}
+
+// O(t) t - количество тегов книги
+func (db *Database) removeBookTags(id int32) error {
+    for _, tag := range db.GetTags(id) {
+        if err := db.RemoveTag(id, tag); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// O(t * k) теги переезжают вместе с книгой при смене ID.
+// Возвращает функцию отката переезда
+func (db *Database) moveTags(oldID, newID int32) (func() error, error) {
+    tags := db.GetTags(oldID)
+    rewrites := make([]recordRewrite, len(tags))
+    for i, tag := range tags {
+        rewrites[i] = recordRewrite{
+            position: db.tagPositions[tagKey{oldID, tag}],
+            old:      tagToBytes(oldID, tag),
+            new:      tagToBytes(newID, tag),
+        }
+    }
+    if err := db.tags.rewriteAll(rewrites); err != nil {
+        return nil, fmt.Errorf("ошибка записи тега: %v", err)
+    }
+
+    // индексы меняются только после того, как записаны все теги
+    relink := func(from, to int32) {
+        for _, tag := range tags {
+            moveKey(db.tagPositions, tagKey{from, tag}, tagKey{to, tag})
+            db.tagIndex[tag] = append(removeID(db.tagIndex[tag], from), to)
+        }
+        moveKey(db.bookTags, from, to)
+    }
+    relink(oldID, newID)
+    return func() error {
+        if err := db.tags.revertAll(rewrites); err != nil {
+            return err
+        }
+        relink(newID, oldID)
+        return nil
+    }, nil
+}
+
+// O(k)
+func removeID(ids []int32, id int32) []int32 {
+    for i, current := range ids {
+        if current == id {
+            return append(ids[:i], ids[i+1:]...)
+        }
+    }
+    return ids
+}
+
+// O(k)
+func removeString(values []string, value string) []string {
+    for i, current := range values {
+        if current == value {
+            return append(values[:i], values[i+1:]...)
+        }
+    }
+    return values
+}
\ No newline at end of file
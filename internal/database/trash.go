@@ -0,0 +1,302 @@
+package database
+
+import (
+    "encoding/binary"
+    "fmt"
+    "path/filepath"
+    "sort"
+    "time"
+)
+
+// Корзина: DeleteBook не стирает запись, а оставляет ее в своем слоте books.db
+// и кладет ссылку в trash.db. Книга пропадает из индексов, но сохраняет
+// теги, обложку и экземпляры. Слот освобождается только при окончательном
+// удалении (PurgeBook), поэтому Compact переносит книги из корзины как живые.
+// Запись в trash.db:
+// BookID    int32 - 4 байта
+// Reserved  int32 - 4 байта
+// DeletedAt int64 - 8 байт, unix-время в секундах
+const trashRecordSize = 16
+
+// Срок хранения в корзине в trash_policy.db - одна запись. Magic отличает
+// сохраненный 0 ("без срока") от пустого слота
+// Magic [4]byte - 4 байта ("TRSH")
+// Days  int32   - 4 байта
+const (
+    trashPolicyRecordSize = 8
+    trashPolicyMagic      = "TRSH"
+)
+
+// Книга в корзине
+type TrashEntry struct {
+    Book      BookView
+    DeletedAt time.Time
+    PurgeAt   time.Time // нулевое - хранится без срока
+}
+
+// O(1)
+func trashToBytes(bookID int32, deletedAt time.Time) []byte {
+    buf := make([]byte, trashRecordSize)
+    binary.LittleEndian.PutUint32(buf[0:4], uint32(bookID))
+    binary.LittleEndian.PutUint64(buf[8:16], uint64(timeToUnix(deletedAt)))
+    return buf
+}
+
+// O(1)
+func bytesToTrash(data []byte) (int32, time.Time) {
+    return int32(binary.LittleEndian.Uint32(data[0:4])),
+        unixToTime(int64(binary.LittleEndian.Uint64(data[8:16])))
+}
+
+// O(n) вызывается после rebuildIndexes: книги из корзины, попавшие в индексы
+// при сканировании books.db, убираются из них в trashIndex
+func (db *Database) loadTrash() error {
+    db.trashIndex = make(map[int32]int64)
+    db.trashPositions = make(map[int32]int64)
+
+    var books []*Book
+    var positions []int64
+    err := db.trash.scan(func(position int64, data []byte) {
+        bookID, _ := bytesToTrash(data)
+        bookPosition, exists := db.idIndex[bookID]
+        if !exists {
+            return
+        }
+        book, err := db.readRecord(bookPosition)
+        if err != nil {
+            return
+        }
+        books = append(books, book)
+        positions = append(positions, bookPosition)
+        db.trashPositions[bookID] = position
+    })
+    if err != nil {
+        return err
+    }
+
+    for i, book := range books {
+        db.removeFromIndexes(book, positions[i])
+        db.trashIndex[book.ID] = positions[i]
+    }
+    return nil
+}
+
+// O(1) срок переживает перезапуск и читается до первой очистки корзины
+// в OpenDatabase; ClearDatabase его не трогает - это настройка, а не данные
+func (db *Database) openTrashPolicy(dir string) error {
+    file, err := openRecordFile(filepath.Join(dir, "trash_policy.db"), trashPolicyRecordSize)
+    if err != nil {
+        return err
+    }
+    db.trashPolicyFile = file
+
+    data, err := file.read(0)
+    if err != nil {
+        // файла еще нет или он пустой - остается срок по умолчанию
+        return nil
+    }
+    if string(data[0:4]) != trashPolicyMagic {
+        return fmt.Errorf("поврежден файл trash_policy.db")
+    }
+    db.trashRetentionDays = int(int32(binary.LittleEndian.Uint32(data[4:8])))
+    return nil
+}
+
+// O(1) сколько дней книга лежит в корзине до автоматического удаления, 0 - без срока
+func (db *Database) SetTrashRetentionDays(days int) error {
+    if days < 0 {
+        return fmt.Errorf("срок хранения в корзине не может быть отрицательным")
+    }
+
+    buf := make([]byte, trashPolicyRecordSize)
+    copy(buf[0:4], trashPolicyMagic)
+    binary.LittleEndian.PutUint32(buf[4:8], uint32(int32(days)))
+    if err := db.trashPolicyFile.write(0, buf); err != nil {
+        return fmt.Errorf("ошибка записи срока хранения в корзине: %v", err)
+    }
+
+    db.trashRetentionDays = days
+    return nil
+}
+
+// O(1)
+func (db *Database) TrashRetentionDays() int {
+    return db.trashRetentionDays
+}
+
+// O(1)
+func (db *Database) InTrash(id int32) bool {
+    _, exists := db.trashIndex[id]
+    return exists
+}
+
+// O(1) книга из корзины вместе с датой удаления
+func (db *Database) findTrash(id int32) (TrashEntry, error) {
+    bookPosition, exists := db.trashIndex[id]
+    if !exists {
+        return TrashEntry{}, fmt.Errorf("книги с ID %d нет в корзине", id)
+    }
+    book, err := db.readRecord(bookPosition)
+    if err != nil {
+        return TrashEntry{}, err
+    }
+    data, err := db.trash.read(db.trashPositions[id])
+    if err != nil {
+        return TrashEntry{}, fmt.Errorf("ошибка чтения корзины: %v", err)
+    }
+
+    _, deletedAt := bytesToTrash(data)
+    entry := TrashEntry{Book: book.ToView(), DeletedAt: deletedAt}
+    if db.trashRetentionDays > 0 {
+        entry.PurgeAt = deletedAt.AddDate(0, 0, db.trashRetentionDays)
+    }
+    return entry, nil
+}
+
+// O(n log n) n - книг в корзине, сначала удаленные последними
+func (db *Database) GetTrash() ([]TrashEntry, error) {
+    entries := make([]TrashEntry, 0, len(db.trashIndex))
+    for id := range db.trashIndex {
+        entry, err := db.findTrash(id)
+        if err != nil {
+            return nil, err
+        }
+        entries = append(entries, entry)
+    }
+    sort.Slice(entries, func(i, j int) bool {
+        if !entries[i].DeletedAt.Equal(entries[j].DeletedAt) {
+            return entries[i].DeletedAt.After(entries[j].DeletedAt)
+        }
+        return entries[i].Book.ID < entries[j].Book.ID
+    })
+    return entries, nil
+}
+
+// O(1) в среднем, перенос книги в корзину из DeleteBook
+func (db *Database) moveToTrash(book *Book, position int64, at time.Time) error {
+    trashPosition, err := db.trash.insert(trashToBytes(book.ID, at))
+    if err != nil {
+        return fmt.Errorf("ошибка записи в корзину: %v", err)
+    }
+    db.removeFromIndexes(book, position)
+    db.trashIndex[book.ID] = position
+    db.trashPositions[book.ID] = trashPosition
+    return nil
+}
+
+// O(1) в среднем, книга возвращается в свой слот со всеми тегами и экземплярами.
+// Авторы, объединенные, пока книга лежала в корзине, приводятся к каноническому имени
+func (db *Database) RestoreFromTrash(id int32) error {
+    position, exists := db.trashIndex[id]
+    if !exists {
+        return fmt.Errorf("книги с ID %d нет в корзине", id)
+    }
+    book, err := db.readRecord(position)
+    if err != nil {
+        return err
+    }
+
+    author := BytesToString(book.Author[:])
+    if canonical := db.CanonicalAuthor(author); canonical != author {
+        copyStringToBytes(canonical, clearBytes(book.Author[:]))
+        if err := db.writeRecord(book, position); err != nil {
+            return fmt.Errorf("ошибка перезаписи книги с ID %d: %v", id, err)
+        }
+    }
+
+    if err := db.trash.remove(db.trashPositions[id]); err != nil {
+        return fmt.Errorf("ошибка удаления из корзины: %v", err)
+    }
+    delete(db.trashIndex, id)
+    delete(db.trashPositions, id)
+    db.updateIndexes(book, position)
+
+    if err := db.logAudit(AuditUndelete, id, nil, viewRef(book)); err != nil {
+        return err
+    }
+    if !db.HasItems(id) {
+        // тираж книги без экземпляров ведется вручную, его не трогаем
+        return nil
+    }
+    // пока книга лежала в корзине, экземпляры могли поменять статус
+    return db.syncCopies(id)
+}
+
+// O(t + k) t - тегов, k - экземпляров. Окончательно удаляет книгу из корзины,
+// слот освобождается и будет переиспользован или убран при Compact
+func (db *Database) PurgeBook(id int32) error {
+    position, exists := db.trashIndex[id]
+    if !exists {
+        return fmt.Errorf("книги с ID %d нет в корзине", id)
+    }
+    book, err := db.readRecord(position)
+    if err != nil {
+        return err
+    }
+
+    // затираем слот: запись с ID 0 после перезапуска считается свободной
+    if err := db.writeRecord(&Book{}, position); err != nil {
+        return err
+    }
+    db.freeList = append(db.freeList, position)
+
+    if err := db.trash.remove(db.trashPositions[id]); err != nil {
+        return fmt.Errorf("ошибка удаления из корзины: %v", err)
+    }
+    delete(db.trashIndex, id)
+    delete(db.trashPositions, id)
+
+    if err := db.logAudit(AuditPurge, id, viewRef(book), nil); err != nil {
+        return err
+    }
+
+    if db.HasCover(id) {
+        if err := db.DeleteCover(id); err != nil {
+            return err
+        }
+    }
+    if err := db.removeISBN(id); err != nil {
+        return err
+    }
+    if err := db.removeBookTags(id); err != nil {
+        return err
+    }
+    return db.removeBookItems(id)
+}
+
+// O(n) окончательно удаляет все книги из корзины, возвращает их число
+func (db *Database) EmptyTrash() (int, error) {
+    ids := make([]int32, 0, len(db.trashIndex))
+    for id := range db.trashIndex {
+        ids = append(ids, id)
+    }
+    for i, id := range ids {
+        if err := db.PurgeBook(id); err != nil {
+            return i, err
+        }
+    }
+    return len(ids), nil
+}
+
+// O(n) удаляет книги, пролежавшие в корзине дольше срока хранения
+func (db *Database) PurgeExpiredTrash(at time.Time) (int, error) {
+    if db.trashRetentionDays == 0 {
+        return 0, nil
+    }
+
+    entries, err := db.GetTrash()
+    if err != nil {
+        return 0, err
+    }
+    purged := 0
+    for _, entry := range entries {
+        if at.Before(entry.PurgeAt) {
+            continue
+        }
+        if err := db.PurgeBook(entry.Book.ID); err != nil {
+            return purged, err
+        }
+        purged++
+    }
+    return purged, nil
+}
\ No newline at end of file
@@ -0,0 +1,283 @@
+package database
+
+import (
+    "errors"
+    "math/rand"
+    "os"
+    "path/filepath"
+    "reflect"
+    "strconv"
+    "strings"
+    "testing"
+    "testing/quick"
+    "time"
+    "unicode/utf8"
+)
+
+// Любые поля после экранирования помещаются в одну строку и читаются обратно без потерь
+func TestTxtLineRoundTrip(t *testing.T) {
+    property := func(fields []string) bool {
+        if len(fields) == 0 {
+            return true
+        }
+        line := joinTxtLine(fields)
+        if strings.ContainsAny(line, "\r\n") {
+            return false
+        }
+        return reflect.DeepEqual(splitTxtLine(line), fields)
+    }
+    if err := quick.Check(property, &quick.Config{MaxCount: 5000}); err != nil {
+        t.Error(err)
+    }
+}
+
+// Строки из служебных символов формата - самый опасный случай для экранирования
+func TestTxtLineRoundTripSpecialCharacters(t *testing.T) {
+    alphabet := []string{`\`, "|", "\n", "\r", "n", "r", "а", " ", `\\`, `\|`, `\n`}
+    random := rand.New(rand.NewSource(1))
+    for i := 0; i < 5000; i++ {
+        fields := make([]string, 1+random.Intn(5))
+        for j := range fields {
+            var b strings.Builder
+            for k := random.Intn(8); k > 0; k-- {
+                b.WriteString(alphabet[random.Intn(len(alphabet))])
+            }
+            fields[j] = b.String()
+        }
+        if got := splitTxtLine(joinTxtLine(fields)); !reflect.DeepEqual(got, fields) {
+            t.Fatalf("%q -> %q -> %q", fields, joinTxtLine(fields), got)
+        }
+    }
+}
+
+// Строка, которую можно сохранить в поле книги: без нулевых байт,
+// не пустая и не длиннее size байт (обрезаем по границе символа)
+func storableString(s string, size int) string {
+    s = strings.ReplaceAll(s, "\x00", "")
+    if strings.TrimSpace(s) == "" {
+        s = "x" + s
+    }
+    for len(s) > size {
+        _, width := utf8.DecodeLastRuneInString(s)
+        s = s[:len(s)-width]
+    }
+    return s
+}
+
+// Экспорт -> импорт через файл не теряет ни одного символа названия и автора
+func TestExportImportTxtRoundTrip(t *testing.T) {
+    dir := t.TempDir()
+    run := 0
+    property := func(title, author string, year uint16, copies uint16) bool {
+        run++
+        runDir := filepath.Join(dir, strconv.Itoa(run))
+        source, err := OpenDatabase(filepath.Join(runDir, "source", "books.db"))
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer source.Close()
+        target, err := OpenDatabase(filepath.Join(runDir, "target", "books.db"))
+        if err != nil {
+            t.Fatal(err)
+        }
+        defer target.Close()
+
+        // служебные символы формата в начале, чтобы они не срезались по длине
+        book := BookView{
+            Title:  storableString(`a|b\n\`+"\n\r"+title, 100),
+            Author: storableString(author+`\|`, 40),
+            Year:   1 + int32(year)%2000,
+            Copies: int32(copies),
+        }
+        if _, err := source.AddBook(book); err != nil {
+            t.Fatalf("%q: %v", book, err)
+        }
+
+        path := filepath.Join(runDir, "books.txt")
+        if err := source.ExportToTxt(path); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := target.ImportFromTxt(path, ImportOptions{}); err != nil {
+            t.Logf("%q: %v", book, err)
+            return false
+        }
+
+        want, _ := source.GetAllBooks()
+        got, _ := target.GetAllBooks()
+        return reflect.DeepEqual(want, got)
+    }
+    if err := quick.Check(property, &quick.Config{MaxCount: 300}); err != nil {
+        t.Error(err)
+    }
+}
+
+// Файлы старого формата без экранирования читаются как раньше
+func TestImportTxtWithoutEscaping(t *testing.T) {
+    dir := t.TempDir()
+    db, err := OpenDatabase(filepath.Join(dir, "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    content := "ID|Название|Автор|Год|Тираж\n" +
+        "1|Преступление и наказание|Федор Достоевский|1866|5000\n" +
+        `2|Заметки C:\книги\и\t.д.|Автор|1900|10` + "\n"
+    path := filepath.Join(dir, "old.txt")
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    summary, err := db.ImportFromTxt(path, ImportOptions{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if summary.Added != 2 {
+        t.Fatalf("импортировано %d книг, ожидалось 2", summary.Added)
+    }
+
+    book, err := db.FindByID(2)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if title := book.ToView().Title; title != `Заметки C:\книги\и\t.д.` {
+        t.Errorf("название %q", title)
+    }
+}
+
+// В старом файле без метки "\n" и "\|" - обычные символы, а не экранирование
+func TestImportLegacyTxtKeepsBackslashes(t *testing.T) {
+    dir := t.TempDir()
+    db, err := OpenDatabase(filepath.Join(dir, "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+
+    content := "ID|Название|Автор|Год|Тираж\n" +
+        `1|Файлы C:\new\reports|Автор\|1900|1` + "\n"
+    path := filepath.Join(dir, "legacy.txt")
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := db.ImportFromTxt(path, ImportOptions{}); err != nil {
+        t.Fatal(err)
+    }
+    book, err := db.FindByID(1)
+    if err != nil {
+        t.Fatal(err)
+    }
+    view := book.ToView()
+    if view.Title != `Файлы C:\new\reports` || view.Author != `Автор\` {
+        t.Errorf("книга прочитана как %q / %q", view.Title, view.Author)
+    }
+}
+
+// Ошибка записи указывает на свою строку, а мягкий режим собирает все ошибки
+func TestImportTxtErrors(t *testing.T) {
+    dir := t.TempDir()
+    content := "ID|Название|Автор|Год|Тираж\n" +
+        "1|Без автора||1900|1\n" +
+        "x|Неверный ID|Автор|1900|1\n" +
+        "3|Верная|Автор|1900|1\n"
+    path := filepath.Join(dir, "errors.txt")
+    if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+        t.Fatal(err)
+    }
+
+    strict, err := OpenDatabase(filepath.Join(dir, "strict", "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer strict.Close()
+    _, err = strict.ImportFromTxt(path, ImportOptions{})
+    var importErr *ImportError
+    if !errors.As(err, &importErr) || importErr.Line != 3 || importErr.Column != "ID" {
+        t.Fatalf("ожидалась ошибка ID в строке 3, получено %v", err)
+    }
+
+    lenient, err := OpenDatabase(filepath.Join(dir, "lenient", "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer lenient.Close()
+    summary, err := lenient.ImportFromTxt(path, ImportOptions{Lenient: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if summary.Added != 1 {
+        t.Errorf("импортировано %d книг, ожидалась 1", summary.Added)
+    }
+    var lines []int
+    for _, importErr := range summary.Errors {
+        lines = append(lines, importErr.Line)
+    }
+    if !reflect.DeepEqual(lines, []int{2, 3}) {
+        t.Errorf("ошибки в строках %v, ожидались 2 и 3", lines)
+    }
+    if summary.Errors[0].Column != "Автор" || summary.Errors[0].Raw != "1|Без автора||1900|1" {
+        t.Errorf("ошибка строки 2: %+v", summary.Errors[0])
+    }
+}
+// Книга, заведенная до появления журнала и впервые измененная после at,
+// возвращается к своему состоянию на at, а не уходит в корзину
+func TestRestoreBookOlderThanAudit(t *testing.T) {
+    dir := t.TempDir()
+    db, err := OpenDatabase(filepath.Join(dir, "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    original := BookView{ID: 1, Title: "Преступление и наказание", Author: "Федор Достоевский", Year: 1866, Copies: 5}
+    if _, err := db.AddBook(original); err != nil {
+        t.Fatal(err)
+    }
+    db.Close()
+    if err := os.Remove(filepath.Join(dir, "audit.log")); err != nil {
+        t.Fatal(err)
+    }
+
+    db, err = OpenDatabase(filepath.Join(dir, "books.db"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer db.Close()
+    at := time.Now()
+    time.Sleep(time.Millisecond)
+    edited := original
+    edited.Title = "Идиот"
+    if err := db.UpdateBook(edited); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := db.AddBook(BookView{ID: 2, Title: "Бесы", Author: "Федор Достоевский", Year: 1872, Copies: 1}); err != nil {
+        t.Fatal(err)
+    }
+
+    if _, err := db.RestoreDatabaseAt(at); err != nil {
+        t.Fatal(err)
+    }
+    book, err := db.FindByID(1)
+    if err != nil {
+        t.Fatalf("книга 1 пропала после отката: %v", err)
+    }
+    if book.ToView() != original {
+        t.Errorf("книга 1 после отката базы: %+v", book.ToView())
+    }
+    if _, err := db.FindByID(2); err == nil {
+        t.Error("книга 2, добавленная после at, осталась в базе")
+    }
+
+    if err := db.UpdateBook(edited); err != nil {
+        t.Fatal(err)
+    }
+    if err := db.RestoreAt(1, at); err != nil {
+        t.Fatal(err)
+    }
+    book, err = db.FindByID(1)
+    if err != nil {
+        t.Fatalf("книга 1 пропала после отката: %v", err)
+    }
+    if book.ToView() != original {
+        t.Errorf("книга 1 после отката книги: %+v", book.ToView())
+    }
+}
\ No newline at end of file
@@ -0,0 +1,132 @@
+package database
+
+import (
+    "fmt"
+    "strings"
+    "time"
+)
+
+// Ошибка конкретного поля. Field - то же название поля, что и в FindBooks
+type FieldError struct {
+    Field   string
+    Message string
+}
+
+func (e FieldError) Error() string {
+    return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Все ошибки проверки одной книги сразу, а не только первая
+type ValidationError struct {
+    ID     int32
+    Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+    lines := make([]string, len(e.Errors))
+    for i, fieldErr := range e.Errors {
+        lines[i] = "- " + fieldErr.Error()
+    }
+    if e.ID == 0 {
+        return fmt.Sprintf("некорректные данные книги:\n%s", strings.Join(lines, "\n"))
+    }
+    return fmt.Sprintf("некорректные данные книги с ID %d:\n%s", e.ID, strings.Join(lines, "\n"))
+}
+
+// Проверка книги перед записью. Вызывается из AddBook и UpdateBook,
+// поэтому одинаково работает для GUI, импорта и любого другого вызова
+type Validator interface {
+    Validate(book BookView) error
+}
+
+// Пользовательское правило: nil, если все в порядке
+type ValidationRule func(book BookView) *FieldError
+
+// Стандартный набор ограничений с возможностью добавить свои правила
+type BookValidator struct {
+    MinYear       int32
+    MaxYear       int32
+    MinCopies     int32
+    RequireTitle  bool
+    RequireAuthor bool
+    Rules         []ValidationRule
+}
+
+// Год от 1 до следующего за текущим, тираж неотрицательный,
+// название и автор обязательны и помещаются в поля записи
+func DefaultValidator() *BookValidator {
+    return &BookValidator{
+        MinYear:       1,
+        MaxYear:       int32(time.Now().Year()) + 1,
+        MinCopies:     0,
+        RequireTitle:  true,
+        RequireAuthor: true,
+    }
+}
+
+// O(1)
+func (v *BookValidator) AddRule(rule ValidationRule) {
+    v.Rules = append(v.Rules, rule)
+}
+
+// O(m + r) m - длина строковых полей, r - количество пользовательских правил
+func (v *BookValidator) Validate(book BookView) error {
+    var errs []FieldError
+
+    if v.RequireTitle && strings.TrimSpace(book.Title) == "" {
+        errs = append(errs, FieldError{Field: "Название", Message: "не может быть пустым"})
+    }
+    if len([]byte(book.Title)) > 100 {
+        errs = append(errs, FieldError{Field: "Название", Message: "длиннее 100 байт"})
+    }
+
+    if v.RequireAuthor && strings.TrimSpace(book.Author) == "" {
+        errs = append(errs, FieldError{Field: "Автор", Message: "не может быть пустым"})
+    }
+    if len([]byte(book.Author)) > 40 {
+        errs = append(errs, FieldError{Field: "Автор", Message: "длиннее 40 байт"})
+    }
+
+    if book.Year < v.MinYear || book.Year > v.MaxYear {
+        errs = append(errs, FieldError{
+            Field:   "Год издания",
+            Message: fmt.Sprintf("должен быть от %d до %d", v.MinYear, v.MaxYear),
+        })
+    }
+
+    if book.Copies < v.MinCopies {
+        errs = append(errs, FieldError{
+            Field:   "Тираж",
+            Message: fmt.Sprintf("не может быть меньше %d", v.MinCopies),
+        })
+    }
+
+    for _, rule := range v.Rules {
+        if fieldErr := rule(book); fieldErr != nil {
+            errs = append(errs, *fieldErr)
+        }
+    }
+
+    if len(errs) > 0 {
+        return &ValidationError{ID: book.ID, Errors: errs}
+    }
+    return nil
+}
+
+// O(1) nil отключает проверку
+func (db *Database) SetValidator(v Validator) {
+    db.validator = v
+}
+
+// O(1)
+func (db *Database) Validator() Validator {
+    return db.validator
+}
+
+// O(1) + стоимость проверки
+func (db *Database) validate(book BookView) error {
+    if db.validator == nil {
+        return nil
+    }
+    return db.validator.Validate(book)
+}
\ No newline at end of file
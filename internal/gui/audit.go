@@ -0,0 +1,197 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+const auditTimeLayout = "02.01.2006 15:04:05"
+
+// Краткое описание книги для журнала
+func describeBook(book *database.BookView) string {
+    if book == nil {
+        return "—"
+    }
+    return fmt.Sprintf("%s — %s, %d г., тираж %d", book.Title, book.Author, book.Year, book.Copies)
+}
+
+// Для изменения показываем только поля, которые поменялись
+func describeChange(entry database.AuditEntry) string {
+    if entry.Before == nil || entry.After == nil {
+        if entry.After != nil {
+            return describeBook(entry.After)
+        }
+        return describeBook(entry.Before)
+    }
+
+    before, after := entry.Before, entry.After
+    var changes []string
+    if before.ID != after.ID {
+        changes = append(changes, fmt.Sprintf("ID: %d → %d", before.ID, after.ID))
+    }
+    if before.Title != after.Title {
+        changes = append(changes, fmt.Sprintf("название: %s → %s", before.Title, after.Title))
+    }
+    if before.Author != after.Author {
+        changes = append(changes, fmt.Sprintf("автор: %s → %s", before.Author, after.Author))
+    }
+    if before.Year != after.Year {
+        changes = append(changes, fmt.Sprintf("год: %d → %d", before.Year, after.Year))
+    }
+    if before.Copies != after.Copies {
+        changes = append(changes, fmt.Sprintf("тираж: %d → %d", before.Copies, after.Copies))
+    }
+    if len(changes) == 0 {
+        return "без изменений"
+    }
+    return strings.Join(changes, "; ")
+}
+
+// Пустая строка - без ограничения
+func parseDate(text string) (time.Time, error) {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return time.Time{}, nil
+    }
+    date, err := time.ParseInLocation(dateLayout, text, time.Local)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("дата должна быть в формате ДД.ММ.ГГГГ: %s", text)
+    }
+    return date, nil
+}
+
+func (a *App) showAuditDialog() {
+    var entries []database.AuditEntry
+
+    auditTable := widget.NewTable(
+        func() (int, int) {
+            return len(entries) + 1, 5
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"Время", "Операция", "Пользователь", "ID", "Изменения"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(entries) {
+                return
+            }
+            // новые записи сверху
+            entry := entries[len(entries)-id.Row]
+            switch id.Col {
+            case 0:
+                label.SetText(entry.Time.Format(auditTimeLayout))
+            case 1:
+                label.SetText(entry.Op.String())
+            case 2:
+                label.SetText(entry.Actor)
+            case 3:
+                label.SetText(fmt.Sprintf("%d", entry.BookID))
+            case 4:
+                label.SetText(describeChange(entry))
+            }
+        },
+    )
+    auditTable.SetColumnWidth(0, 160)
+    auditTable.SetColumnWidth(1, 110)
+    auditTable.SetColumnWidth(2, 120)
+    auditTable.SetColumnWidth(3, 60)
+    auditTable.SetColumnWidth(4, 600)
+
+    idEntry := widget.NewEntry()
+    idEntry.SetPlaceHolder("Все книги")
+    fromEntry := widget.NewEntry()
+    fromEntry.SetPlaceHolder("ДД.ММ.ГГГГ")
+    toEntry := widget.NewEntry()
+    toEntry.SetPlaceHolder("ДД.ММ.ГГГГ")
+    countLabel := widget.NewLabel("")
+
+    applyFilter := func() {
+        query := database.AuditQuery{}
+        if idEntry.Text != "" {
+            id, err := strconv.Atoi(idEntry.Text)
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ID должен быть числом"), a.window)
+                return
+            }
+            query.BookID = int32(id)
+        }
+
+        from, err := parseDate(fromEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        to, err := parseDate(toEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        query.From = from
+        if !to.IsZero() {
+            // дата "по" включается целиком
+            query.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+        }
+
+        result, err := a.database.GetAudit(query)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        entries = result
+        countLabel.SetText(fmt.Sprintf("Записей: %d", len(entries)))
+        auditTable.Refresh()
+    }
+    applyFilter()
+
+    idEntry.OnSubmitted = func(_ string) {
+        applyFilter()
+    }
+
+    actorEntry := widget.NewEntry()
+    actorEntry.SetText(a.database.Actor())
+    actorEntry.OnSubmitted = func(name string) {
+        if err := a.database.SetActor(name); err != nil {
+            dialog.ShowError(err, a.window)
+            actorEntry.SetText(a.database.Actor())
+        }
+    }
+
+    filters := container.NewHBox(
+        widget.NewLabel("ID книги:"), idEntry,
+        widget.NewLabel("с"), fromEntry,
+        widget.NewLabel("по"), toEntry,
+        widget.NewButton("🔍 Показать", applyFilter),
+        widget.NewButton("⏪ Откатить базу", a.showRestoreDatabaseDialog),
+        countLabel,
+    )
+    actorRow := container.NewBorder(nil, nil,
+        widget.NewLabel("Изменения вносит:"),
+        widget.NewButton("Сохранить", func() {
+            actorEntry.OnSubmitted(actorEntry.Text)
+        }),
+        actorEntry,
+    )
+
+    content := container.NewBorder(
+        container.NewVBox(actorRow, filters),
+        nil, nil, nil,
+        auditTable,
+    )
+
+    auditDialog := dialog.NewCustom("Журнал изменений", "Закрыть", content, a.window)
+    auditDialog.Resize(fyne.NewSize(1100, 600))
+    auditDialog.Show()
+}
\ No newline at end of file
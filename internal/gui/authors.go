@@ -0,0 +1,153 @@
+package gui
+
+import (
+    "fmt"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+func (a *App) showAuthorsDialog() {
+    groups := a.database.FindDuplicateAuthors()
+    var selectedGroup []string
+
+    variantsCheck := widget.NewCheckGroup(nil, nil)
+    canonicalEntry := widget.NewEntry()
+    canonicalEntry.SetPlaceHolder("Каноническое имя автора")
+
+    groupsLabel := widget.NewLabel("")
+    updateGroupsLabel := func() {
+        if len(groups) == 0 {
+            groupsLabel.SetText("Похожих авторов не найдено")
+        } else {
+            groupsLabel.SetText(fmt.Sprintf("Групп похожих авторов: %d", len(groups)))
+        }
+    }
+    updateGroupsLabel()
+
+    groupsList := widget.NewList(
+        func() int {
+            return len(groups)
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            item.(*widget.Label).SetText(strings.Join(groups[id], "; "))
+        },
+    )
+    groupsList.OnSelected = func(id widget.ListItemID) {
+        selectedGroup = groups[id]
+        variantsCheck.Options = selectedGroup
+        variantsCheck.SetSelected(selectedGroup)
+        canonicalEntry.SetText(selectedGroup[0])
+    }
+
+    mergeButton := widget.NewButton("Объединить", func() {
+        if selectedGroup == nil {
+            dialog.ShowInformation("Ошибка", "Выберите группу авторов", a.window)
+            return
+        }
+        if len(variantsCheck.Selected) == 0 {
+            dialog.ShowInformation("Ошибка", "Отметьте варианты написания для объединения", a.window)
+            return
+        }
+
+        count, err := a.database.MergeAuthors(canonicalEntry.Text, variantsCheck.Selected)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+
+        dialog.ShowInformation("Успех",
+            fmt.Sprintf("Авторы объединены в '%s'\nПерезаписано книг: %d", canonicalEntry.Text, count), a.window)
+        a.refreshTable()
+
+        groups = a.database.FindDuplicateAuthors()
+        selectedGroup = nil
+        variantsCheck.Options = nil
+        variantsCheck.SetSelected(nil)
+        canonicalEntry.SetText("")
+        groupsList.UnselectAll()
+        groupsList.Refresh()
+        updateGroupsLabel()
+    })
+
+    aliasesLabel := widget.NewLabel("")
+    aliasesLabel.Wrapping = fyne.TextWrapWord
+
+    renameEntry := widget.NewEntry()
+    renameEntry.SetPlaceHolder("Новое имя автора")
+
+    authorSelect := widget.NewSelect(a.database.GetAllAuthors(), func(author string) {
+        renameEntry.SetText(author)
+        aliases := a.database.AuthorAliases(author)
+        if len(aliases) == 0 {
+            aliasesLabel.SetText("Псевдонимов нет")
+        } else {
+            aliasesLabel.SetText("Псевдонимы: " + strings.Join(aliases, "; "))
+        }
+    })
+    authorSelect.PlaceHolder = "Выберите автора"
+
+    renameButton := widget.NewButton("Переименовать", func() {
+        if authorSelect.Selected == "" {
+            dialog.ShowInformation("Ошибка", "Выберите автора", a.window)
+            return
+        }
+
+        oldName := authorSelect.Selected
+        count, err := a.database.RenameAuthor(oldName, renameEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+
+        dialog.ShowInformation("Успех",
+            fmt.Sprintf("Автор '%s' переименован\nПерезаписано книг: %d\nСтарое имя сохранено как псевдоним", oldName, count), a.window)
+        a.refreshTable()
+
+        authorSelect.Options = a.database.GetAllAuthors()
+        authorSelect.ClearSelected()
+        renameEntry.SetText("")
+        aliasesLabel.SetText("")
+        groups = a.database.FindDuplicateAuthors()
+        groupsList.Refresh()
+        updateGroupsLabel()
+    })
+
+    mergeContent := container.NewBorder(
+        groupsLabel,
+        container.NewVBox(
+            widget.NewSeparator(),
+            widget.NewLabel("Варианты написания:"),
+            variantsCheck,
+            widget.NewLabel("Объединить в:"),
+            canonicalEntry,
+            mergeButton,
+        ),
+        nil, nil,
+        groupsList,
+    )
+
+    renameContent := container.NewVBox(
+        widget.NewLabel("Автор:"),
+        authorSelect,
+        aliasesLabel,
+        widget.NewLabel("Новое имя:"),
+        renameEntry,
+        renameButton,
+    )
+
+    tabs := container.NewAppTabs(
+        container.NewTabItem("Похожие авторы", mergeContent),
+        container.NewTabItem("Переименование", renameContent),
+    )
+
+    authorsDialog := dialog.NewCustom("Авторы", "Закрыть", tabs, a.window)
+    authorsDialog.Resize(fyne.NewSize(700, 600))
+    authorsDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,114 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "path/filepath"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Библиография из главного меню: вся база, книги в таблице или выбранная книга
+func (a *App) showTableBibliographyDialog() {
+    books, err := a.database.GetAllBooks()
+    if err != nil {
+        dialog.ShowError(err, a.window)
+        return
+    }
+    sources := []bookSource{
+        {Name: fmt.Sprintf("Вся база (%d)", len(books)), Books: books},
+    }
+    if len(a.tagFilter) > 0 {
+        sources = append(sources, bookSource{Name: fmt.Sprintf("Книги в таблице (%d)", len(a.books)), Books: a.books})
+    }
+    if book, err := a.database.FindByID(a.selectedBookID); err == nil {
+        view := book.ToView()
+        sources = append(sources, bookSource{Name: "Выбранная книга: " + view.Title, Books: []database.BookView{view}})
+    }
+    a.showBibliographyDialog(sources)
+}
+
+func (a *App) showBibliographyDialog(sources []bookSource) {
+    sourceNames := make([]string, len(sources))
+    for i, source := range sources {
+        sourceNames[i] = source.Name
+    }
+    sourceSelect := widget.NewSelect(sourceNames, nil)
+    sourceSelect.SetSelectedIndex(0)
+
+    formats := []string{"BibTeX (.bib)", "RIS (.ris)"}
+    formatSelect := widget.NewSelect(formats, nil)
+    formatSelect.SetSelectedIndex(0)
+
+    info := widget.NewLabel("Ключ цитирования - фамилия автора и год (dostoevskii1866), совпадающие ключи различаются буквами a, b, c")
+    info.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("Книги", sourceSelect),
+        widget.NewFormItem("Формат", formatSelect),
+        widget.NewFormItem("", info),
+    )
+
+    optionsDialog := dialog.NewCustomConfirm("Экспорт библиографии", "Выбрать файл", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        books := sources[sourceSelect.SelectedIndex()].Books
+        if len(books) == 0 {
+            dialog.ShowInformation("Ошибка", "Нет книг для экспорта", a.window)
+            return
+        }
+        if formatSelect.SelectedIndex() == 1 {
+            a.saveReport("books.ris", ".ris", func(path string) error {
+                return a.database.ExportToRIS(path, books)
+            })
+            return
+        }
+        a.saveReport("books.bib", ".bib", func(path string) error {
+            return a.database.ExportToBibTeX(path, books)
+        })
+    }, a.window)
+    optionsDialog.Resize(fyne.NewSize(500, 250))
+    optionsDialog.Show()
+}
+
+func (a *App) showImportBibliographyDialog() {
+    a.chooseImportFile([]string{".bib", ".ris"}, func(path string) {
+        conflictSelect := newConflictSelect()
+        lenientCheck := newLenientCheck()
+
+        info := widget.NewLabel(fmt.Sprintf("Файл: %s\n\nЗаписи станут новыми книгами. Книга считается уже импортированной, "+
+            "если совпали название, автор и год; для нее выберите действие (перезапись заменяет теги, объединение дописывает их)", path))
+        info.Wrapping = fyne.TextWrapWord
+
+        form := widget.NewForm(
+            widget.NewFormItem("", info),
+            widget.NewFormItem("Книга уже есть", conflictSelect),
+            widget.NewFormItem("", lenientCheck),
+        )
+
+        optionsDialog := dialog.NewCustomConfirm("Импорт библиографии", "Импортировать", "Отмена", form, func(ok bool) {
+            if !ok {
+                return
+            }
+            opts := database.ImportOptions{
+                OnConflict: selectedConflictPolicy(conflictSelect),
+                Lenient:    lenientCheck.Checked,
+            }
+            if strings.ToLower(filepath.Ext(path)) == ".ris" {
+                a.runImport("импорт RIS", func() (database.ImportSummary, error) {
+                    return a.database.ImportFromRIS(path, opts)
+                })
+                return
+            }
+            a.runImport("импорт BibTeX", func() (database.ImportSummary, error) {
+                return a.database.ImportFromBibTeX(path, opts)
+            })
+        }, a.window)
+        optionsDialog.Resize(fyne.NewSize(560, 320))
+        optionsDialog.Show()
+    })
+}
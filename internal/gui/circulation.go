@@ -0,0 +1,376 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+const dateLayout = "02.01.2006"
+
+func formatDate(t time.Time) string {
+    if t.IsZero() {
+        return "—"
+    }
+    return t.Format(dateLayout)
+}
+
+func (a *App) refreshCirculation() {
+    members, err := a.database.GetAllMembers()
+    if err != nil {
+        fmt.Printf("Ошибка загрузки читателей: %v\n", err)
+        members = []database.MemberView{}
+    }
+    a.members = members
+
+    a.memberNames = make(map[int32]string, len(members))
+    for _, member := range members {
+        a.memberNames[member.ID] = member.Name
+    }
+
+    loans, err := a.database.GetLoans(a.activeLoansOnly)
+    if err != nil {
+        fmt.Printf("Ошибка загрузки выдач: %v\n", err)
+        loans = []database.LoanView{}
+    }
+    a.loans = loans
+    a.refreshOverdue()
+    a.refreshHolds()
+
+    a.bookTitles = make(map[int32]string)
+    bookIDs := make([]int32, 0, len(loans)+len(a.holds))
+    for _, loan := range loans {
+        bookIDs = append(bookIDs, loan.BookID)
+    }
+    for _, hold := range a.holds {
+        bookIDs = append(bookIDs, hold.BookID)
+    }
+    for _, bookID := range bookIDs {
+        if _, ok := a.bookTitles[bookID]; ok {
+            continue
+        }
+        if book, err := a.database.FindByID(bookID); err == nil {
+            a.bookTitles[bookID] = book.ToView().Title
+        }
+    }
+
+    if a.membersTable != nil {
+        a.membersTable.Refresh()
+    }
+    if a.loansTable != nil {
+        a.loansTable.Refresh()
+    }
+    if a.holdsTable != nil {
+        a.holdsTable.Refresh()
+    }
+}
+
+func (a *App) createMembersTab() fyne.CanvasObject {
+    selectedRow := -1
+
+    a.membersTable = widget.NewTable(
+        func() (int, int) {
+            return len(a.members) + 1, 4
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"ID", "Имя", "Контакты", "На руках"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(a.members) {
+                return
+            }
+            member := a.members[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", member.ID))
+            case 1:
+                label.SetText(member.Name + overdueBadge(a.overdueMembers[member.ID]))
+            case 2:
+                label.SetText(member.Contact)
+            case 3:
+                label.SetText(fmt.Sprintf("%d", a.database.ActiveLoansOfMember(member.ID)))
+            }
+        },
+    )
+    a.membersTable.OnSelected = func(id widget.TableCellID) {
+        selectedRow = id.Row - 1
+    }
+    a.membersTable.SetColumnWidth(0, 60)
+    a.membersTable.SetColumnWidth(1, 250)
+    a.membersTable.SetColumnWidth(2, 250)
+    a.membersTable.SetColumnWidth(3, 100)
+
+    selectedMember := func() (database.MemberView, bool) {
+        if selectedRow < 0 || selectedRow >= len(a.members) {
+            dialog.ShowInformation("Ошибка", "Выберите читателя в таблице", a.window)
+            return database.MemberView{}, false
+        }
+        return a.members[selectedRow], true
+    }
+
+    addButton := widget.NewButton("➕ Читатель", func() {
+        a.showMemberForm(database.MemberView{})
+    })
+    editButton := widget.NewButton("✏️ Изменить", func() {
+        if member, ok := selectedMember(); ok {
+            a.showMemberForm(member)
+        }
+    })
+    deleteButton := widget.NewButton("🗑️ Удалить", func() {
+        member, ok := selectedMember()
+        if !ok {
+            return
+        }
+        dialog.ShowConfirm("Удаление читателя",
+            fmt.Sprintf("Удалить читателя '%s'?", member.Name),
+            func(confirmed bool) {
+                if !confirmed {
+                    return
+                }
+                if err := a.database.DeleteMember(member.ID); err != nil {
+                    dialog.ShowError(err, a.window)
+                    return
+                }
+                selectedRow = -1
+                a.membersTable.UnselectAll()
+                a.refreshCirculation()
+            }, a.window)
+    })
+
+    return container.NewBorder(
+        container.NewHBox(addButton, editButton, deleteButton),
+        nil, nil, nil,
+        a.membersTable,
+    )
+}
+
+// ID 0 - новый читатель
+func (a *App) showMemberForm(member database.MemberView) {
+    nameEntry := widget.NewEntry()
+    nameEntry.SetText(member.Name)
+    contactEntry := widget.NewEntry()
+    contactEntry.SetText(member.Contact)
+    contactEntry.SetPlaceHolder("Телефон или e-mail")
+
+    title := "Новый читатель"
+    if member.ID != 0 {
+        title = fmt.Sprintf("Читатель ID %d", member.ID)
+    }
+
+    form := &widget.Form{
+        Items: []*widget.FormItem{
+            {Text: "Имя", Widget: nameEntry},
+            {Text: "Контакты", Widget: contactEntry},
+        },
+        OnSubmit: func() {
+            updated := database.MemberView{
+                ID:      member.ID,
+                Name:    nameEntry.Text,
+                Contact: contactEntry.Text,
+            }
+
+            var err error
+            if member.ID == 0 {
+                _, err = a.database.AddMember(updated)
+            } else {
+                err = a.database.UpdateMember(updated)
+            }
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            a.refreshCirculation()
+        },
+    }
+
+    customDialog := dialog.NewCustomConfirm(title, "Сохранить", "Отмена",
+        form,
+        func(save bool) {
+            if save {
+                form.OnSubmit()
+            }
+        }, a.window)
+
+    customDialog.Resize(fyne.NewSize(500, 250))
+    customDialog.Show()
+}
+
+func (a *App) createLoansTab() fyne.CanvasObject {
+    selectedRow := -1
+
+    a.loansTable = widget.NewTable(
+        func() (int, int) {
+            return len(a.loans) + 1, 7
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"ID", "Книга", "Читатель", "Выдана", "Срок", "Возвращена", "Просрочка"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(a.loans) {
+                return
+            }
+            loan := a.loans[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", loan.ID))
+            case 1:
+                label.SetText(fmt.Sprintf("%d — %s", loan.BookID, a.bookTitles[loan.BookID]))
+            case 2:
+                label.SetText(fmt.Sprintf("%d — %s", loan.MemberID, a.memberNames[loan.MemberID]))
+            case 3:
+                label.SetText(formatDate(loan.Issued))
+            case 4:
+                label.SetText(formatDate(loan.Due))
+            case 5:
+                label.SetText(formatDate(loan.Returned))
+            case 6:
+                if days := database.OverdueDays(loan, time.Now()); days > 0 {
+                    fine := a.database.LoanFine(loan, time.Now())
+                    label.SetText(fmt.Sprintf("⏰ %d дн., %s руб.", days, database.FormatMoney(fine)))
+                } else {
+                    label.SetText("")
+                }
+            }
+        },
+    )
+    a.loansTable.OnSelected = func(id widget.TableCellID) {
+        selectedRow = id.Row - 1
+    }
+    a.loansTable.SetColumnWidth(0, 60)
+    a.loansTable.SetColumnWidth(1, 280)
+    a.loansTable.SetColumnWidth(2, 200)
+    a.loansTable.SetColumnWidth(3, 100)
+    a.loansTable.SetColumnWidth(4, 100)
+    a.loansTable.SetColumnWidth(5, 100)
+    a.loansTable.SetColumnWidth(6, 160)
+
+    activeCheck := widget.NewCheck("Только невозвращенные", func(activeOnly bool) {
+        a.activeLoansOnly = activeOnly
+        selectedRow = -1
+        a.loansTable.UnselectAll()
+        a.refreshCirculation()
+    })
+    activeCheck.SetChecked(true)
+
+    checkoutButton := widget.NewButton("📕 Выдать", a.showCheckoutDialog)
+    overdueButton := widget.NewButton("⏰ Просрочки", a.showOverdueDialog)
+    returnButton := widget.NewButton("📗 Вернуть", func() {
+        if selectedRow < 0 || selectedRow >= len(a.loans) {
+            dialog.ShowInformation("Ошибка", "Выберите выдачу в таблице", a.window)
+            return
+        }
+        if err := a.database.ReturnLoan(a.loans[selectedRow].ID); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        selectedRow = -1
+        a.loansTable.UnselectAll()
+        a.refreshCirculation()
+    })
+
+    return container.NewBorder(
+        container.NewHBox(checkoutButton, returnButton, overdueButton, widget.NewSeparator(), activeCheck),
+        nil, nil, nil,
+        a.loansTable,
+    )
+}
+
+func (a *App) showCheckoutDialog() {
+    if len(a.members) == 0 {
+        dialog.ShowInformation("Ошибка", "Сначала добавьте читателя", a.window)
+        return
+    }
+
+    bookEntry := widget.NewEntry()
+    bookEntry.SetPlaceHolder("ID книги")
+
+    bookInfo := widget.NewLabel("")
+    bookInfo.Wrapping = fyne.TextWrapWord
+    bookEntry.OnChanged = func(text string) {
+        id, err := strconv.Atoi(text)
+        if err != nil {
+            bookInfo.SetText("")
+            return
+        }
+        book, err := a.database.FindByID(int32(id))
+        if err != nil {
+            bookInfo.SetText("❌ Книга не найдена")
+            return
+        }
+        available, _ := a.database.AvailableCopies(int32(id))
+        bookInfo.SetText(fmt.Sprintf("📖 %s\nДоступно экземпляров: %d, броней в очереди: %d",
+            book.ToView().Title, available, a.database.ActiveHoldsOfBook(int32(id))))
+    }
+
+    memberOptions := make([]string, len(a.members))
+    for i, member := range a.members {
+        memberOptions[i] = fmt.Sprintf("%d — %s", member.ID, member.Name)
+    }
+    memberSelect := widget.NewSelect(memberOptions, nil)
+
+    daysEntry := widget.NewEntry()
+    daysEntry.SetText("14")
+
+    form := &widget.Form{
+        Items: []*widget.FormItem{
+            {Text: "Книга", Widget: bookEntry},
+            {Text: "", Widget: bookInfo},
+            {Text: "Читатель", Widget: memberSelect},
+            {Text: "Срок, дней", Widget: daysEntry},
+        },
+        OnSubmit: func() {
+            bookID, err := strconv.Atoi(bookEntry.Text)
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ID книги должен быть числом"), a.window)
+                return
+            }
+            if memberSelect.Selected == "" {
+                dialog.ShowError(fmt.Errorf("выберите читателя"), a.window)
+                return
+            }
+            memberID, _ := strconv.Atoi(strings.SplitN(memberSelect.Selected, " ", 2)[0])
+            days, err := strconv.Atoi(daysEntry.Text)
+            if err != nil || days <= 0 {
+                dialog.ShowError(fmt.Errorf("срок должен быть положительным числом дней"), a.window)
+                return
+            }
+
+            due := time.Now().AddDate(0, 0, days)
+            if _, err := a.database.Checkout(int32(bookID), int32(memberID), due); err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            a.refreshCirculation()
+        },
+    }
+
+    customDialog := dialog.NewCustomConfirm("Выдача книги", "Выдать", "Отмена",
+        form,
+        func(ok bool) {
+            if ok {
+                form.OnSubmit()
+            }
+        }, a.window)
+
+    customDialog.Resize(fyne.NewSize(500, 350))
+    customDialog.Show()
+}
\ No newline at end of file
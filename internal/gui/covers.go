@@ -0,0 +1,146 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "image"
+    "io"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/canvas"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/storage"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Размер миниатюры в таблице: строка с обложкой чуть выше обычной
+const (
+    coverThumbnailWidth  = 30
+    coverThumbnailHeight = 40
+)
+
+// Миниатюра вместе с хэшем картинки, из которой она сделана
+type coverThumbnail struct {
+    hash  string
+    image image.Image
+}
+
+// Миниатюры уменьшаются один раз и живут в кэше, пока не сменится хэш обложки.
+// Уменьшаем с запасом в 2 раза для экранов с высокой плотностью пикселей
+func (a *App) coverThumbnail(id int32) image.Image {
+    hash := a.database.CoverHash(id)
+    if cached, ok := a.coverCache[id]; ok && cached.hash == hash {
+        return cached.image
+    }
+
+    var thumbnail image.Image
+    if hash != "" {
+        img, err := a.database.CoverThumbnail(id, 2*coverThumbnailWidth, 2*coverThumbnailHeight)
+        if err != nil {
+            fmt.Printf("Ошибка загрузки обложки: %v\n", err)
+        } else {
+            thumbnail = img
+        }
+    }
+
+    a.coverCache[id] = coverThumbnail{hash: hash, image: thumbnail}
+    return thumbnail
+}
+
+// O(n) Выбрасывает из кэша миниатюры, чья обложка сменилась или удалена
+// (в том числе после смены ID или очистки базы); остальные переживают refreshTable
+func (a *App) pruneCoverCache() {
+    for id, cached := range a.coverCache {
+        if a.database.CoverHash(id) != cached.hash {
+            delete(a.coverCache, id)
+        }
+    }
+}
+
+// Полноразмерная обложка для окна просмотра, в кэш не попадает
+func (a *App) coverResource(id int32) fyne.Resource {
+    if !a.database.HasCover(id) {
+        return nil
+    }
+    data, err := a.database.GetCover(id)
+    if err != nil {
+        fmt.Printf("Ошибка загрузки обложки: %v\n", err)
+        return nil
+    }
+    return fyne.NewStaticResource(fmt.Sprintf("cover_%d", id), data)
+}
+
+func (a *App) showCoverDialog(book database.BookView) {
+    preview := canvas.NewImageFromResource(nil)
+    preview.FillMode = canvas.ImageFillContain
+    preview.SetMinSize(fyne.NewSize(300, 400))
+
+    noCoverLabel := widget.NewLabel("Обложка не задана")
+
+    updatePreview := func() {
+        resource := a.coverResource(book.ID)
+        preview.Resource = resource
+        preview.Refresh()
+        if resource == nil {
+            noCoverLabel.Show()
+        } else {
+            noCoverLabel.Hide()
+        }
+    }
+    updatePreview()
+
+    onChanged := func() {
+        updatePreview()
+        if a.table != nil {
+            a.table.Refresh()
+        }
+    }
+
+    loadButton := widget.NewButton("Загрузить...", func() {
+        fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            if reader == nil {
+                return
+            }
+            defer reader.Close()
+
+            data, err := io.ReadAll(reader)
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), a.window)
+                return
+            }
+
+            if err := a.database.SetCover(book.ID, data); err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            onChanged()
+        }, a.window)
+
+        fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".png", ".jpg", ".jpeg"}))
+        fileDialog.Show()
+    })
+
+    removeButton := widget.NewButton("Удалить обложку", func() {
+        if err := a.database.DeleteCover(book.ID); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        onChanged()
+    })
+
+    content := container.NewBorder(
+        widget.NewLabel(fmt.Sprintf("%s — %s", book.Title, book.Author)),
+        container.NewHBox(loadButton, removeButton),
+        nil, nil,
+        container.NewStack(container.NewCenter(noCoverLabel), preview),
+    )
+
+    coverDialog := dialog.NewCustom(fmt.Sprintf("Обложка книги ID %d", book.ID), "Закрыть", content, a.window)
+    coverDialog.Resize(fyne.NewSize(450, 550))
+    coverDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,109 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Разделители CSV в порядке пунктов списка
+var csvDelimiters = []struct {
+    Name  string
+    Value rune
+}{
+    {"Запятая (,)", ','},
+    {"Точка с запятой (;)", ';'},
+    {"Табуляция", '\t'},
+    {"Вертикальная черта (|)", '|'},
+}
+
+// Список разделителей для формы
+func newDelimiterSelect() *widget.Select {
+    names := make([]string, len(csvDelimiters))
+    for i, delimiter := range csvDelimiters {
+        names[i] = delimiter.Name
+    }
+    delimiterSelect := widget.NewSelect(names, nil)
+    delimiterSelect.SetSelectedIndex(0)
+    return delimiterSelect
+}
+
+func (a *App) showExportCSVDialog() {
+    delimiterSelect := newDelimiterSelect()
+
+    encodings := []string{"UTF-8 с BOM (для Excel)", "UTF-8", "Windows-1251"}
+    encodingSelect := widget.NewSelect(encodings, nil)
+    encodingSelect.SetSelectedIndex(0)
+
+    form := widget.NewForm(
+        widget.NewFormItem("Разделитель", delimiterSelect),
+        widget.NewFormItem("Кодировка", encodingSelect),
+    )
+
+    optionsDialog := dialog.NewCustomConfirm("Экспорт в CSV", "Выбрать файл", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        opts := database.CSVOptions{Delimiter: csvDelimiters[delimiterSelect.SelectedIndex()].Value}
+        switch encodingSelect.SelectedIndex() {
+        case 0:
+            opts.Encoding, opts.BOM = database.CSVUTF8, true
+        case 1:
+            opts.Encoding = database.CSVUTF8
+        case 2:
+            opts.Encoding = database.CSVWindows1251
+        }
+
+        a.saveReport("books_export.csv", ".csv", func(path string) error {
+            return a.database.ExportToCSV(path, opts)
+        })
+    }, a.window)
+    optionsDialog.Resize(fyne.NewSize(450, 200))
+    optionsDialog.Show()
+}
+
+func (a *App) showImportCSVDialog() {
+    a.chooseImportFile([]string{".csv", ".txt"}, func(path string) {
+        delimiterSelect := newDelimiterSelect()
+
+        encodings := []string{"Определить автоматически", "UTF-8", "Windows-1251"}
+        encodingSelect := widget.NewSelect(encodings, nil)
+        encodingSelect.SetSelectedIndex(0)
+
+        conflictSelect := newConflictSelect()
+        lenientCheck := newLenientCheck()
+
+        info := widget.NewLabel(fmt.Sprintf("Файл: %s\n\nНовые книги будут добавлены, для книг с существующим ID выберите действие", path))
+        info.Wrapping = fyne.TextWrapWord
+
+        form := widget.NewForm(
+            widget.NewFormItem("", info),
+            widget.NewFormItem("Разделитель", delimiterSelect),
+            widget.NewFormItem("Кодировка", encodingSelect),
+            widget.NewFormItem("ID уже есть", conflictSelect),
+            widget.NewFormItem("", lenientCheck),
+        )
+
+        optionsDialog := dialog.NewCustomConfirm("Импорт из CSV", "Импортировать", "Отмена", form, func(ok bool) {
+            if !ok {
+                return
+            }
+            opts := database.CSVOptions{
+                Delimiter: csvDelimiters[delimiterSelect.SelectedIndex()].Value,
+                Encoding:      database.CSVEncoding(encodingSelect.SelectedIndex()),
+                ImportOptions: database.ImportOptions{
+                    OnConflict: selectedConflictPolicy(conflictSelect),
+                    Lenient:    lenientCheck.Checked,
+                },
+            }
+            a.runImport("импорт CSV", func() (database.ImportSummary, error) {
+                return a.database.ImportFromCSV(path, opts)
+            })
+        }, a.window)
+        optionsDialog.Resize(fyne.NewSize(560, 360))
+        optionsDialog.Show()
+    })
+}
\ No newline at end of file
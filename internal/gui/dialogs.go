@@ -15,24 +15,51 @@ import (
 
 func (a *App) showAddDialog() {
     idEntry := widget.NewEntry()
+    idEntry.SetPlaceHolder(fmt.Sprintf("Следующий: %d", a.database.NextID()))
+    idEntry.Disable()
+
+    autoIDCheck := widget.NewCheck("Назначить автоматически", func(auto bool) {
+        if auto {
+            idEntry.SetText("")
+            idEntry.Disable()
+        } else {
+            idEntry.Enable()
+        }
+    })
+    autoIDCheck.SetChecked(true)
+
     titleEntry := widget.NewEntry()
     authorEntry := widget.NewEntry()
     yearEntry := widget.NewEntry()
     copiesEntry := widget.NewEntry()
+    isbnEntry := widget.NewEntry()
+    isbnEntry.SetPlaceHolder("Необязательно, ISBN-10 или ISBN-13")
+    tagEditor, getTags := a.newTagEditor(nil)
 
     form := &widget.Form{
         Items: []*widget.FormItem{
-            {Text: "ID", Widget: idEntry},
+            {Text: "ID", Widget: container.NewBorder(nil, nil, nil, autoIDCheck, idEntry)},
             {Text: "Название", Widget: titleEntry},
             {Text: "Автор", Widget: authorEntry},
             {Text: "Год издания", Widget: yearEntry},
             {Text: "Тираж", Widget: copiesEntry},
+            {Text: "ISBN", Widget: isbnEntry},
+            {Text: "Теги", Widget: tagEditor},
         },
         OnSubmit: func() {
-            id, err := strconv.Atoi(idEntry.Text)
-            if err != nil {
-                dialog.ShowError(err, a.window)
-                return
+            // 0 - база сама выдаст следующий ID
+            id := 0
+            if !autoIDCheck.Checked {
+                var err error
+                id, err = strconv.Atoi(idEntry.Text)
+                if err != nil {
+                    dialog.ShowError(err, a.window)
+                    return
+                }
+                if id <= 0 {
+                    dialog.ShowError(fmt.Errorf("ID должен быть положительным числом"), a.window)
+                    return
+                }
             }
 
             year, err := strconv.Atoi(yearEntry.Text)
@@ -47,6 +74,10 @@ func (a *App) showAddDialog() {
                 return
             }
 
+            if !a.checkISBN(isbnEntry.Text) {
+                return
+            }
+
             book := database.BookView{
                 ID:     int32(id),
                 Title:  titleEntry.Text,
@@ -55,10 +86,13 @@ func (a *App) showAddDialog() {
                 Copies: int32(copies),
             }
 
-            if err := a.database.AddBook(book); err != nil {
+            if newID, err := a.database.AddBook(book); err != nil {
                 dialog.ShowError(err, a.window)
             } else {
-                dialog.ShowInformation("Успех", "Книга добавлена", a.window)
+                a.saveTags(newID, getTags())
+                a.saveISBN(newID, isbnEntry.Text)
+                a.record(&addCommand{id: newID})
+                dialog.ShowInformation("Успех", fmt.Sprintf("Книга добавлена, ID: %d", newID), a.window)
                 a.refreshTable()
             }
         },
@@ -139,6 +173,10 @@ func (a *App) showEditDialog() {
 }
 
 func (a *App) showEditForm(book database.BookView) {
+    idEntry := widget.NewEntry()
+    idEntry.SetText(fmt.Sprintf("%d", book.ID))
+    idEntry.SetPlaceHolder("Введите новый ID")
+
     titleEntry := widget.NewEntry()
     titleEntry.SetText(book.Title)
     titleEntry.SetPlaceHolder("Введите название книги")
@@ -155,6 +193,14 @@ func (a *App) showEditForm(book database.BookView) {
     copiesEntry.SetText(fmt.Sprintf("%d", book.Copies))
     copiesEntry.SetPlaceHolder("Введите тираж")
 
+    isbnBefore := a.database.GetISBN(book.ID)
+    isbnEntry := widget.NewEntry()
+    isbnEntry.SetText(isbnBefore)
+    isbnEntry.SetPlaceHolder("Пусто - без ISBN")
+
+    tagsBefore := a.database.GetTags(book.ID)
+    tagEditor, getTags := a.newTagEditor(tagsBefore)
+
     clearTitle := func() {
         titleEntry.SetText("")
     }
@@ -180,8 +226,21 @@ func (a *App) showEditForm(book database.BookView) {
     yearContainer := container.NewBorder(nil, nil, nil, 
         widget.NewButton("Очистить", clearYear), yearEntry)
 
+    clearCopiesButton := widget.NewButton("Очистить", clearCopies)
     copiesContainer := container.NewBorder(nil, nil, nil, 
-        widget.NewButton("Очистить", clearCopies), copiesEntry)
+        clearCopiesButton, copiesEntry)
+
+    // тираж книги с экземплярами считается по ним и вручную не меняется
+    itemsPanel := a.newItemsPanel(book.ID, func(copies int32) {
+        if a.database.HasItems(book.ID) {
+            copiesEntry.SetText(fmt.Sprintf("%d", copies))
+            copiesEntry.Disable()
+            clearCopiesButton.Disable()
+        } else {
+            copiesEntry.Enable()
+            clearCopiesButton.Enable()
+        }
+    })
 
     infoText := fmt.Sprintf("Редактирование книги ID: %d\n\nОставьте поле пустым, чтобы сохранить текущее значение\nНажмите 'Очистить', чтобы стереть поле", book.ID)
     infoLabel := widget.NewLabel(infoText)
@@ -190,16 +249,29 @@ func (a *App) showEditForm(book database.BookView) {
     form := &widget.Form{
         Items: []*widget.FormItem{
             {Text: "Информация", Widget: infoLabel},
+            {Text: "ID", Widget: idEntry},
             {Text: "Название книги", Widget: titleContainer},
             {Text: "Автор", Widget: authorContainer},
             {Text: "Год издания", Widget: yearContainer},
             {Text: "Тираж", Widget: copiesContainer},
+            {Text: "ISBN", Widget: isbnEntry},
+            {Text: "Теги", Widget: tagEditor},
+            {Text: "Экземпляры", Widget: itemsPanel},
         },
         OnSubmit: func() {
             updatedBook := database.BookView{
                 ID: book.ID,
             }
 
+            if idEntry.Text != "" {
+                newID, err := strconv.Atoi(idEntry.Text)
+                if err != nil {
+                    dialog.ShowError(fmt.Errorf("ID должен быть числом"), a.window)
+                    return
+                }
+                updatedBook.ID = int32(newID)
+            }
+
             if titleEntry.Text == "" {
                 updatedBook.Title = book.Title
             } else {
@@ -234,27 +306,43 @@ func (a *App) showEditForm(book database.BookView) {
                 updatedBook.Copies = int32(copies)
             }
 
-            if updatedBook.Title == "" {
-                dialog.ShowError(fmt.Errorf("название книги не может быть пустым"), a.window)
+            if !a.checkISBN(isbnEntry.Text) {
                 return
             }
 
-            if updatedBook.Author == "" {
-                dialog.ShowError(fmt.Errorf("автор не может быть пустым"), a.window)
-                return
-            }
-
-            if err := a.database.UpdateBook(updatedBook); err != nil {
+            // смена ID и полей - одна операция: либо все, либо ничего
+            if err := a.database.ReplaceBook(book.ID, updatedBook); err != nil {
                 dialog.ShowError(err, a.window)
             } else {
+                a.saveTags(updatedBook.ID, getTags())
+                a.saveISBN(updatedBook.ID, isbnEntry.Text)
+                // в базе книга могла нормализоваться (автор, тираж по экземплярам)
+                if saved, err := a.database.FindByID(updatedBook.ID); err == nil {
+                    a.record(&editCommand{
+                        before:     book,
+                        after:      saved.ToView(),
+                        beforeTags: tagsBefore,
+                        afterTags:  a.database.GetTags(updatedBook.ID),
+                        beforeISBN: isbnBefore,
+                        afterISBN:  a.database.GetISBN(updatedBook.ID),
+                    })
+                }
                 dialog.ShowInformation("Успех", "Книга успешно обновлена", a.window)
                 a.refreshTable()
             }
         },
     }
 
-    content := container.NewVBox(form)
-    customDialog := dialog.NewCustomConfirm("Редактирование книги", "Сохранить", "Отмена", 
+    var customDialog dialog.Dialog
+    historyPanel := a.newHistoryPanel(book.ID, func() {
+        customDialog.Hide()
+    })
+
+    content := container.NewAppTabs(
+        container.NewTabItem("Книга", container.NewVScroll(form)),
+        container.NewTabItem("История", historyPanel),
+    )
+    customDialog = dialog.NewCustomConfirm("Редактирование книги", "Сохранить", "Отмена", 
         content,
         func(save bool) {
             if save {
@@ -262,7 +350,7 @@ func (a *App) showEditForm(book database.BookView) {
             }
         }, a.window)
     
-    customDialog.Resize(fyne.NewSize(600, 500))
+    customDialog.Resize(fyne.NewSize(700, 650))
     customDialog.Show()
 }
 
@@ -283,7 +371,8 @@ func (a *App) showDeleteDialog() {
             if err := a.database.DeleteBook(int32(id)); err != nil {
                 dialog.ShowError(err, a.window)
             } else {
-                dialog.ShowInformation("Успех", "Книга удалена", a.window)
+                a.record(&deleteCommand{id: int32(id)})
+                dialog.ShowInformation("Успех", "Книга перемещена в корзину", a.window)
                 a.refreshTable()
             }
         },
@@ -303,12 +392,19 @@ func (a *App) showDeleteDialog() {
 
 func (a *App) showClearDatabaseDialog() {
     confirmDialog := dialog.NewConfirm("Очистка базы данных", 
-        "ВНИМАНИЕ! Вы собираетесь полностью очистить базу данных.\nВсе книги будут удалены без возможности восстановления.\n\nПродолжить?",
+        "ВНИМАНИЕ! Вы собираетесь полностью очистить базу данных.\nВсе книги (включая корзину), теги, обложки и экземпляры будут удалены.\nЧитатели, история выдач, штрафы, брони и псевдонимы авторов сохранятся;\nпока есть невозвращенные книги или активные брони, очистка невозможна.\nВсе стертое можно вернуть кнопкой \"Отменить\".\n\nПродолжить?",
         func(confirmed bool) {
             if confirmed {
+                snapshots, err := takeAllSnapshots(a.database)
+                if err != nil {
+                    dialog.ShowError(err, a.window)
+                    return
+                }
+
                 if err := a.database.ClearDatabase(); err != nil {
                     dialog.ShowError(fmt.Errorf("ошибка очистки БД: %v", err), a.window)
                 } else {
+                    a.record(&clearCommand{snapshots: snapshots})
                     dialog.ShowInformation("Успех", "База данных полностью очищена", a.window)
                     a.refreshTable()
                 }
@@ -405,6 +501,33 @@ func (a *App) showSearchDialog() {
     }
 
     searchButton := widget.NewButton("Найти", performSearch)
+    labelsButton := widget.NewButton("🖨️ Этикетки", func() {
+        if len(searchResults) == 0 {
+            dialog.ShowInformation("Ошибка", "Сначала найдите книги", a.window)
+            return
+        }
+        a.showLabelsDialog([]bookSource{
+            {Name: fmt.Sprintf("Результаты поиска (%d)", len(searchResults)), Books: searchResults},
+        })
+    })
+    bibliographyButton := widget.NewButton("📚 Библиография", func() {
+        if len(searchResults) == 0 {
+            dialog.ShowInformation("Ошибка", "Сначала найдите книги", a.window)
+            return
+        }
+        a.showBibliographyDialog([]bookSource{
+            {Name: fmt.Sprintf("Результаты поиска (%d)", len(searchResults)), Books: searchResults},
+        })
+    })
+    excelButton := widget.NewButton("📊 Excel", func() {
+        if len(searchResults) == 0 {
+            dialog.ShowInformation("Ошибка", "Сначала найдите книги", a.window)
+            return
+        }
+        a.showExcelExportDialog([]bookSource{
+            {Name: fmt.Sprintf("Результаты поиска (%d)", len(searchResults)), Books: searchResults},
+        })
+    })
     clearButton := widget.NewButton("Очистить", func() {
         searchValueEntry.SetText("")
         searchResults = []database.BookView{}
@@ -429,6 +552,9 @@ func (a *App) showSearchDialog() {
         container.NewHBox(
             searchButton,
             clearButton,
+            labelsButton,
+            bibliographyButton,
+            excelButton,
         ),
         widget.NewSeparator(),
         resultsLabel,
@@ -484,35 +610,11 @@ func (a *App) showExportDialog() {
 }
 
 func (a *App) showImportDialog() {
-    fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
-        if err != nil {
-            dialog.ShowError(err, a.window)
-            return
-        }
-        if reader == nil {
-            return
-        }
-        defer reader.Close()
-
-        confirmDialog := dialog.NewConfirm("Импорт данных", 
-            "Внимание! При импорте:\n- Новые книги будут добавлены\n- Существующие книги с одинаковым ID будут обновлены\n\nПродолжить?",
-            func(confirmed bool) {
-                if confirmed {
-                    count, err := a.database.ImportFromTxt(reader.URI().Path())
-                    if err != nil {
-                        dialog.ShowError(fmt.Errorf("ошибка импорта: %v", err), a.window)
-                    } else {
-                        dialog.ShowInformation("Успех", 
-                            fmt.Sprintf("Импорт завершен!\nДобавлено/обновлено книг: %d", count), a.window)
-                        a.refreshTable()
-                    }
-                }
-            }, a.window)
-        confirmDialog.Show()
-    }, a.window)
-
-    fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".txt"}))
-    fileDialog.Show()
+    a.chooseImportFile([]string{".txt"}, func(path string) {
+        a.showImportPreview("TXT", func(opts database.ImportOptions) (*database.ImportPlan, error) {
+            return a.database.PlanImport(path, opts)
+        })
+    })
 }
 
 func (a *App) showStatsDialog() {
@@ -533,58 +635,79 @@ func (a *App) showStatsDialog() {
     dialog.ShowInformation("Статистика", statsText, a.window)
 }
 
+func (a *App) showCompactDialog() {
+    confirmDialog := dialog.NewConfirm("Сжатие базы данных",
+        "Свободные слоты удаленных записей будут убраны из файлов,\nа обложки без ссылок на них - удалены.\n\nПродолжить?",
+        func(confirmed bool) {
+            if confirmed {
+                stats, err := a.database.Compact()
+                if err != nil {
+                    dialog.ShowError(fmt.Errorf("ошибка сжатия БД: %v", err), a.window)
+                } else {
+                    dialog.ShowInformation("Успех",
+                        fmt.Sprintf("Сжатие завершено!\nОсвобождено слотов: %d\nУдалено обложек: %d",
+                            stats.ReclaimedSlots, stats.RemovedCovers), a.window)
+                    a.refreshTable()
+                }
+            }
+        }, a.window)
+    confirmDialog.Show()
+}
+
+// Экспорт в Excel из главного меню: вся база или книги в таблице
 func (a *App) showExportExcelDialog() {
-    fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
-        if err != nil {
-            dialog.ShowError(err, a.window)
-            return
-        }
-        if writer == nil {
-            return
-        }
-        defer writer.Close()
+    books, err := a.database.GetAllBooks()
+    if err != nil {
+        dialog.ShowError(err, a.window)
+        return
+    }
+    sources := []bookSource{
+        {Name: fmt.Sprintf("Вся база (%d)", len(books)), Books: books},
+    }
+    if len(a.tagFilter) > 0 {
+        sources = append(sources, bookSource{Name: fmt.Sprintf("Книги в таблице (%d)", len(a.books)), Books: a.books})
+    }
+    a.showExcelExportDialog(sources)
+}
 
-        if err := a.database.ExportToExcel(writer.URI().Path()); err != nil {
-            dialog.ShowError(fmt.Errorf("ошибка экспорта: %v", err), a.window)
-        } else {
-            dialog.ShowInformation("Успех", "Данные успешно экспортированы в Excel", a.window)
-        }
-    }, a.window)
+func (a *App) showExcelExportDialog(sources []bookSource) {
+    sourceNames := make([]string, len(sources))
+    for i, source := range sources {
+        sourceNames[i] = source.Name
+    }
+    sourceSelect := widget.NewSelect(sourceNames, nil)
+    sourceSelect.SetSelectedIndex(0)
 
-    fileDialog.SetFileName("books_export.xlsx")
-    fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
-    fileDialog.Show()
-}
+    summaryCheck := widget.NewCheck("Лист «Сводка»: книги и тираж по авторам и по десятилетиям", nil)
+    summaryCheck.SetChecked(true)
 
-func (a *App) showImportExcelDialog() {
-    fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
-        if err != nil {
-            dialog.ShowError(err, a.window)
+    info := widget.NewLabel("Шапка листа «Книги» закреплена и с автофильтром, в колонках ID, года и тиража Excel не даст ввести неверное значение")
+    info.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("Книги", sourceSelect),
+        widget.NewFormItem("", summaryCheck),
+        widget.NewFormItem("", info),
+    )
+
+    optionsDialog := dialog.NewCustomConfirm("Экспорт в Excel", "Выбрать файл", "Отмена", form, func(ok bool) {
+        if !ok {
             return
         }
-        if reader == nil {
+        books := sources[sourceSelect.SelectedIndex()].Books
+        if len(books) == 0 {
+            dialog.ShowInformation("Ошибка", "Нет книг для экспорта", a.window)
             return
         }
-        defer reader.Close()
-
-        confirmDialog := dialog.NewConfirm("Импорт данных из Excel", 
-            "Внимание! При импорте:\n- Новые книги будут добавлены\n- Существующие книги с одинаковым ID будут обновлены\n\nПродолжить?",
-            func(confirmed bool) {
-                if confirmed {
-                    count, err := a.database.ImportFromExcel(reader.URI().Path())
-                    if err != nil {
-                        dialog.ShowError(fmt.Errorf("ошибка импорта: %v", err), a.window)
-                    } else {
-                        dialog.ShowInformation("Успех", 
-                            fmt.Sprintf("Импорт завершен!\nДобавлено/обновлено книг: %d", count), a.window)
-                        a.refreshTable()
-                    }
-                }
-            }, a.window)
-        confirmDialog.Show()
+        opts := database.ExcelExportOptions{Summary: summaryCheck.Checked}
+        a.saveReport("books_export.xlsx", ".xlsx", func(path string) error {
+            return a.database.ExportBooksToExcel(path, books, opts)
+        })
     }, a.window)
-
-    fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
-    fileDialog.Show()
+    optionsDialog.Resize(fyne.NewSize(500, 260))
+    optionsDialog.Show()
 }
 
+func (a *App) showImportExcelDialog() {
+    a.chooseImportFile([]string{".xlsx"}, a.showExcelMappingDialog)
+}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Пункт "столбца нет" в списках сопоставления
+const noExcelColumn = "— нет —"
+
+// Выбор листа и столбцов для импорта Excel. Столбцы заранее подобраны
+// по заголовкам, их можно поменять; дальше - обычный предпросмотр импорта
+func (a *App) showExcelMappingDialog(path string) {
+    layout, err := database.InspectExcel(path, "")
+    if err != nil {
+        dialog.ShowError(err, a.window)
+        return
+    }
+
+    columnSelects := make([]*widget.Select, len(database.BookFields))
+    for i := range columnSelects {
+        columnSelects[i] = widget.NewSelect(nil, nil)
+    }
+    // списки столбцов по заголовкам листа и выбор по найденному сопоставлению
+    fillColumns := func() {
+        options := []string{noExcelColumn}
+        for column, header := range layout.Headers {
+            options = append(options, fmt.Sprintf("%d: %s", column+1, strings.TrimSpace(header)))
+        }
+        for i, field := range database.BookFields {
+            columnSelects[i].Options = options
+            if column, ok := layout.Mapping[field]; ok {
+                columnSelects[i].SetSelectedIndex(column + 1)
+            } else {
+                columnSelects[i].SetSelectedIndex(0)
+            }
+        }
+    }
+    fillColumns()
+
+    sheetSelect := widget.NewSelect(layout.Sheets, nil)
+    sheetSelect.SetSelected(layout.Sheet)
+    sheetSelect.OnChanged = func(sheet string) {
+        inspected, err := database.InspectExcel(path, sheet)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        layout = inspected
+        fillColumns()
+    }
+
+    info := widget.NewLabel(fmt.Sprintf("Файл: %s\n\nСтолбцы подобраны по заголовкам первой строки. Без ID книги получат новые ID, без тиража тираж будет 0", path))
+    info.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("", info),
+        widget.NewFormItem("Лист", sheetSelect),
+    )
+    for i, field := range database.BookFields {
+        form.Append(field, columnSelects[i])
+    }
+
+    mappingDialog := dialog.NewCustomConfirm("Импорт из Excel", "Далее", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        mapping := make(database.ExcelMapping)
+        for i, field := range database.BookFields {
+            if index := columnSelects[i].SelectedIndex(); index > 0 {
+                mapping[field] = index - 1
+            }
+        }
+        if missing := mapping.Missing(); len(missing) > 0 {
+            dialog.ShowInformation("Импорт", "Выберите столбцы: "+strings.Join(missing, ", "), a.window)
+            return
+        }
+
+        sheet := layout.Sheet
+        a.showImportPreview("Excel", func(opts database.ImportOptions) (*database.ImportPlan, error) {
+            return a.database.PlanImportExcel(path, database.ExcelOptions{Sheet: sheet, Mapping: mapping, ImportOptions: opts})
+        })
+    }, a.window)
+    mappingDialog.Resize(fyne.NewSize(560, 460))
+    mappingDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,139 @@
+package gui
+
+import (
+    "fmt"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+const restoreTimeLayout = "02.01.2006 15:04"
+
+// Вкладка "История": все версии книги и откат к выбранной.
+// onRestored вызывается после успешного отката, чтобы закрыть форму со старыми значениями
+func (a *App) newHistoryPanel(bookID int32, onRestored func()) fyne.CanvasObject {
+    history, err := a.database.History(bookID)
+    if err != nil {
+        return widget.NewLabel(err.Error())
+    }
+    if len(history) == 0 {
+        return widget.NewLabel("В журнале нет записей об этой книге")
+    }
+
+    selected := -1
+    detailsLabel := widget.NewLabel("Выберите версию")
+    detailsLabel.Wrapping = fyne.TextWrapWord
+
+    // новые версии сверху
+    versions := widget.NewList(
+        func() int {
+            return len(history)
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.ListItemID, item fyne.CanvasObject) {
+            entry := history[len(history)-1-id]
+            item.(*widget.Label).SetText(fmt.Sprintf("%s  %s  (%s)",
+                entry.Time.Format(auditTimeLayout), entry.Op, entry.Actor))
+        },
+    )
+
+    restoreButton := widget.NewButton("↩️ Вернуть эту версию", func() {
+        if selected < 0 {
+            dialog.ShowInformation("История", "Выберите версию", a.window)
+            return
+        }
+        entry := history[selected]
+        message := fmt.Sprintf("Вернуть книгу ID %d к состоянию на %s?\n%s",
+            bookID, entry.Time.Format(auditTimeLayout), describeBook(entry.After))
+        if entry.After == nil {
+            message = fmt.Sprintf("На %s книги не было. Удалить книгу ID %d?",
+                entry.Time.Format(auditTimeLayout), bookID)
+        }
+        dialog.ShowConfirm("Откат", message, func(ok bool) {
+            if !ok {
+                return
+            }
+            err := a.recordBatch("откат книги", func() error {
+                return a.database.RestoreAt(bookID, entry.Time)
+            })
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            a.refreshTable()
+            onRestored()
+            dialog.ShowInformation("Успех", "Книга возвращена к выбранной версии", a.window)
+        }, a.window)
+    })
+    restoreButton.Disable()
+
+    versions.OnSelected = func(id widget.ListItemID) {
+        selected = len(history) - 1 - id
+        entry := history[selected]
+        detailsLabel.SetText(fmt.Sprintf("Изменения: %s\n\nВерсия: %s",
+            describeChange(entry), describeBook(entry.After)))
+        restoreButton.Enable()
+    }
+
+    return container.NewBorder(nil,
+        container.NewVBox(detailsLabel, restoreButton),
+        nil, nil, versions)
+}
+
+// Пустая строка - ошибка: откатывать базу "никуда" нельзя
+func parseRestoreTime(text string) (time.Time, error) {
+    text = strings.TrimSpace(text)
+    at, err := time.ParseInLocation(restoreTimeLayout, text, time.Local)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("время должно быть в формате ДД.ММ.ГГГГ ЧЧ:ММ: %s", text)
+    }
+    // минута указана целиком
+    return at.Add(time.Minute - time.Nanosecond), nil
+}
+
+// Откат всей базы к моменту времени
+func (a *App) showRestoreDatabaseDialog() {
+    timeEntry := widget.NewEntry()
+    timeEntry.SetPlaceHolder("ДД.ММ.ГГГГ ЧЧ:ММ")
+    timeEntry.SetText(time.Now().Format(restoreTimeLayout))
+
+    infoLabel := widget.NewLabel("Все книги из журнала вернутся к состоянию на указанную минуту.\n" +
+        "Книги, добавленные позже, будут удалены. Откат тоже записывается в журнал")
+    infoLabel.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("", infoLabel),
+        widget.NewFormItem("Момент времени", timeEntry),
+    )
+
+    restoreDialog := dialog.NewCustomConfirm("Откат базы", "Откатить", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        at, err := parseRestoreTime(timeEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        var changed int
+        err = a.recordBatch("откат базы", func() error {
+            var err error
+            changed, err = a.database.RestoreDatabaseAt(at)
+            return err
+        })
+        a.refreshTable()
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        dialog.ShowInformation("Успех", fmt.Sprintf("Изменено книг: %d", changed), a.window)
+    }, a.window)
+    restoreDialog.Resize(fyne.NewSize(500, 250))
+    restoreDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,203 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Снимаем просроченные брони и перечитываем список
+func (a *App) refreshHolds() {
+    if _, err := a.database.ExpireHolds(time.Now()); err != nil {
+        fmt.Printf("Ошибка снятия просроченных броней: %v\n", err)
+    }
+
+    holds, err := a.database.GetHolds(a.activeHoldsOnly)
+    if err != nil {
+        fmt.Printf("Ошибка загрузки броней: %v\n", err)
+        holds = []database.HoldView{}
+    }
+    a.holds = holds
+}
+
+func (a *App) createHoldsTab() fyne.CanvasObject {
+    selectedRow := -1
+
+    a.holdsTable = widget.NewTable(
+        func() (int, int) {
+            return len(a.holds) + 1, 7
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"ID", "Книга", "Читатель", "Поставлена", "Очередь", "Статус", "Ждет до"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(a.holds) {
+                return
+            }
+            hold := a.holds[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", hold.ID))
+            case 1:
+                label.SetText(fmt.Sprintf("%d — %s", hold.BookID, a.bookTitles[hold.BookID]))
+            case 2:
+                label.SetText(fmt.Sprintf("%d — %s", hold.MemberID, a.memberNames[hold.MemberID]))
+            case 3:
+                label.SetText(formatDate(hold.Placed))
+            case 4:
+                if position := a.database.HoldPosition(hold.ID); position > 0 {
+                    label.SetText(fmt.Sprintf("%d", position))
+                } else {
+                    label.SetText("—")
+                }
+            case 5:
+                if hold.Status == database.HoldReady {
+                    label.SetText("📬 " + hold.Status.String())
+                } else {
+                    label.SetText(hold.Status.String())
+                }
+            case 6:
+                label.SetText(formatDate(hold.ExpiresAt))
+            }
+        },
+    )
+    a.holdsTable.OnSelected = func(id widget.TableCellID) {
+        selectedRow = id.Row - 1
+    }
+    a.holdsTable.SetColumnWidth(0, 60)
+    a.holdsTable.SetColumnWidth(1, 280)
+    a.holdsTable.SetColumnWidth(2, 200)
+    a.holdsTable.SetColumnWidth(3, 100)
+    a.holdsTable.SetColumnWidth(4, 80)
+    a.holdsTable.SetColumnWidth(5, 160)
+    a.holdsTable.SetColumnWidth(6, 100)
+
+    activeCheck := widget.NewCheck("Только активные", func(activeOnly bool) {
+        a.activeHoldsOnly = activeOnly
+        selectedRow = -1
+        a.holdsTable.UnselectAll()
+        a.refreshCirculation()
+    })
+    activeCheck.SetChecked(true)
+
+    placeButton := widget.NewButton("📌 Забронировать", a.showHoldDialog)
+    cancelButton := widget.NewButton("❌ Снять бронь", func() {
+        if selectedRow < 0 || selectedRow >= len(a.holds) {
+            dialog.ShowInformation("Ошибка", "Выберите бронь в таблице", a.window)
+            return
+        }
+        if err := a.database.CancelHold(a.holds[selectedRow].ID); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        selectedRow = -1
+        a.holdsTable.UnselectAll()
+        a.refreshCirculation()
+    })
+
+    pickupEntry := widget.NewEntry()
+    pickupEntry.SetText(fmt.Sprintf("%d", a.database.HoldPickupDays()))
+    pickupEntry.OnSubmitted = func(text string) {
+        days, err := strconv.Atoi(text)
+        if err == nil {
+            err = a.database.SetHoldPickupDays(days)
+        } else {
+            err = fmt.Errorf("срок хранения должен быть числом дней")
+        }
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            pickupEntry.SetText(fmt.Sprintf("%d", a.database.HoldPickupDays()))
+        }
+    }
+
+    return container.NewBorder(
+        container.NewHBox(placeButton, cancelButton, widget.NewSeparator(), activeCheck,
+            widget.NewSeparator(), widget.NewLabel("Хранить готовую бронь, дней:"), pickupEntry),
+        nil, nil, nil,
+        a.holdsTable,
+    )
+}
+
+func (a *App) showHoldDialog() {
+    if len(a.members) == 0 {
+        dialog.ShowInformation("Ошибка", "Сначала добавьте читателя", a.window)
+        return
+    }
+
+    bookEntry := widget.NewEntry()
+    bookEntry.SetPlaceHolder("ID книги")
+
+    bookInfo := widget.NewLabel("")
+    bookInfo.Wrapping = fyne.TextWrapWord
+    bookEntry.OnChanged = func(text string) {
+        id, err := strconv.Atoi(text)
+        if err != nil {
+            bookInfo.SetText("")
+            return
+        }
+        book, err := a.database.FindByID(int32(id))
+        if err != nil {
+            bookInfo.SetText("❌ Книга не найдена")
+            return
+        }
+        available, _ := a.database.AvailableCopies(int32(id))
+        bookInfo.SetText(fmt.Sprintf("📖 %s\nДоступно экземпляров: %d, в очереди: %d",
+            book.ToView().Title, available, a.database.ActiveHoldsOfBook(int32(id))))
+    }
+
+    memberOptions := make([]string, len(a.members))
+    for i, member := range a.members {
+        memberOptions[i] = fmt.Sprintf("%d — %s", member.ID, member.Name)
+    }
+    memberSelect := widget.NewSelect(memberOptions, nil)
+
+    form := &widget.Form{
+        Items: []*widget.FormItem{
+            {Text: "Книга", Widget: bookEntry},
+            {Text: "", Widget: bookInfo},
+            {Text: "Читатель", Widget: memberSelect},
+        },
+        OnSubmit: func() {
+            bookID, err := strconv.Atoi(bookEntry.Text)
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ID книги должен быть числом"), a.window)
+                return
+            }
+            if memberSelect.Selected == "" {
+                dialog.ShowError(fmt.Errorf("выберите читателя"), a.window)
+                return
+            }
+            memberID, _ := strconv.Atoi(strings.SplitN(memberSelect.Selected, " ", 2)[0])
+
+            if _, err := a.database.PlaceHold(int32(bookID), int32(memberID)); err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            a.refreshCirculation()
+        },
+    }
+
+    customDialog := dialog.NewCustomConfirm("Бронирование книги", "Забронировать", "Отмена",
+        form,
+        func(ok bool) {
+            if ok {
+                form.OnSubmit()
+            }
+        }, a.window)
+
+    customDialog.Resize(fyne.NewSize(500, 300))
+    customDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,82 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Итоги мягкого импорта: что записано и таблица строк, которые не удалось
+// импортировать, а за ними замечания к записанным книгам.
+// Отчет можно сохранить в CSV, исправить и загрузить снова
+func (a *App) showImportErrors(summary database.ImportSummary) {
+    importErrors := append(append([]database.ImportError(nil), summary.Errors...), summary.Notes...)
+    totals := widget.NewLabel("Импорт завершен!\n" + summary.String())
+
+    headers := []string{"Строка", "Поле", "Причина", "Содержимое строки"}
+    table := widget.NewTable(
+        func() (int, int) {
+            return len(importErrors) + 1, len(headers)
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                label.SetText(headers[id.Col])
+                return
+            }
+            importErr := importErrors[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", importErr.Line))
+            case 1:
+                label.SetText(importErr.Column)
+            case 2:
+                label.SetText(strings.ReplaceAll(importErr.Reason, "\n", " "))
+            case 3:
+                label.SetText(importErr.Raw)
+            }
+        },
+    )
+    for col, width := range []float32{70, 120, 420, 350} {
+        table.SetColumnWidth(col, width)
+    }
+
+    details := widget.NewLabel("Нажмите на строку, чтобы увидеть ошибку целиком")
+    details.Wrapping = fyne.TextWrapWord
+    table.OnSelected = func(id widget.TableCellID) {
+        table.UnselectAll()
+        if id.Row == 0 {
+            return
+        }
+        importErr := importErrors[id.Row-1]
+        details.SetText(fmt.Sprintf("Строка %d: %s\n%s", importErr.Line, importErr.Raw, importErr.Reason))
+    }
+
+    saveButton := widget.NewButton("💾 Сохранить отчет", func() {
+        a.saveReport("import_errors.csv", ".csv", func(path string) error {
+            return database.WriteImportErrors(path, importErrors)
+        })
+    })
+
+    content := container.NewBorder(
+        totals,
+        container.NewVBox(details, saveButton),
+        nil, nil,
+        table,
+    )
+    title := fmt.Sprintf("Строки с ошибками: %d", len(summary.Errors))
+    if len(summary.Notes) > 0 {
+        title += fmt.Sprintf(", с замечаниями: %d", len(summary.Notes))
+    }
+    errorsDialog := dialog.NewCustom(title, "Закрыть", content, a.window)
+    errorsDialog.Resize(fyne.NewSize(1000, 600))
+    errorsDialog.Show()
+}
\ No newline at end of file
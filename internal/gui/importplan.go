@@ -0,0 +1,221 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Фильтры таблицы предпросмотра в порядке пунктов списка; 0 - все строки
+var planFilters = []struct {
+    Name   string
+    Action database.PlanAction
+}{
+    {"Все строки", 0},
+    {"Новые", database.PlanNew},
+    {"Измененные", database.PlanChanged},
+    {"Без изменений", database.PlanUnchanged},
+    {"Пропущенные", database.PlanSkipped},
+    {"С ошибками", database.PlanInvalid},
+}
+
+// Значение поля для таблицы: у измененного поля - "было → стало"
+func planCell(row database.PlanRow, field, value string) string {
+    for _, diff := range row.Diffs {
+        if diff.Field == field {
+            return diff.Old + " → " + diff.New
+        }
+    }
+    return value
+}
+
+// Как построить план при выбранных настройках импорта
+type planFunc func(opts database.ImportOptions) (*database.ImportPlan, error)
+
+// Пробный импорт TXT или Excel: таблица того, что произойдет с каждой строкой
+// файла. Снятые строки не применяются, смена политики конфликтов пересчитывает план
+func (a *App) showImportPreview(title string, planImport planFunc) {
+    plan, err := planImport(database.ImportOptions{})
+    if err != nil {
+        dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), a.window)
+        return
+    }
+    if len(plan.Rows) == 0 {
+        dialog.ShowInformation("Импорт", "В файле нет книг", a.window)
+        return
+    }
+
+    // индексы строк плана, показанных в таблице
+    var visible []int
+    filterAction := database.PlanAction(0)
+    applyFilter := func() {
+        visible = visible[:0]
+        for i, row := range plan.Rows {
+            if filterAction == 0 || row.Action == filterAction {
+                visible = append(visible, i)
+            }
+        }
+    }
+    applyFilter()
+
+    summary := widget.NewLabel("")
+    updateSummary := func() {
+        summary.SetText(fmt.Sprintf("Новых: %d, изменено: %d, без изменений: %d, пропуск: %d, с ошибками: %d. Будет применено: %d",
+            plan.Count(database.PlanNew), plan.Count(database.PlanChanged), plan.Count(database.PlanUnchanged),
+            plan.Count(database.PlanSkipped), plan.Count(database.PlanInvalid), plan.SelectedCount()))
+    }
+    updateSummary()
+
+    headers := []string{"", "Строка", "Действие", "ID", "Название", "Автор", "Год", "Тираж", "Примечание"}
+    table := widget.NewTable(
+        func() (int, int) {
+            return len(visible) + 1, len(headers)
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                label.SetText(headers[id.Col])
+                return
+            }
+            row := plan.Rows[visible[id.Row-1]]
+            book := row.Book
+            switch id.Col {
+            case 0:
+                switch {
+                case row.Action != database.PlanNew && row.Action != database.PlanChanged:
+                    label.SetText("—")
+                case row.Selected:
+                    label.SetText("☑")
+                default:
+                    label.SetText("☐")
+                }
+            case 1:
+                label.SetText(fmt.Sprintf("%d", row.Line))
+            case 2:
+                if row.Renumber {
+                    label.SetText("Новая (новый ID)")
+                } else {
+                    label.SetText(row.Action.String())
+                }
+            case 3:
+                label.SetText(fmt.Sprintf("%d", book.ID))
+            case 4:
+                label.SetText(planCell(row, "Название", book.Title))
+            case 5:
+                label.SetText(planCell(row, "Автор", book.Author))
+            case 6:
+                label.SetText(planCell(row, "Год издания", fmt.Sprintf("%d", book.Year)))
+            case 7:
+                label.SetText(planCell(row, "Тираж", fmt.Sprintf("%d", book.Copies)))
+            case 8:
+                label.SetText(strings.ReplaceAll(row.Reason, "\n", " "))
+            }
+        },
+    )
+    for col, width := range []float32{40, 70, 120, 60, 260, 200, 110, 100, 350} {
+        table.SetColumnWidth(col, width)
+    }
+
+    details := widget.NewLabel("Нажмите на строку, чтобы включить или исключить ее из импорта")
+    details.Wrapping = fyne.TextWrapWord
+    table.OnSelected = func(id widget.TableCellID) {
+        table.UnselectAll()
+        if id.Row == 0 {
+            return
+        }
+        row := &plan.Rows[visible[id.Row-1]]
+        if row.Action == database.PlanInvalid {
+            details.SetText(fmt.Sprintf("Строка %d: %s\n%s", row.Line, row.Raw, row.Reason))
+            return
+        }
+        if row.Action == database.PlanUnchanged {
+            details.SetText(fmt.Sprintf("Строка %d: книга с ID %d уже в базе без изменений", row.Line, row.Book.ID))
+            return
+        }
+        if row.Action == database.PlanSkipped {
+            details.SetText(fmt.Sprintf("Строка %d: книга с ID %d уже в базе и будет пропущена", row.Line, row.Book.ID))
+            return
+        }
+        row.Selected = !row.Selected
+        details.SetText(fmt.Sprintf("Строка %d: %s", row.Line, row.Raw))
+        table.Refresh()
+        updateSummary()
+    }
+
+    setAll := func(selected bool) {
+        for _, i := range visible {
+            row := &plan.Rows[i]
+            if row.Action == database.PlanNew || row.Action == database.PlanChanged {
+                row.Selected = selected
+            }
+        }
+        table.Refresh()
+        updateSummary()
+    }
+
+    filterNames := make([]string, len(planFilters))
+    for i, filter := range planFilters {
+        filterNames[i] = filter.Name
+    }
+    var filterSelect *widget.Select
+    filterSelect = widget.NewSelect(filterNames, func(string) {
+        filterAction = planFilters[filterSelect.SelectedIndex()].Action
+        applyFilter()
+        table.Refresh()
+    })
+    filterSelect.SetSelectedIndex(0)
+
+    // новый план по той же строке файла; отметки строк сбрасываются
+    conflictSelect := newConflictSelect()
+    conflictSelect.OnChanged = func(string) {
+        replanned, err := planImport(database.ImportOptions{OnConflict: selectedConflictPolicy(conflictSelect)})
+        if err != nil {
+            dialog.ShowError(fmt.Errorf("ошибка чтения файла: %v", err), a.window)
+            return
+        }
+        plan = replanned
+        applyFilter()
+        table.Refresh()
+        updateSummary()
+    }
+
+    toolbar := container.NewHBox(
+        widget.NewLabel("Показать:"),
+        filterSelect,
+        widget.NewLabel("ID уже есть:"),
+        conflictSelect,
+        widget.NewButton("Выбрать все", func() { setAll(true) }),
+        widget.NewButton("Снять все", func() { setAll(false) }),
+    )
+
+    content := container.NewBorder(
+        container.NewVBox(summary, toolbar),
+        details,
+        nil, nil,
+        table,
+    )
+
+    previewDialog := dialog.NewCustomConfirm("Предпросмотр импорта: "+title, "Применить выбранное", "Отмена", content, func(ok bool) {
+        if !ok {
+            return
+        }
+        if plan.SelectedCount() == 0 {
+            dialog.ShowInformation("Импорт", "Не выбрано ни одной строки", a.window)
+            return
+        }
+
+        a.runImport("импорт "+title, func() (database.ImportSummary, error) {
+            return a.database.ApplyImportPlan(plan)
+        })
+    }, a.window)
+    previewDialog.Resize(fyne.NewSize(1200, 650))
+    previewDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,26 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "strings"
+
+    "fyne.io/fyne/v2/dialog"
+)
+
+// O(1) пустое поле - ISBN не указан; неверный ISBN показывается до записи книги
+func (a *App) checkISBN(isbn string) bool {
+    if strings.TrimSpace(isbn) == "" {
+        return true
+    }
+    if _, err := database.NormalizeISBN(isbn); err != nil {
+        dialog.ShowError(err, a.window)
+        return false
+    }
+    return true
+}
+
+func (a *App) saveISBN(id int32, isbn string) {
+    if err := a.database.SetISBN(id, isbn); err != nil {
+        dialog.ShowError(err, a.window)
+    }
+}
\ No newline at end of file
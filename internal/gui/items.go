@@ -0,0 +1,249 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "image/color"
+    "strconv"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/canvas"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Список экземпляров книги с кнопками управления. Изменения сразу пишутся в БД,
+// onChange получает новый тираж, посчитанный по экземплярам
+func (a *App) newItemsPanel(bookID int32, onChange func(copies int32)) fyne.CanvasObject {
+    var items []database.ItemView
+    selected := -1
+
+    list := widget.NewList(
+        func() int {
+            return len(items)
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.ListItemID, object fyne.CanvasObject) {
+            item := items[id]
+            shelf := item.Shelf
+            if shelf == "" {
+                shelf = "—"
+            }
+            object.(*widget.Label).SetText(fmt.Sprintf("%s  |  полка %s  |  %s  |  %s",
+                item.Barcode, shelf, item.Condition, item.Status))
+        },
+    )
+    list.OnSelected = func(id widget.ListItemID) {
+        selected = id
+    }
+
+    reload := func() {
+        loaded, err := a.database.GetItems(bookID)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        items = loaded
+        selected = -1
+        list.UnselectAll()
+        list.Refresh()
+
+        if book, err := a.database.FindByID(bookID); err == nil {
+            onChange(book.Copies)
+        }
+    }
+    reload()
+
+    // тираж в основной таблице тоже поменялся
+    changed := func() {
+        reload()
+        a.refreshTable()
+    }
+
+    addButton := widget.NewButton("➕ Экземпляр", func() {
+        a.showItemForm(database.ItemView{BookID: bookID}, true, changed)
+    })
+    editButton := widget.NewButton("✏️", func() {
+        if selected < 0 || selected >= len(items) {
+            dialog.ShowInformation("Ошибка", "Выберите экземпляр в списке", a.window)
+            return
+        }
+        a.showItemForm(items[selected], false, changed)
+    })
+    deleteButton := widget.NewButton("🗑️", func() {
+        if selected < 0 || selected >= len(items) {
+            dialog.ShowInformation("Ошибка", "Выберите экземпляр в списке", a.window)
+            return
+        }
+        if err := a.database.DeleteItem(items[selected].Barcode); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        changed()
+    })
+
+    listSize := canvas.NewRectangle(color.Transparent)
+    listSize.SetMinSize(fyne.NewSize(0, 120))
+
+    return container.NewBorder(
+        nil,
+        container.NewHBox(addButton, editButton, deleteButton),
+        nil, nil,
+        container.NewStack(listSize, list),
+    )
+}
+
+// isNew - новый экземпляр, пустой штрихкод будет сгенерирован
+func (a *App) showItemForm(item database.ItemView, isNew bool, onSaved func()) {
+    barcodeEntry := widget.NewEntry()
+    barcodeEntry.SetText(item.Barcode)
+    if isNew {
+        barcodeEntry.SetPlaceHolder("Пусто - сгенерировать")
+    } else {
+        barcodeEntry.Disable()
+    }
+
+    // несколько экземпляров сразу - так книгу с выданными экземплярами
+    // переводят на учет экземпляров, штрихкоды тогда генерируются
+    countEntry := widget.NewEntry()
+    countEntry.SetText("1")
+
+    shelfEntry := widget.NewEntry()
+    shelfEntry.SetText(item.Shelf)
+    shelfEntry.SetPlaceHolder("Например, A-3-12")
+
+    conditionOptions := make([]string, len(database.AllItemConditions))
+    for i, condition := range database.AllItemConditions {
+        conditionOptions[i] = condition.String()
+    }
+    conditionSelect := widget.NewSelect(conditionOptions, nil)
+
+    statusOptions := make([]string, len(database.AllItemStatuses))
+    for i, status := range database.AllItemStatuses {
+        statusOptions[i] = status.String()
+    }
+    statusSelect := widget.NewSelect(statusOptions, nil)
+
+    if isNew {
+        conditionSelect.SetSelectedIndex(0)
+        statusSelect.SetSelectedIndex(0)
+    } else {
+        conditionSelect.SetSelected(item.Condition.String())
+        statusSelect.SetSelected(item.Status.String())
+    }
+
+    formItems := []*widget.FormItem{
+        {Text: "Штрихкод", Widget: barcodeEntry},
+    }
+    if isNew {
+        formItems = append(formItems, &widget.FormItem{Text: "Количество", Widget: countEntry})
+    }
+    formItems = append(formItems,
+        &widget.FormItem{Text: "Полка", Widget: shelfEntry},
+        &widget.FormItem{Text: "Состояние", Widget: conditionSelect},
+        &widget.FormItem{Text: "Статус", Widget: statusSelect},
+    )
+
+    form := &widget.Form{
+        Items: formItems,
+        OnSubmit: func() {
+            updated := database.ItemView{
+                Barcode:   barcodeEntry.Text,
+                BookID:    item.BookID,
+                Shelf:     shelfEntry.Text,
+                Condition: database.AllItemConditions[conditionSelect.SelectedIndex()],
+                Status:    database.AllItemStatuses[statusSelect.SelectedIndex()],
+            }
+
+            var err error
+            if isNew {
+                count, convErr := strconv.Atoi(strings.TrimSpace(countEntry.Text))
+                if convErr != nil || count < 1 || count > 1000 {
+                    dialog.ShowInformation("Ошибка", "Количество - число от 1 до 1000", a.window)
+                    return
+                }
+                if count > 1 && strings.TrimSpace(updated.Barcode) != "" {
+                    dialog.ShowInformation("Ошибка", "Для нескольких экземпляров штрихкоды генерируются - оставьте поле пустым", a.window)
+                    return
+                }
+                added := make([]database.ItemView, count)
+                for i := range added {
+                    added[i] = updated
+                }
+                _, err = a.database.AddItems(added)
+            } else {
+                err = a.database.UpdateItem(updated)
+            }
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            onSaved()
+        },
+    }
+
+    title := "Новый экземпляр"
+    if !isNew {
+        title = "Экземпляр " + item.Barcode
+    }
+
+    customDialog := dialog.NewCustomConfirm(title, "Сохранить", "Отмена",
+        form,
+        func(save bool) {
+            if save {
+                form.OnSubmit()
+            }
+        }, a.window)
+
+    customDialog.Resize(fyne.NewSize(450, 300))
+    customDialog.Show()
+}
+
+// Поиск книги по штрихкоду экземпляра, например со сканера
+func (a *App) showBarcodeDialog() {
+    barcodeEntry := widget.NewEntry()
+    barcodeEntry.SetPlaceHolder("Отсканируйте или введите штрихкод")
+
+    resultLabel := widget.NewLabel("")
+    resultLabel.Wrapping = fyne.TextWrapWord
+
+    var found database.BookView
+    editButton := widget.NewButton("✏️ Редактировать книгу", func() {
+        a.showEditForm(found)
+    })
+    editButton.Disable()
+
+    lookup := func(barcode string) {
+        item, book, err := a.database.FindByBarcode(barcode)
+        if err != nil {
+            resultLabel.SetText("❌ " + err.Error())
+            editButton.Disable()
+            return
+        }
+        found = book
+        available, _ := a.database.AvailableCopies(book.ID)
+        resultLabel.SetText(fmt.Sprintf("📖 %s — %s (%d)\nID книги: %d, доступно экземпляров: %d\n\nПолка: %s\nСостояние: %s\nСтатус: %s",
+            book.Title, book.Author, book.Year, book.ID, available,
+            item.Shelf, item.Condition, item.Status))
+        editButton.Enable()
+    }
+    barcodeEntry.OnSubmitted = lookup
+
+    content := container.NewVBox(
+        container.NewBorder(nil, nil, nil,
+            widget.NewButton("🔍 Найти", func() {
+                lookup(barcodeEntry.Text)
+            }), barcodeEntry),
+        resultLabel,
+        editButton,
+    )
+
+    barcodeDialog := dialog.NewCustom("Поиск по штрихкоду", "Закрыть", content, a.window)
+    barcodeDialog.Resize(fyne.NewSize(500, 320))
+    barcodeDialog.Show()
+    a.window.Canvas().Focus(barcodeEntry)
+}
\ No newline at end of file
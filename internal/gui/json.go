@@ -0,0 +1,79 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "path/filepath"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+func (a *App) showExportJSONDialog() {
+    formats := []string{"JSON (массив книг)", "JSON Lines (книга на строку)"}
+    formatSelect := widget.NewSelect(formats, nil)
+    formatSelect.SetSelectedIndex(0)
+
+    form := widget.NewForm(widget.NewFormItem("Формат", formatSelect))
+
+    optionsDialog := dialog.NewCustomConfirm("Экспорт в JSON", "Выбрать файл", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        if formatSelect.SelectedIndex() == 1 {
+            a.saveReport("books_export.jsonl", ".jsonl", a.database.ExportToJSONL)
+            return
+        }
+        a.saveReport("books_export.json", ".json", a.database.ExportToJSON)
+    }, a.window)
+    optionsDialog.Resize(fyne.NewSize(420, 160))
+    optionsDialog.Show()
+}
+
+func (a *App) showImportJSONDialog() {
+    a.chooseImportFile([]string{".json", ".jsonl", ".ndjson"}, func(path string) {
+        extension := strings.ToLower(filepath.Ext(path))
+        lines := extension == ".jsonl" || extension == ".ndjson"
+
+        strictCheck := widget.NewCheck("Строгий режим: незнакомые поля - ошибка", nil)
+        conflictSelect := newConflictSelect()
+        lenientCheck := newLenientCheck()
+
+        format := "JSON (массив книг)"
+        if lines {
+            format = "JSON Lines (книга на строку)"
+        }
+        info := widget.NewLabel(fmt.Sprintf("Файл: %s\nФормат: %s\n\nНовые книги будут добавлены, для книг с существующим ID выберите действие", path, format))
+        info.Wrapping = fyne.TextWrapWord
+
+        form := widget.NewForm(
+            widget.NewFormItem("", info),
+            widget.NewFormItem("", strictCheck),
+            widget.NewFormItem("ID уже есть", conflictSelect),
+            widget.NewFormItem("", lenientCheck),
+        )
+
+        optionsDialog := dialog.NewCustomConfirm("Импорт из JSON", "Импортировать", "Отмена", form, func(ok bool) {
+            if !ok {
+                return
+            }
+            opts := database.JSONOptions{
+                Strict: strictCheck.Checked,
+                ImportOptions: database.ImportOptions{
+                    OnConflict: selectedConflictPolicy(conflictSelect),
+                    Lenient:    lenientCheck.Checked,
+                },
+            }
+            a.runImport("импорт JSON", func() (database.ImportSummary, error) {
+                if lines {
+                    return a.database.ImportFromJSONL(path, opts)
+                }
+                return a.database.ImportFromJSON(path, opts)
+            })
+        }, a.window)
+        optionsDialog.Resize(fyne.NewSize(560, 340))
+        optionsDialog.Show()
+    })
+}
\ No newline at end of file
@@ -0,0 +1,166 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "github.com/nydeg/bd/internal/labels"
+    "fmt"
+    "strconv"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Набор книг для печати этикеток или выгрузки библиографии
+type bookSource struct {
+    Name  string
+    Books []database.BookView
+}
+
+// Этикетки из основной таблицы: все показанные книги или последняя выбранная
+func (a *App) showTableLabelsDialog() {
+    sources := []bookSource{
+        {Name: fmt.Sprintf("Книги в таблице (%d)", len(a.books)), Books: a.books},
+    }
+    if book, err := a.database.FindByID(a.selectedBookID); err == nil {
+        view := book.ToView()
+        sources = append([]bookSource{{Name: "Выбранная книга: " + view.Title, Books: []database.BookView{view}}}, sources...)
+    }
+    a.showLabelsDialog(sources)
+}
+
+func (a *App) showLabelsDialog(sources []bookSource) {
+    sourceNames := make([]string, len(sources))
+    for i, source := range sources {
+        sourceNames[i] = source.Name
+    }
+    sourceSelect := widget.NewSelect(sourceNames, nil)
+    sourceSelect.SetSelectedIndex(0)
+
+    perItemCheck := widget.NewCheck("По этикетке на каждый экземпляр", nil)
+    perItemCheck.SetChecked(true)
+
+    symbologies := []string{"Авто (EAN-13 для ISBN)", "Code 128", "EAN-13"}
+    symbologySelect := widget.NewSelect(symbologies, nil)
+    symbologySelect.SetSelectedIndex(0)
+
+    qrCheck := widget.NewCheck("QR-код с ID книги", nil)
+    qrCheck.SetChecked(true)
+    textCheck := widget.NewCheck("Название и автор", nil)
+    textCheck.SetChecked(true)
+
+    defaults := labels.DefaultSheet()
+    columnsEntry := widget.NewEntry()
+    columnsEntry.SetText(fmt.Sprintf("%d", defaults.Columns))
+    rowsEntry := widget.NewEntry()
+    rowsEntry.SetText(fmt.Sprintf("%d", defaults.Rows))
+    marginEntry := widget.NewEntry()
+    marginEntry.SetText(fmt.Sprintf("%g", defaults.MarginMM))
+    gapEntry := widget.NewEntry()
+    gapEntry.SetText(fmt.Sprintf("%g", defaults.GapMM))
+
+    hint := widget.NewLabel("Без экземпляров на этикетке ISBN книги (EAN-13), а если его нет - ID книги. " +
+        "При выборе EAN-13 экземпляры со штрихкодом не EAN получают ISBN книги")
+    hint.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("Книги", sourceSelect),
+        widget.NewFormItem("", perItemCheck),
+        widget.NewFormItem("Штрихкод", symbologySelect),
+        widget.NewFormItem("", hint),
+        widget.NewFormItem("", qrCheck),
+        widget.NewFormItem("", textCheck),
+        widget.NewFormItem("Столбцов на листе A4", columnsEntry),
+        widget.NewFormItem("Строк на листе A4", rowsEntry),
+        widget.NewFormItem("Поля, мм", marginEntry),
+        widget.NewFormItem("Зазор, мм", gapEntry),
+    )
+
+    // собираем этикетки и сетку из формы
+    collect := func() ([]labels.Label, labels.Sheet, error) {
+        sheet := labels.DefaultSheet()
+        var err error
+        if sheet.Columns, err = strconv.Atoi(columnsEntry.Text); err != nil {
+            return nil, sheet, fmt.Errorf("число столбцов должно быть целым")
+        }
+        if sheet.Rows, err = strconv.Atoi(rowsEntry.Text); err != nil {
+            return nil, sheet, fmt.Errorf("число строк должно быть целым")
+        }
+        if sheet.MarginMM, err = strconv.ParseFloat(marginEntry.Text, 64); err != nil {
+            return nil, sheet, fmt.Errorf("поля должны быть числом")
+        }
+        if sheet.GapMM, err = strconv.ParseFloat(gapEntry.Text, 64); err != nil {
+            return nil, sheet, fmt.Errorf("зазор должен быть числом")
+        }
+        sheet.Options.Symbology = labels.Symbology(symbologySelect.SelectedIndex())
+        sheet.Options.ShowQR = qrCheck.Checked
+        sheet.Options.ShowText = textCheck.Checked
+
+        books := sources[sourceSelect.SelectedIndex()].Books
+        var result []labels.Label
+        for _, book := range books {
+            var codes []string
+            if perItemCheck.Checked {
+                items, err := a.database.GetItems(book.ID)
+                if err != nil {
+                    return nil, sheet, err
+                }
+                for _, item := range items {
+                    codes = append(codes, item.Barcode)
+                }
+            }
+            result = append(result, labels.FromBook(book, a.database.GetISBN(book.ID), codes...)...)
+        }
+        if len(result) == 0 {
+            return nil, sheet, fmt.Errorf("нет книг для печати этикеток")
+        }
+        return result, sheet, nil
+    }
+
+    pdfButton := widget.NewButton("📄 Сохранить PDF", func() {
+        printed, sheet, err := collect()
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        a.saveReport("labels.pdf", ".pdf", func(path string) error {
+            return labels.SavePDF(path, printed, sheet)
+        })
+    })
+
+    pngButton := widget.NewButton("🖼️ Сохранить PNG в папку", func() {
+        printed, sheet, err := collect()
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        opts, err := sheet.LabelOptions()
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+
+        dialog.ShowFolderOpen(func(folder fyne.ListableURI, err error) {
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            if folder == nil {
+                return
+            }
+            paths, err := labels.SavePNG(folder.Path(), printed, opts)
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ошибка сохранения этикеток: %v", err), a.window)
+                return
+            }
+            dialog.ShowInformation("Успех", fmt.Sprintf("Сохранено этикеток: %d", len(paths)), a.window)
+        }, a.window)
+    })
+
+    content := container.NewBorder(nil, container.NewHBox(pdfButton, pngButton), nil, nil, form)
+
+    labelsDialog := dialog.NewCustom("Печать этикеток", "Закрыть", content, a.window)
+    labelsDialog.Resize(fyne.NewSize(550, 600))
+    labelsDialog.Show()
+}
\ No newline at end of file
@@ -0,0 +1,154 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Форматы MARC в порядке пунктов списка
+var marcFormats = []struct {
+    Name      string
+    Format    database.MARCFormat
+    Extension string
+}{
+    {"ISO 2709 (двоичный, .mrc)", database.MARCISO2709, ".mrc"},
+    {"MARCXML (.xml)", database.MARCXML, ".xml"},
+}
+
+func (a *App) showExportMARCDialog() {
+    names := make([]string, len(marcFormats))
+    for i, format := range marcFormats {
+        names[i] = format.Name
+    }
+    formatSelect := widget.NewSelect(names, nil)
+    formatSelect.SetSelectedIndex(0)
+
+    info := widget.NewLabel("Автор - 100, название - 245, год - 264, теги - 650, экземпляры - 852")
+    info.Wrapping = fyne.TextWrapWord
+
+    form := widget.NewForm(
+        widget.NewFormItem("Формат", formatSelect),
+        widget.NewFormItem("", info),
+    )
+
+    optionsDialog := dialog.NewCustomConfirm("Экспорт в MARC 21", "Выбрать файл", "Отмена", form, func(ok bool) {
+        if !ok {
+            return
+        }
+        format := marcFormats[formatSelect.SelectedIndex()]
+        a.saveReport("books_export"+format.Extension, format.Extension, func(path string) error {
+            return a.database.ExportToMARC(path, format.Format)
+        })
+    }, a.window)
+    optionsDialog.Resize(fyne.NewSize(480, 200))
+    optionsDialog.Show()
+}
+
+func (a *App) showImportMARCDialog() {
+    a.chooseImportFile([]string{".mrc", ".marc", ".iso", ".xml"}, func(path string) {
+        format := marcFormats[0]
+        if strings.ToLower(filepath.Ext(path)) == ".xml" {
+            format = marcFormats[1]
+        }
+
+        keepIDsCheck := widget.NewCheck("Брать ID книги из поля 001 (для файлов, выгруженных из этой базы)", nil)
+        conflictSelect := newConflictSelect()
+        info := widget.NewLabel(fmt.Sprintf("Файл: %s\nФормат: %s\n\nПоля, которые нельзя сохранить в книге (ISBN, соавторы и т.д.), попадут в отчет", path, format.Name))
+        info.Wrapping = fyne.TextWrapWord
+
+        form := widget.NewForm(
+            widget.NewFormItem("", info),
+            widget.NewFormItem("", keepIDsCheck),
+            widget.NewFormItem("ID уже есть", conflictSelect),
+        )
+
+        optionsDialog := dialog.NewCustomConfirm("Импорт из MARC 21", "Импортировать", "Отмена", form, func(ok bool) {
+            if !ok {
+                return
+            }
+            opts := database.MARCOptions{
+                Format:        format.Format,
+                KeepIDs:       keepIDsCheck.Checked,
+                ImportOptions: database.ImportOptions{OnConflict: selectedConflictPolicy(conflictSelect)},
+            }
+
+            var summary database.ImportSummary
+            var reports []database.MARCReport
+            err := a.recordBatch("импорт MARC", func() error {
+                var err error
+                summary, reports, err = a.database.ImportFromMARC(path, opts)
+                return err
+            })
+            a.refreshTable()
+            if err != nil {
+                dialog.ShowError(fmt.Errorf("ошибка импорта: %v", err), a.window)
+                return
+            }
+            a.showMARCReport(summary, reports)
+        }, a.window)
+        optionsDialog.Resize(fyne.NewSize(520, 280))
+        optionsDialog.Show()
+    })
+}
+
+// Текст отчета: по абзацу на запись с замечаниями
+func formatMARCReport(reports []database.MARCReport) string {
+    var b strings.Builder
+    for _, report := range reports {
+        fmt.Fprintf(&b, "Запись %d", report.Record)
+        if report.Title != "" {
+            fmt.Fprintf(&b, " «%s»", report.Title)
+        }
+        if report.Skipped {
+            b.WriteString(" - пропущена")
+        } else {
+            fmt.Fprintf(&b, " - книга ID %d", report.BookID)
+        }
+        b.WriteString("\n")
+        for _, note := range report.Notes {
+            fmt.Fprintf(&b, "  • %s\n", note)
+        }
+        b.WriteString("\n")
+    }
+    return b.String()
+}
+
+func (a *App) showMARCReport(summary database.ImportSummary, reports []database.MARCReport) {
+    if len(reports) == 0 {
+        dialog.ShowInformation("Успех",
+            "Импорт завершен!\n"+summary.String()+"\nВсе поля перенесены без потерь", a.window)
+        return
+    }
+
+    skipped := 0
+    for _, report := range reports {
+        if report.Skipped {
+            skipped++
+        }
+    }
+    text := formatMARCReport(reports)
+
+    totals := widget.NewLabel(fmt.Sprintf("%s\nПропущено записей: %d, записей с замечаниями: %d",
+        summary, skipped, len(reports)))
+    details := widget.NewLabel(text)
+    details.Wrapping = fyne.TextWrapWord
+
+    saveButton := widget.NewButton("💾 Сохранить отчет", func() {
+        a.saveReport("marc_report.txt", ".txt", func(path string) error {
+            return os.WriteFile(path, []byte(text), 0644)
+        })
+    })
+
+    content := container.NewBorder(totals, saveButton, nil, nil, container.NewVScroll(details))
+    reportDialog := dialog.NewCustom("Отчет об импорте MARC", "Закрыть", content, a.window)
+    reportDialog.Resize(fyne.NewSize(750, 550))
+    reportDialog.Show()
+}
\ No newline at end of file
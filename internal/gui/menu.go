@@ -0,0 +1,103 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/storage"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Главное меню: импорт и экспорт во всех форматах
+func (a *App) createMainMenu() *fyne.MainMenu {
+    importMenu := fyne.NewMenuItem("Импорт", nil)
+    importMenu.ChildMenu = fyne.NewMenu("",
+        fyne.NewMenuItem("TXT...", a.showImportDialog),
+        fyne.NewMenuItem("CSV...", a.showImportCSVDialog),
+        fyne.NewMenuItem("JSON / JSON Lines...", a.showImportJSONDialog),
+        fyne.NewMenuItem("MARC 21 (ISO 2709, MARCXML)...", a.showImportMARCDialog),
+        fyne.NewMenuItem("BibTeX / RIS...", a.showImportBibliographyDialog),
+        fyne.NewMenuItem("Excel...", a.showImportExcelDialog),
+    )
+
+    exportMenu := fyne.NewMenuItem("Экспорт", nil)
+    exportMenu.ChildMenu = fyne.NewMenu("",
+        fyne.NewMenuItem("TXT...", a.showExportDialog),
+        fyne.NewMenuItem("CSV...", a.showExportCSVDialog),
+        fyne.NewMenuItem("JSON / JSON Lines...", a.showExportJSONDialog),
+        fyne.NewMenuItem("MARC 21 (ISO 2709, MARCXML)...", a.showExportMARCDialog),
+        fyne.NewMenuItem("BibTeX / RIS...", a.showTableBibliographyDialog),
+        fyne.NewMenuItem("Excel...", a.showExportExcelDialog),
+    )
+
+    editMenu := fyne.NewMenu("Правка",
+        fyne.NewMenuItem("Отменить", a.undo),
+        fyne.NewMenuItem("Повторить", a.redo),
+    )
+
+    return fyne.NewMainMenu(
+        fyne.NewMenu("Файл", importMenu, exportMenu),
+        editMenu,
+    )
+}
+
+// Выбор файла для импорта с указанными расширениями
+func (a *App) chooseImportFile(extensions []string, onChosen func(path string)) {
+    fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        if reader == nil {
+            return
+        }
+        reader.Close()
+        onChosen(reader.URI().Path())
+    }, a.window)
+
+    fileDialog.SetFilter(storage.NewExtensionFileFilter(extensions))
+    fileDialog.Show()
+}
+
+// Импорт с записью в стек отмены и итоговым сообщением
+func (a *App) runImport(title string, importFn func() (database.ImportSummary, error)) {
+    var summary database.ImportSummary
+    err := a.recordBatch(title, func() error {
+        var err error
+        summary, err = importFn()
+        return err
+    })
+    a.refreshTable()
+    if err != nil {
+        dialog.ShowError(fmt.Errorf("ошибка импорта: %v\n\nДо ошибки:\n%s", err, summary), a.window)
+        return
+    }
+    if len(summary.Errors) > 0 || len(summary.Notes) > 0 {
+        a.showImportErrors(summary)
+        return
+    }
+    dialog.ShowInformation("Успех", "Импорт завершен!\n"+summary.String(), a.window)
+}
+
+// Список политик для книг, ID которых уже есть в базе; по умолчанию - перезапись
+func newConflictSelect() *widget.Select {
+    names := make([]string, len(database.AllConflictPolicies))
+    for i, policy := range database.AllConflictPolicies {
+        names[i] = policy.String()
+    }
+    conflictSelect := widget.NewSelect(names, nil)
+    conflictSelect.SetSelectedIndex(0)
+    return conflictSelect
+}
+
+// O(1) выбранная в списке политика
+func selectedConflictPolicy(conflictSelect *widget.Select) database.ConflictPolicy {
+    return database.AllConflictPolicies[conflictSelect.SelectedIndex()]
+}
+
+// Флажок мягкого режима: ошибочные строки попадают в отчет, а не прерывают импорт
+func newLenientCheck() *widget.Check {
+    return widget.NewCheck("Не прерываться на ошибках: импортировать верные строки, ошибки показать отчетом", nil)
+}
\ No newline at end of file
@@ -0,0 +1,221 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "math"
+    "strconv"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/storage"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Значок просрочки для строк книг и читателей
+func overdueBadge(count int) string {
+    if count == 0 {
+        return ""
+    }
+    return fmt.Sprintf("  ⏰ просрочено: %d", count)
+}
+
+func (a *App) refreshOverdue() {
+    books, members, err := a.database.OverdueCounts(time.Now())
+    if err != nil {
+        fmt.Printf("Ошибка расчета просрочек: %v\n", err)
+        books, members = map[int32]int{}, map[int32]int{}
+    }
+    a.overdueBooks = books
+    a.overdueMembers = members
+}
+
+func parseMoney(text string) (int64, error) {
+    value, err := strconv.ParseFloat(text, 64)
+    if err != nil || value < 0 {
+        return 0, fmt.Errorf("сумма должна быть неотрицательным числом")
+    }
+    return int64(math.Round(value * 100)), nil
+}
+
+func (a *App) showOverdueDialog() {
+    var items []database.OverdueItem
+
+    totalLabel := widget.NewLabel("")
+
+    selectedRow := -1
+
+    reportTable := widget.NewTable(
+        func() (int, int) {
+            return len(items) + 1, 7
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"Выдача", "Книга", "Читатель", "Срок", "Дней", "Штраф, руб.", "Книга возвращена"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(items) {
+                return
+            }
+            item := items[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", item.Loan.ID))
+            case 1:
+                label.SetText(fmt.Sprintf("%d — %s", item.Loan.BookID, item.BookTitle))
+            case 2:
+                label.SetText(fmt.Sprintf("%d — %s", item.Loan.MemberID, item.MemberName))
+            case 3:
+                label.SetText(formatDate(item.Loan.Due))
+            case 4:
+                label.SetText(fmt.Sprintf("%d", item.DaysOverdue))
+            case 5:
+                label.SetText(database.FormatMoney(item.Fine))
+            case 6:
+                label.SetText(formatDate(item.Loan.Returned))
+            }
+        },
+    )
+    reportTable.OnSelected = func(id widget.TableCellID) {
+        selectedRow = id.Row - 1
+    }
+    reportTable.SetColumnWidth(0, 70)
+    reportTable.SetColumnWidth(1, 250)
+    reportTable.SetColumnWidth(2, 180)
+    reportTable.SetColumnWidth(3, 100)
+    reportTable.SetColumnWidth(4, 60)
+    reportTable.SetColumnWidth(5, 100)
+    reportTable.SetColumnWidth(6, 130)
+
+    reload := func() {
+        report, err := a.database.OverdueReport(time.Now())
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        items = report
+        selectedRow = -1
+        reportTable.UnselectAll()
+
+        var total int64
+        for _, item := range items {
+            total += item.Fine
+        }
+        totalLabel.SetText(fmt.Sprintf("Выдач с просрочкой или неоплаченным штрафом: %d, штрафов на сумму: %s руб.", len(items), database.FormatMoney(total)))
+        reportTable.Refresh()
+    }
+    reload()
+
+    policy := a.database.FinePolicy()
+    dailyEntry := widget.NewEntry()
+    dailyEntry.SetText(database.FormatMoney(policy.DailyFine))
+    graceEntry := widget.NewEntry()
+    graceEntry.SetText(fmt.Sprintf("%d", policy.GraceDays))
+    maxEntry := widget.NewEntry()
+    maxEntry.SetText(database.FormatMoney(policy.MaxFine))
+
+    applyButton := widget.NewButton("Применить", func() {
+        daily, err := parseMoney(dailyEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        grace, err := strconv.Atoi(graceEntry.Text)
+        if err != nil {
+            dialog.ShowError(fmt.Errorf("льготный период должен быть числом дней"), a.window)
+            return
+        }
+        maxFine, err := parseMoney(maxEntry.Text)
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+
+        newPolicy := database.FinePolicy{DailyFine: daily, GraceDays: grace, MaxFine: maxFine}
+        if err := a.database.SetFinePolicy(newPolicy); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        reload()
+    })
+
+    policyForm := container.NewVBox(
+        widget.NewForm(
+            widget.NewFormItem("Штраф в день, руб.", dailyEntry),
+            widget.NewFormItem("Льготных дней", graceEntry),
+            widget.NewFormItem("Максимум, руб. (0 - нет)", maxEntry),
+        ),
+        applyButton,
+    )
+
+    exportTxtButton := widget.NewButton("📤 Экспорт TXT", func() {
+        a.saveReport("overdue.txt", ".txt", func(path string) error {
+            return a.database.ExportOverdueToTxt(path, time.Now())
+        })
+    })
+    exportExcelButton := widget.NewButton("📈 Экспорт Excel", func() {
+        a.saveReport("overdue.xlsx", ".xlsx", func(path string) error {
+            return a.database.ExportOverdueToExcel(path, time.Now())
+        })
+    })
+
+    // штраф по невозвращенной книге еще растет, оплатить можно только начисленный при возврате
+    payButton := widget.NewButton("💰 Штраф оплачен", func() {
+        if selectedRow < 0 || selectedRow >= len(items) {
+            dialog.ShowInformation("Штрафы", "Выберите выдачу в таблице", a.window)
+            return
+        }
+        item := items[selectedRow]
+        if item.Loan.Active() {
+            dialog.ShowInformation("Штрафы", "Книга еще не возвращена: штраф начисляется при возврате", a.window)
+            return
+        }
+        if err := a.database.PayFine(item.Loan.ID); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        reload()
+    })
+
+    content := container.NewBorder(
+        container.NewVBox(policyForm, totalLabel),
+        container.NewHBox(payButton, exportTxtButton, exportExcelButton),
+        nil, nil,
+        reportTable,
+    )
+
+    overdueDialog := dialog.NewCustom("Просроченные выдачи", "Закрыть", content, a.window)
+    overdueDialog.Resize(fyne.NewSize(900, 550))
+    overdueDialog.Show()
+}
+
+// Диалог сохранения файла для отчетов
+func (a *App) saveReport(fileName, extension string, export func(path string) error) {
+    fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        if writer == nil {
+            return
+        }
+        defer writer.Close()
+
+        if err := export(writer.URI().Path()); err != nil {
+            dialog.ShowError(fmt.Errorf("ошибка экспорта: %v", err), a.window)
+        } else {
+            dialog.ShowInformation("Успех", "Отчет успешно сохранен", a.window)
+        }
+    }, a.window)
+
+    fileDialog.SetFileName(fileName)
+    fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{extension}))
+    fileDialog.Show()
+}
\ No newline at end of file
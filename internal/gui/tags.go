@@ -0,0 +1,145 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "sort"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/theme"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Редактор тегов в виде "фишек": кнопка с крестиком удаляет тег,
+// поле ввода с Enter или кнопкой добавляет. Возвращает виджет и функцию,
+// которая отдает текущий набор тегов
+func (a *App) newTagEditor(initial []string) (fyne.CanvasObject, func() []string) {
+    tags := append([]string(nil), initial...)
+    chips := container.NewGridWrap(fyne.NewSize(140, 36))
+
+    var renderChips func()
+    renderChips = func() {
+        chips.Objects = nil
+        for _, tag := range tags {
+            chips.Add(widget.NewButtonWithIcon(tag, theme.CancelIcon(), func() {
+                for i, current := range tags {
+                    if current == tag {
+                        tags = append(tags[:i], tags[i+1:]...)
+                        break
+                    }
+                }
+                renderChips()
+            }))
+        }
+        chips.Refresh()
+    }
+    renderChips()
+
+    tagEntry := widget.NewEntry()
+    tagEntry.SetPlaceHolder("Новый тег")
+
+    addTag := func() {
+        tag := tagEntry.Text
+        tagEntry.SetText("")
+        if tag == "" {
+            return
+        }
+        for _, current := range tags {
+            if current == tag {
+                return
+            }
+        }
+        tags = append(tags, tag)
+        renderChips()
+    }
+    tagEntry.OnSubmitted = func(_ string) {
+        addTag()
+    }
+
+    editor := container.NewVBox(
+        chips,
+        container.NewBorder(nil, nil, nil, widget.NewButton("Добавить", addTag), tagEntry),
+    )
+
+    return editor, func() []string {
+        return append([]string(nil), tags...)
+    }
+}
+
+// Боковая панель с тегами: отмеченные теги фильтруют основную таблицу
+func (a *App) createTagSidebar() fyne.CanvasObject {
+    a.tagCheckGroup = widget.NewCheckGroup(nil, func(selected []string) {
+        a.tagFilter = selected
+        a.refreshTable()
+    })
+
+    modeRadio := widget.NewRadioGroup([]string{"Все отмеченные", "Любой из отмеченных"}, func(mode string) {
+        a.tagFilterAny = mode == "Любой из отмеченных"
+        if len(a.tagFilter) > 0 {
+            a.refreshTable()
+        }
+    })
+    modeRadio.SetSelected("Все отмеченные")
+    modeRadio.Required = true
+
+    resetButton := widget.NewButton("Сбросить", func() {
+        a.tagCheckGroup.SetSelected(nil)
+    })
+
+    sidebar := container.NewBorder(
+        container.NewVBox(widget.NewLabel("Теги:"), modeRadio, widget.NewSeparator()),
+        resetButton,
+        nil, nil,
+        container.NewVScroll(a.tagCheckGroup),
+    )
+
+    a.refreshTagSidebar()
+    return sidebar
+}
+
+// Обновляем список тегов, не трогая OnChanged, чтобы не уйти в цикл с refreshTable
+func (a *App) refreshTagSidebar() {
+    if a.tagCheckGroup == nil {
+        return
+    }
+
+    counts := a.database.GetAllTags()
+    options := make([]string, 0, len(counts))
+    for tag := range counts {
+        options = append(options, tag)
+    }
+    sort.Strings(options)
+
+    // отметки с исчезнувших тегов снимаем
+    var selected []string
+    for _, tag := range a.tagFilter {
+        if _, exists := counts[tag]; exists {
+            selected = append(selected, tag)
+        }
+    }
+    a.tagFilter = selected
+
+    a.tagCheckGroup.Options = options
+    a.tagCheckGroup.Selected = selected
+    a.tagCheckGroup.Refresh()
+}
+
+// Книги для основной таблицы с учетом фильтра по тегам
+func (a *App) loadBooks() ([]database.BookView, error) {
+    if len(a.tagFilter) == 0 {
+        return a.database.GetAllBooks()
+    }
+
+    query := database.TagQuery{All: a.tagFilter}
+    if a.tagFilterAny {
+        query = database.TagQuery{Any: a.tagFilter}
+    }
+    return a.database.FindByTags(query)
+}
+
+func (a *App) saveTags(id int32, tags []string) {
+    if err := a.database.SetTags(id, tags); err != nil {
+        dialog.ShowError(err, a.window)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,172 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "strconv"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/container"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/widget"
+)
+
+// Удаляем книги с истекшим сроком хранения и перечитываем корзину
+func (a *App) refreshTrash() {
+    if _, err := a.database.PurgeExpiredTrash(time.Now()); err != nil {
+        fmt.Printf("Ошибка очистки корзины: %v\n", err)
+    }
+
+    trash, err := a.database.GetTrash()
+    if err != nil {
+        fmt.Printf("Ошибка загрузки корзины: %v\n", err)
+        trash = []database.TrashEntry{}
+    }
+    a.trash = trash
+
+    if a.trashTable != nil {
+        a.trashTable.Refresh()
+    }
+}
+
+func (a *App) createTrashTab() fyne.CanvasObject {
+    selectedRow := -1
+
+    a.trashTable = widget.NewTable(
+        func() (int, int) {
+            return len(a.trash) + 1, 6
+        },
+        func() fyne.CanvasObject {
+            return widget.NewLabel("template")
+        },
+        func(id widget.TableCellID, cell fyne.CanvasObject) {
+            label := cell.(*widget.Label)
+            if id.Row == 0 {
+                headers := []string{"ID", "Название", "Автор", "Год", "Удалена", "Удалится"}
+                label.SetText(headers[id.Col])
+                return
+            }
+            if id.Row-1 >= len(a.trash) {
+                return
+            }
+            entry := a.trash[id.Row-1]
+            switch id.Col {
+            case 0:
+                label.SetText(fmt.Sprintf("%d", entry.Book.ID))
+            case 1:
+                label.SetText(entry.Book.Title)
+            case 2:
+                label.SetText(entry.Book.Author)
+            case 3:
+                label.SetText(fmt.Sprintf("%d", entry.Book.Year))
+            case 4:
+                label.SetText(formatDate(entry.DeletedAt))
+            case 5:
+                if entry.PurgeAt.IsZero() {
+                    label.SetText("не удалится")
+                } else {
+                    label.SetText(formatDate(entry.PurgeAt))
+                }
+            }
+        },
+    )
+    a.trashTable.OnSelected = func(id widget.TableCellID) {
+        selectedRow = id.Row - 1
+    }
+    a.trashTable.SetColumnWidth(0, 60)
+    a.trashTable.SetColumnWidth(1, 300)
+    a.trashTable.SetColumnWidth(2, 200)
+    a.trashTable.SetColumnWidth(3, 70)
+    a.trashTable.SetColumnWidth(4, 100)
+    a.trashTable.SetColumnWidth(5, 100)
+
+    // книга, выбранная в таблице
+    selected := func() (database.TrashEntry, bool) {
+        if selectedRow < 0 || selectedRow >= len(a.trash) {
+            dialog.ShowInformation("Ошибка", "Выберите книгу в таблице", a.window)
+            return database.TrashEntry{}, false
+        }
+        return a.trash[selectedRow], true
+    }
+    afterChange := func() {
+        selectedRow = -1
+        a.trashTable.UnselectAll()
+        a.refreshTable()
+    }
+
+    restoreButton := widget.NewButton("♻️ Восстановить", func() {
+        entry, ok := selected()
+        if !ok {
+            return
+        }
+        if err := a.database.RestoreFromTrash(entry.Book.ID); err != nil {
+            dialog.ShowError(err, a.window)
+            return
+        }
+        a.record(&untrashCommand{id: entry.Book.ID})
+        afterChange()
+    })
+
+    purgeButton := widget.NewButton("🔥 Удалить навсегда", func() {
+        entry, ok := selected()
+        if !ok {
+            return
+        }
+        message := fmt.Sprintf("Удалить книгу ID %d \"%s\" навсегда?\nТеги, обложка и экземпляры будут удалены, отменить это нельзя.",
+            entry.Book.ID, entry.Book.Title)
+        dialog.ShowConfirm("Удаление из корзины", message, func(ok bool) {
+            if !ok {
+                return
+            }
+            if err := a.database.PurgeBook(entry.Book.ID); err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            afterChange()
+        }, a.window)
+    })
+
+    emptyButton := widget.NewButton("🧹 Очистить корзину", func() {
+        if len(a.trash) == 0 {
+            return
+        }
+        message := fmt.Sprintf("Удалить навсегда все книги из корзины (%d)?\nОтменить это нельзя.", len(a.trash))
+        dialog.ShowConfirm("Очистка корзины", message, func(ok bool) {
+            if !ok {
+                return
+            }
+            count, err := a.database.EmptyTrash()
+            afterChange()
+            if err != nil {
+                dialog.ShowError(err, a.window)
+                return
+            }
+            dialog.ShowInformation("Успех", fmt.Sprintf("Удалено книг: %d", count), a.window)
+        }, a.window)
+    })
+
+    retentionEntry := widget.NewEntry()
+    retentionEntry.SetText(fmt.Sprintf("%d", a.database.TrashRetentionDays()))
+    retentionEntry.OnSubmitted = func(text string) {
+        days, err := strconv.Atoi(text)
+        if err == nil {
+            err = a.database.SetTrashRetentionDays(days)
+        } else {
+            err = fmt.Errorf("срок хранения должен быть числом дней")
+        }
+        if err != nil {
+            dialog.ShowError(err, a.window)
+            retentionEntry.SetText(fmt.Sprintf("%d", a.database.TrashRetentionDays()))
+            return
+        }
+        a.refreshTable()
+    }
+
+    return container.NewBorder(
+        container.NewHBox(restoreButton, purgeButton, emptyButton, widget.NewSeparator(),
+            widget.NewLabel("Хранить в корзине, дней (0 - без срока):"), retentionEntry),
+        nil, nil, nil,
+        a.trashTable,
+    )
+}
\ No newline at end of file
@@ -0,0 +1,445 @@
+package gui
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "time"
+
+    "fyne.io/fyne/v2"
+    "fyne.io/fyne/v2/dialog"
+    "fyne.io/fyne/v2/driver/desktop"
+)
+
+// Сколько последних операций можно отменить
+const undoLimit = 100
+
+// Операция, которую можно отменить и повторить обратными операциями базы
+type command interface {
+    name() string
+    undo(db *database.Database) error
+    redo(db *database.Database) error
+}
+
+// Стек отмены: done - выполненные, undone - отмененные (для повтора).
+// Новая операция очищает undone, как в любом редакторе
+type undoStack struct {
+    done   []command
+    undone []command
+}
+
+// O(1) амортизированно
+func (s *undoStack) push(c command) {
+    s.done = append(s.done, c)
+    if len(s.done) > undoLimit {
+        s.done = s.done[len(s.done)-undoLimit:]
+    }
+    s.undone = nil
+}
+
+// Книга со всем, что стирает очистка базы: тегами, обложкой и экземплярами.
+// DeletedAt - книга лежала в корзине и после восстановления уходит туда же
+// с прежней датой удаления, нулевое - книга была в фонде
+type bookSnapshot struct {
+    Book      database.BookView
+    Tags      []string
+    ISBN      string
+    Cover     []byte
+    Items     []database.ItemView
+    DeletedAt time.Time
+}
+
+// O(t + k) t - тегов, k - экземпляров книги
+func takeSnapshot(db *database.Database, id int32) (bookSnapshot, error) {
+    book, err := db.FindByID(id)
+    if err != nil {
+        return bookSnapshot{}, err
+    }
+    return snapshotOf(db, book.ToView())
+}
+
+// O(t + k) теги, обложка и экземпляры книги из корзины хранятся до окончательного удаления
+func snapshotOf(db *database.Database, book database.BookView) (bookSnapshot, error) {
+    snapshot := bookSnapshot{Book: book, Tags: db.GetTags(book.ID), ISBN: db.GetISBN(book.ID)}
+    var err error
+    if db.HasCover(book.ID) {
+        if snapshot.Cover, err = db.GetCover(book.ID); err != nil {
+            return bookSnapshot{}, err
+        }
+    }
+    if snapshot.Items, err = db.GetItems(book.ID); err != nil {
+        return bookSnapshot{}, err
+    }
+    return snapshot, nil
+}
+
+// O(n * (t + k)) снимки всех книг вместе с корзиной - все, что стирает ClearDatabase
+func takeAllSnapshots(db *database.Database) ([]bookSnapshot, error) {
+    books, err := db.GetAllBooks()
+    if err != nil {
+        return nil, err
+    }
+    trash, err := db.GetTrash()
+    if err != nil {
+        return nil, err
+    }
+
+    snapshots := make([]bookSnapshot, 0, len(books)+len(trash))
+    for _, book := range books {
+        snapshot, err := snapshotOf(db, book)
+        if err != nil {
+            return nil, err
+        }
+        snapshots = append(snapshots, snapshot)
+    }
+    for _, entry := range trash {
+        snapshot, err := snapshotOf(db, entry.Book)
+        if err != nil {
+            return nil, err
+        }
+        snapshot.DeletedAt = entry.DeletedAt
+        snapshots = append(snapshots, snapshot)
+    }
+    return snapshots, nil
+}
+
+// O(t + k) возвращаем книгу под тем же ID. При сбое уже возвращенное
+// убирается, и книги в базе нет, как до вызова
+func (s bookSnapshot) restore(db *database.Database) error {
+    if _, err := db.AddBook(s.Book); err != nil {
+        return err
+    }
+    if err := s.fill(db); err != nil {
+        if discardErr := s.discard(db); discardErr != nil {
+            return fmt.Errorf("%v (откат не удался: %v)", err, discardErr)
+        }
+        return err
+    }
+    return nil
+}
+
+// O(t + k) теги, ISBN, обложка и экземпляры только что добавленной книги
+func (s bookSnapshot) fill(db *database.Database) error {
+    if err := db.SetTags(s.Book.ID, s.Tags); err != nil {
+        return err
+    }
+    if err := db.SetISBN(s.Book.ID, s.ISBN); err != nil {
+        return err
+    }
+    if s.Cover != nil {
+        if err := db.SetCover(s.Book.ID, s.Cover); err != nil {
+            return err
+        }
+    }
+    if len(s.Items) > 0 {
+        if _, err := db.AddItems(s.Items); err != nil {
+            return err
+        }
+    }
+    if !s.DeletedAt.IsZero() {
+        return db.DeleteBookAt(s.Book.ID, s.DeletedAt)
+    }
+    return nil
+}
+
+// O(t + k) окончательно убирает возвращенную книгу - откат restore
+func (s bookSnapshot) discard(db *database.Database) error {
+    if !db.InTrash(s.Book.ID) {
+        if err := db.DeleteBook(s.Book.ID); err != nil {
+            return err
+        }
+    }
+    return db.PurgeBook(s.Book.ID)
+}
+
+// Добавление книги: отмена убирает ее в корзину, повтор возвращает оттуда
+// вместе с тегами, обложкой и экземплярами
+type addCommand struct {
+    id int32
+}
+
+func (c *addCommand) name() string {
+    return fmt.Sprintf("добавление книги ID %d", c.id)
+}
+
+func (c *addCommand) undo(db *database.Database) error {
+    return db.DeleteBook(c.id)
+}
+
+func (c *addCommand) redo(db *database.Database) error {
+    return db.RestoreFromTrash(c.id)
+}
+
+// Удаление книги - зеркало добавления
+type deleteCommand struct {
+    id int32
+}
+
+func (c *deleteCommand) name() string {
+    return fmt.Sprintf("удаление книги ID %d", c.id)
+}
+
+func (c *deleteCommand) undo(db *database.Database) error {
+    return db.RestoreFromTrash(c.id)
+}
+
+func (c *deleteCommand) redo(db *database.Database) error {
+    return db.DeleteBook(c.id)
+}
+
+// Возврат книги из корзины - обратное удалению
+type untrashCommand struct {
+    id int32
+}
+
+func (c *untrashCommand) name() string {
+    return fmt.Sprintf("возврат книги ID %d из корзины", c.id)
+}
+
+func (c *untrashCommand) undo(db *database.Database) error {
+    return db.DeleteBook(c.id)
+}
+
+func (c *untrashCommand) redo(db *database.Database) error {
+    return db.RestoreFromTrash(c.id)
+}
+
+// Редактирование книги, возможно со сменой ID
+type editCommand struct {
+    before, after         database.BookView
+    beforeTags, afterTags []string
+    beforeISBN, afterISBN string
+}
+
+func (c *editCommand) name() string {
+    return fmt.Sprintf("изменение книги ID %d", c.after.ID)
+}
+
+// O(t) переводит книгу из состояния from в to
+func applyEdit(db *database.Database, from, to database.BookView, tags []string, isbn string) error {
+    if err := db.ReplaceBook(from.ID, to); err != nil {
+        return err
+    }
+    if err := db.SetTags(to.ID, tags); err != nil {
+        return err
+    }
+    return db.SetISBN(to.ID, isbn)
+}
+
+func (c *editCommand) undo(db *database.Database) error {
+    return applyEdit(db, c.after, c.before, c.beforeTags, c.beforeISBN)
+}
+
+func (c *editCommand) redo(db *database.Database) error {
+    return applyEdit(db, c.before, c.after, c.afterTags, c.afterISBN)
+}
+
+// Изменение одной книги в массовой операции; nil - книги не было
+type bookChange struct {
+    Before *database.BookView
+    After  *database.BookView
+}
+
+// Массовая операция (импорт, откат базы) - набор изменений книг
+type batchCommand struct {
+    title   string
+    changes []bookChange
+}
+
+func (c *batchCommand) name() string {
+    return fmt.Sprintf("%s (книг: %d)", c.title, len(c.changes))
+}
+
+// O(1) переводит одну книгу из from в to
+func applyChange(db *database.Database, from, to *database.BookView) error {
+    switch {
+    case to == nil:
+        return db.DeleteBook(from.ID)
+    case from == nil && db.InTrash(to.ID):
+        if err := db.RestoreFromTrash(to.ID); err != nil {
+            return err
+        }
+        return db.UpdateBook(*to)
+    case from == nil:
+        _, err := db.AddBook(*to)
+        return err
+    default:
+        return db.UpdateBook(*to)
+    }
+}
+
+// O(n) в обратном порядке
+func (c *batchCommand) undo(db *database.Database) error {
+    for i := len(c.changes) - 1; i >= 0; i-- {
+        if err := applyChange(db, c.changes[i].After, c.changes[i].Before); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// O(n)
+func (c *batchCommand) redo(db *database.Database) error {
+    for _, change := range c.changes {
+        if err := applyChange(db, change.Before, change.After); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// O(n) снимок всех книг по ID для batchCommand
+func booksByID(db *database.Database) (map[int32]database.BookView, error) {
+    books, err := db.GetAllBooks()
+    if err != nil {
+        return nil, err
+    }
+    result := make(map[int32]database.BookView, len(books))
+    for _, book := range books {
+        result[book.ID] = book
+    }
+    return result, nil
+}
+
+// O(n) разница двух снимков - изменения массовой операции
+func diffBooks(before, after map[int32]database.BookView) []bookChange {
+    var changes []bookChange
+    for id, old := range before {
+        if current, exists := after[id]; !exists {
+            changes = append(changes, bookChange{Before: &old})
+        } else if current != old {
+            changes = append(changes, bookChange{Before: &old, After: &current})
+        }
+    }
+    for id, current := range after {
+        if _, exists := before[id]; !exists {
+            changes = append(changes, bookChange{After: &current})
+        }
+    }
+    return changes
+}
+
+// Очистка базы: книги и корзина возвращаются с тегами, обложками и экземплярами.
+// Читателей, выдачи, штрафы и брони очистка не трогает
+type clearCommand struct {
+    snapshots []bookSnapshot
+}
+
+func (c *clearCommand) name() string {
+    return fmt.Sprintf("очистка базы (книг: %d)", len(c.snapshots))
+}
+
+// O(n * (t + k)) все или ничего: при сбое уже возвращенные книги снова
+// убираются, база остается очищенной и отмену можно повторить
+func (c *clearCommand) undo(db *database.Database) error {
+    for i, snapshot := range c.snapshots {
+        if err := snapshot.restore(db); err != nil {
+            for j := i - 1; j >= 0; j-- {
+                if discardErr := c.snapshots[j].discard(db); discardErr != nil {
+                    return fmt.Errorf("%v (откат не удался: %v)", err, discardErr)
+                }
+            }
+            return err
+        }
+    }
+    return nil
+}
+
+func (c *clearCommand) redo(db *database.Database) error {
+    return db.ClearDatabase()
+}
+
+// Выполненная операция попадает в стек отмены
+func (a *App) record(c command) {
+    a.undoStack.push(c)
+    a.updateUndoButtons()
+}
+
+// Массовая операция с записью разницы до/после в стек отмены
+func (a *App) recordBatch(title string, operation func() error) error {
+    before, err := booksByID(a.database)
+    if err != nil {
+        return err
+    }
+    operationErr := operation()
+
+    // даже неудачная операция могла успеть что-то поменять
+    after, err := booksByID(a.database)
+    if err != nil {
+        return err
+    }
+    if changes := diffBooks(before, after); len(changes) > 0 {
+        a.record(&batchCommand{title: title, changes: changes})
+    }
+    return operationErr
+}
+
+func (a *App) undo() {
+    stack := &a.undoStack
+    if len(stack.done) == 0 {
+        return
+    }
+    c := stack.done[len(stack.done)-1]
+    if err := c.undo(a.database); err != nil {
+        dialog.ShowError(fmt.Errorf("не удалось отменить %s: %v", c.name(), err), a.window)
+        a.refreshTable()
+        return
+    }
+    stack.done = stack.done[:len(stack.done)-1]
+    stack.undone = append(stack.undone, c)
+
+    a.refreshTable()
+    a.updateUndoButtons()
+    a.updateStatusBar("Отменено: " + c.name())
+}
+
+func (a *App) redo() {
+    stack := &a.undoStack
+    if len(stack.undone) == 0 {
+        return
+    }
+    c := stack.undone[len(stack.undone)-1]
+    if err := c.redo(a.database); err != nil {
+        dialog.ShowError(fmt.Errorf("не удалось повторить %s: %v", c.name(), err), a.window)
+        a.refreshTable()
+        return
+    }
+    stack.undone = stack.undone[:len(stack.undone)-1]
+    stack.done = append(stack.done, c)
+
+    a.refreshTable()
+    a.updateUndoButtons()
+    a.updateStatusBar("Повторено: " + c.name())
+}
+
+// Кнопки недоступны, когда отменять или повторять нечего
+func (a *App) updateUndoButtons() {
+    if a.undoButton == nil {
+        return
+    }
+    if len(a.undoStack.done) > 0 {
+        a.undoButton.Enable()
+    } else {
+        a.undoButton.Disable()
+    }
+    if len(a.undoStack.undone) > 0 {
+        a.redoButton.Enable()
+    } else {
+        a.redoButton.Disable()
+    }
+}
+
+// Ctrl+Z - отменить, Ctrl+Shift+Z - повторить (Cmd на macOS)
+func (a *App) registerUndoShortcuts() {
+    a.window.Canvas().AddShortcut(&desktop.CustomShortcut{
+        KeyName:  fyne.KeyZ,
+        Modifier: fyne.KeyModifierShortcutDefault,
+    }, func(fyne.Shortcut) {
+        a.undo()
+    })
+    a.window.Canvas().AddShortcut(&desktop.CustomShortcut{
+        KeyName:  fyne.KeyZ,
+        Modifier: fyne.KeyModifierShortcutDefault | fyne.KeyModifierShift,
+    }, func(fyne.Shortcut) {
+        a.redo()
+    })
+}
\ No newline at end of file
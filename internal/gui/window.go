@@ -3,18 +3,43 @@ package gui
 import (
     "github.com/nydeg/bd/internal/database"
     "fmt"
+    "image/color"
 
     "fyne.io/fyne/v2"
     "fyne.io/fyne/v2/app"
+    "fyne.io/fyne/v2/canvas"
     "fyne.io/fyne/v2/container"
     "fyne.io/fyne/v2/widget"
 )
 
 type App struct {
-    database      *database.Database
-    window        fyne.Window
-    table         *widget.Table
-    books         []database.BookView
+    database       *database.Database
+    window         fyne.Window
+    table          *widget.Table
+    books          []database.BookView
+    selectedBookID int32
+    coverCache     map[int32]coverThumbnail
+    tagCheckGroup  *widget.CheckGroup
+    tagFilter      []string
+    tagFilterAny   bool
+
+    members         []database.MemberView
+    memberNames     map[int32]string
+    membersTable    *widget.Table
+    loans           []database.LoanView
+    bookTitles      map[int32]string
+    loansTable      *widget.Table
+    activeLoansOnly bool
+    holds           []database.HoldView
+    holdsTable      *widget.Table
+    activeHoldsOnly bool
+    overdueBooks    map[int32]int
+    overdueMembers  map[int32]int
+    trash           []database.TrashEntry
+    trashTable      *widget.Table
+    undoStack       undoStack
+    undoButton      *widget.Button
+    redoButton      *widget.Button
     // statusLabel   *widget.Label
     updateStatusBar func(string)
 }
@@ -27,6 +52,7 @@ func Run(db *database.Database) {
         database: db,
         window:   window,
         books:    []database.BookView{},
+        coverCache: make(map[int32]coverThumbnail),
     }
     
     app.createUI()
@@ -38,9 +64,25 @@ func (a *App) createUI() {
     a.table = a.createTable()
     toolbar := a.createToolbar()
     statusBar := a.createStatusBar()
+
+    // прозрачный прямоугольник задает ширину боковой панели
+    sidebarWidth := canvas.NewRectangle(color.Transparent)
+    sidebarWidth.SetMinSize(fyne.NewSize(180, 0))
+    sidebar := container.NewStack(sidebarWidth, a.createTagSidebar())
+    
+    booksTab := container.NewBorder(nil, nil, sidebar, nil, a.table)
+    tabs := container.NewAppTabs(
+        container.NewTabItem("Книги", booksTab),
+        container.NewTabItem("Читатели", a.createMembersTab()),
+        container.NewTabItem("Выдачи", a.createLoansTab()),
+        container.NewTabItem("Брони", a.createHoldsTab()),
+        container.NewTabItem("Корзина", a.createTrashTab()),
+    )
     
-    content := container.NewBorder(toolbar, statusBar, nil, nil, a.table)
+    content := container.NewBorder(toolbar, statusBar, nil, nil, tabs)
     a.window.SetContent(content)
+    a.window.SetMainMenu(a.createMainMenu())
+    a.registerUndoShortcuts()
     
     a.refreshTable()
 }
@@ -50,27 +92,40 @@ func (a *App) createToolbar() fyne.CanvasObject {
     editButton := widget.NewButton("✏️ Редактировать", a.showEditDialog)
     deleteButton := widget.NewButton("🗑️ Удалить", a.showDeleteDialog)
     searchButton := widget.NewButton("🔍 Поиск", a.showSearchDialog)
+    authorsButton := widget.NewButton("👤 Авторы", a.showAuthorsDialog)
+    barcodeButton := widget.NewButton("🏷️ Штрихкод", a.showBarcodeDialog)
+    labelsButton := widget.NewButton("🖨️ Этикетки", a.showTableLabelsDialog)
+    auditButton := widget.NewButton("📜 Журнал", a.showAuditDialog)
     refreshButton := widget.NewButton("🔄 Обновить", a.refreshTable)
+    a.undoButton = widget.NewButton("↶ Отменить", a.undo)
+    a.redoButton = widget.NewButton("↷ Повторить", a.redo)
+    a.updateUndoButtons()
 
     importTxtButton := widget.NewButton("📥 Импорт TXT", a.showImportDialog)
     exportTxtButton := widget.NewButton("📤 Экспорт TXT", a.showExportDialog)
     
     importExcelButton := widget.NewButton("📊 Импорт Excel", a.showImportExcelDialog)
     exportExcelButton := widget.NewButton("📈 Экспорт Excel", a.showExportExcelDialog)
+
+    importJSONButton := widget.NewButton("🧾 Импорт JSON", a.showImportJSONDialog)
+    exportJSONButton := widget.NewButton("🧾 Экспорт JSON", a.showExportJSONDialog)
     
     clearButton := widget.NewButton("🗑️ Очистить БД", a.showClearDatabaseDialog)
     statsButton := widget.NewButton("📊 Статистика", a.showStatsDialog)
+    compactButton := widget.NewButton("🧹 Сжать БД", a.showCompactDialog)
     
     toolbar := container.NewHBox(
-        addButton, editButton, deleteButton, searchButton, 
+        addButton, editButton, deleteButton, searchButton, authorsButton, barcodeButton, labelsButton,
         widget.NewSeparator(),
-        refreshButton, 
+        refreshButton, a.undoButton, a.redoButton,
         widget.NewSeparator(),
         importTxtButton, exportTxtButton,
         widget.NewSeparator(),
         importExcelButton, exportExcelButton,
         widget.NewSeparator(),
-        clearButton, statsButton,
+        importJSONButton, exportJSONButton,
+        widget.NewSeparator(),
+        clearButton, statsButton, auditButton, compactButton,
     )
     
     return toolbar
@@ -87,35 +142,54 @@ func (a *App) createStatusBar() fyne.CanvasObject {
 }
 
 func (a *App) refreshTable() {
-    books, err := a.database.GetAllBooks()
+    a.refreshTagSidebar()
+
+    books, err := a.loadBooks()
     if err != nil {
         fmt.Printf("Ошибка загрузки книг: %v\n", err)
         a.books = []database.BookView{}
     } else {
         a.books = books
     }
+    a.pruneCoverCache()
+
+    a.refreshCirculation()
+    a.refreshTrash()
     
     if a.table != nil {
         a.table.Refresh()
     }
     
     if a.updateStatusBar != nil {
-        a.updateStatusBar(fmt.Sprintf("Загружено книг: %d", len(a.books)))
+        if len(a.tagFilter) > 0 {
+            a.updateStatusBar(fmt.Sprintf("Показано книг: %d (фильтр по тегам)", len(a.books)))
+        } else {
+            a.updateStatusBar(fmt.Sprintf("Загружено книг: %d", len(a.books)))
+        }
     }
 }
 
 func (a *App) createTable() *widget.Table {
     table := widget.NewTable(
         func() (int, int) {
-            return len(a.books) + 1, 5
+            return len(a.books) + 1, 6
         },
         func() fyne.CanvasObject {
-            return widget.NewLabel("template")
+            thumbnail := canvas.NewImageFromImage(nil)
+            thumbnail.FillMode = canvas.ImageFillContain
+            thumbnail.SetMinSize(fyne.NewSize(coverThumbnailWidth, coverThumbnailHeight))
+            return container.NewStack(widget.NewLabel("template"), thumbnail)
         },
         func(id widget.TableCellID, cell fyne.CanvasObject) {
-            label := cell.(*widget.Label)
+            objects := cell.(*fyne.Container).Objects
+            label := objects[0].(*widget.Label)
+            thumbnail := objects[1].(*canvas.Image)
+
+            label.Show()
+            thumbnail.Hide()
+
             if id.Row == 0 {
-                headers := []string{"ID", "Название", "Автор", "Год", "Тираж"}
+                headers := []string{"ID", "Название", "Автор", "Год", "Тираж", "Обложка"}
                 if id.Col < len(headers) {
                     label.SetText(headers[id.Col])
                 }
@@ -126,24 +200,45 @@ func (a *App) createTable() *widget.Table {
                     case 0:
                         label.SetText(fmt.Sprintf("%d", book.ID))
                     case 1:
-                        label.SetText(book.Title)
+                        label.SetText(book.Title + overdueBadge(a.overdueBooks[book.ID]))
                     case 2:
                         label.SetText(book.Author)
                     case 3:
                         label.SetText(fmt.Sprintf("%d", book.Year))
                     case 4:
                         label.SetText(fmt.Sprintf("%d", book.Copies))
+                    case 5:
+                        if img := a.coverThumbnail(book.ID); img != nil {
+                            label.Hide()
+                            thumbnail.Image = img
+                            thumbnail.Refresh()
+                            thumbnail.Show()
+                        } else {
+                            label.SetText("—")
+                        }
                     }
                 }
             }
         },
     )
+
+    // клик по миниатюре открывает просмотр и замену обложки
+    table.OnSelected = func(id widget.TableCellID) {
+        if id.Row > 0 && id.Row-1 < len(a.books) {
+            a.selectedBookID = a.books[id.Row-1].ID
+        }
+        if id.Col == 5 && id.Row > 0 && id.Row-1 < len(a.books) {
+            a.showCoverDialog(a.books[id.Row-1])
+        }
+        table.UnselectAll()
+    }
     
     table.SetColumnWidth(0, 60)
     table.SetColumnWidth(1, 300)
     table.SetColumnWidth(2, 200)
     table.SetColumnWidth(3, 80)
     table.SetColumnWidth(4, 100)
+    table.SetColumnWidth(5, 80)
     
     return table
 }
\ No newline at end of file
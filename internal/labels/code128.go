@@ -0,0 +1,84 @@
+package labels
+
+import (
+    "fmt"
+)
+
+// Ширины полос и пробелов символов Code 128 (в модулях), индекс - значение символа.
+// 103-105 - стартовые символы A, B, C; последний - стоп
+var code128Patterns = []string{
+    "212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+    "221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+    "221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+    "212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+    "231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+    "231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+    "314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+    "112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+    "111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+    "214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+    "114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+    code128StartB = 104
+    code128StartC = 105
+    code128Stop   = 106
+)
+
+// O(n) кодирует строку в Code 128: только цифры четной длины - набор C
+// (две цифры на символ), иначе набор B (печатные ASCII 32-126).
+// Возвращает модули: true - полоса
+func encodeCode128(text string) ([]bool, error) {
+    if text == "" {
+        return nil, fmt.Errorf("пустая строка для Code 128")
+    }
+
+    var values []int
+    if isDigits(text) && len(text)%2 == 0 {
+        values = append(values, code128StartC)
+        for i := 0; i < len(text); i += 2 {
+            values = append(values, int(text[i]-'0')*10+int(text[i+1]-'0'))
+        }
+    } else {
+        values = append(values, code128StartB)
+        for _, r := range text {
+            if r < 32 || r > 126 {
+                return nil, fmt.Errorf("символ '%c' нельзя закодировать в Code 128", r)
+            }
+            values = append(values, int(r)-32)
+        }
+    }
+
+    // контрольный символ: старт плюс взвешенная по позиции сумма, по модулю 103
+    checksum := values[0]
+    for i := 1; i < len(values); i++ {
+        checksum += i * values[i]
+    }
+    values = append(values, checksum%103, code128Stop)
+
+    var modules []bool
+    for _, value := range values {
+        bar := true
+        for _, width := range code128Patterns[value] {
+            for j := 0; j < int(width-'0'); j++ {
+                modules = append(modules, bar)
+            }
+            bar = !bar
+        }
+    }
+    return modules, nil
+}
+
+// O(n)
+func isDigits(text string) bool {
+    if text == "" {
+        return false
+    }
+    for _, r := range text {
+        if r < '0' || r > '9' {
+            return false
+        }
+    }
+    return true
+}
\ No newline at end of file
@@ -0,0 +1,129 @@
+package labels
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Коды цифр EAN-13 левой половины в наборе L; R - инверсия L, G - R задом наперед
+var eanLCodes = []string{
+    "0001101", "0011001", "0010011", "0111101", "0100011",
+    "0110001", "0101111", "0111011", "0110111", "0001011",
+}
+
+// Первая цифра EAN-13 не кодируется полосами, а задает чередование наборов L и G
+var eanParity = []string{
+    "LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+    "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+}
+
+// O(1) контрольная цифра по первым 12 цифрам: веса 1 и 3 по очереди
+func eanCheckDigit(digits string) byte {
+    sum := 0
+    for i := 0; i < 12; i++ {
+        weight := 1
+        if i%2 == 1 {
+            weight = 3
+        }
+        sum += int(digits[i]-'0') * weight
+    }
+    return byte('0' + (10-sum%10)%10)
+}
+
+// O(1) 12 цифр - дописываем контрольную, 13 - проверяем ее
+func NormalizeEAN13(code string) (string, error) {
+    code = strings.ReplaceAll(strings.TrimSpace(code), "-", "")
+    if !isDigits(code) || (len(code) != 12 && len(code) != 13) {
+        return "", fmt.Errorf("EAN-13 должен состоять из 12 или 13 цифр: %s", code)
+    }
+    check := eanCheckDigit(code)
+    if len(code) == 12 {
+        return code + string(check), nil
+    }
+    if code[12] != check {
+        return "", fmt.Errorf("неверная контрольная цифра EAN-13: %s", code)
+    }
+    return code, nil
+}
+
+// O(1) ISBN-10 или ISBN-13 (с дефисами или без) -> EAN-13.
+// ISBN-10 получает префикс 978 и новую контрольную цифру
+func ISBNToEAN13(isbn string) (string, error) {
+    code := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+    switch len(code) {
+    case 13:
+        if !strings.HasPrefix(code, "978") && !strings.HasPrefix(code, "979") {
+            return "", fmt.Errorf("ISBN-13 должен начинаться с 978 или 979: %s", isbn)
+        }
+        return NormalizeEAN13(code)
+    case 10:
+        if !isDigits(code[:9]) || !(isDigits(code[9:]) || code[9] == 'X') {
+            return "", fmt.Errorf("неверный ISBN-10: %s", isbn)
+        }
+        sum := 0
+        for i := 0; i < 9; i++ {
+            sum += int(code[i]-'0') * (10 - i)
+        }
+        check := 10
+        if code[9] != 'X' {
+            check = int(code[9] - '0')
+        }
+        if (sum+check)%11 != 0 {
+            return "", fmt.Errorf("неверная контрольная цифра ISBN-10: %s", isbn)
+        }
+        return NormalizeEAN13("978" + code[:9])
+    }
+    return "", fmt.Errorf("ISBN должен содержать 10 или 13 символов: %s", isbn)
+}
+
+// O(1) 95 модулей: краевые, 6 цифр, центральные, 6 цифр, краевые
+func encodeEAN13(code string) ([]bool, error) {
+    code, err := NormalizeEAN13(code)
+    if err != nil {
+        return nil, err
+    }
+
+    var pattern strings.Builder
+    pattern.WriteString("101")
+    parity := eanParity[code[0]-'0']
+    for i := 1; i <= 6; i++ {
+        l := eanLCodes[code[i]-'0']
+        if parity[i-1] == 'G' {
+            l = reverseString(invertBits(l))
+        }
+        pattern.WriteString(l)
+    }
+    pattern.WriteString("01010")
+    for i := 7; i <= 12; i++ {
+        pattern.WriteString(invertBits(eanLCodes[code[i]-'0']))
+    }
+    pattern.WriteString("101")
+
+    modules := make([]bool, pattern.Len())
+    for i, c := range pattern.String() {
+        modules[i] = c == '1'
+    }
+    return modules, nil
+}
+
+// O(n)
+func invertBits(bits string) string {
+    inverted := []byte(bits)
+    for i, b := range inverted {
+        if b == '0' {
+            inverted[i] = '1'
+        } else {
+            inverted[i] = '0'
+        }
+    }
+    return string(inverted)
+}
+
+// O(n) только для ASCII
+func reverseString(s string) string {
+    reversed := []byte(s)
+    for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+        reversed[i], reversed[j] = reversed[j], reversed[i]
+    }
+    return string(reversed)
+}
\ No newline at end of file
@@ -0,0 +1,345 @@
+// Пакет labels печатает этикетки для книг: штрихкод (Code 128 или EAN-13),
+// QR-код с ID книги и подпись. Этикетки сохраняются в PNG по одной
+// или раскладываются сеткой по страницам PDF. Все на чистом Go
+package labels
+
+import (
+    "github.com/nydeg/bd/internal/database"
+    "fmt"
+    "image"
+    "image/color"
+    "image/draw"
+    "image/png"
+    "math"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "golang.org/x/image/font"
+    "golang.org/x/image/font/gofont/goregular"
+    "golang.org/x/image/font/opentype"
+    "golang.org/x/image/math/fixed"
+)
+
+type Symbology int
+
+const (
+    // EAN-13 для ISBN и 12-13-значных цифровых кодов, иначе Code 128
+    SymbologyAuto Symbology = iota
+    SymbologyCode128
+    SymbologyEAN13
+)
+
+// Одна этикетка. Code печатается штрихкодом, QR кодирует ID книги.
+// ISBN издания ("" - не указан) печатается как EAN-13, если Code
+// нельзя напечатать выбранным EAN-13
+type Label struct {
+    BookID int32
+    Title  string
+    Author string
+    Code   string
+    ISBN   string
+}
+
+// Этикетки для книги: по одной на каждый код (например, штрихкоды экземпляров),
+// без кодов - одна с ISBN, а без него с ID книги вместо штрихкода
+func FromBook(book database.BookView, isbn string, codes ...string) []Label {
+    if len(codes) == 0 {
+        code := isbn
+        if code == "" {
+            code = fmt.Sprintf("%d", book.ID)
+        }
+        codes = []string{code}
+    }
+    labels := make([]Label, len(codes))
+    for i, code := range codes {
+        labels[i] = Label{
+            BookID: book.ID,
+            Title:  book.Title,
+            Author: book.Author,
+            Code:   code,
+            ISBN:   isbn,
+        }
+    }
+    return labels
+}
+
+// O(n) по одной этикетке на книгу - для выделения или результатов поиска.
+// ISBN книг берется из базы
+func FromBooks(db *database.Database, books []database.BookView) []Label {
+    labels := make([]Label, 0, len(books))
+    for _, book := range books {
+        labels = append(labels, FromBook(book, db.GetISBN(book.ID))...)
+    }
+    return labels
+}
+
+// Размер и содержимое одной этикетки
+type Options struct {
+    WidthMM   float64
+    HeightMM  float64
+    DPI       int
+    Symbology Symbology
+    ShowQR    bool
+    ShowText  bool // название и автор
+}
+
+// 70x37 мм - самый ходовой размер самоклеящихся листов A4 (3x8)
+func DefaultOptions() Options {
+    return Options{
+        WidthMM:   70,
+        HeightMM:  37,
+        DPI:       300,
+        Symbology: SymbologyAuto,
+        ShowQR:    true,
+        ShowText:  true,
+    }
+}
+
+// O(1)
+func (o Options) pixels(mm float64) int {
+    return int(math.Round(mm / 25.4 * float64(o.DPI)))
+}
+
+// O(1)
+func (o Options) validate() error {
+    if o.WidthMM <= 0 || o.HeightMM <= 0 {
+        return fmt.Errorf("размер этикетки должен быть положительным")
+    }
+    if o.DPI < 72 || o.DPI > 1200 {
+        return fmt.Errorf("разрешение должно быть от 72 до 1200 точек на дюйм")
+    }
+    return nil
+}
+
+// O(n) модули штрихкода и текст под ним (для EAN-13 - с контрольной цифрой)
+func barcodeModules(code string, symbology Symbology) ([]bool, string, int, error) {
+    if symbology == SymbologyAuto {
+        symbology = SymbologyCode128
+        if _, err := ISBNToEAN13(code); err == nil {
+            symbology = SymbologyEAN13
+        } else if _, err := NormalizeEAN13(code); err == nil {
+            symbology = SymbologyEAN13
+        }
+    }
+
+    if symbology == SymbologyEAN13 {
+        normalized, err := ISBNToEAN13(code)
+        if err != nil {
+            normalized, err = NormalizeEAN13(code)
+        }
+        if err != nil {
+            return nil, "", 0, err
+        }
+        modules, err := encodeEAN13(normalized)
+        // тихая зона EAN-13: 11 модулей слева, 7 справа, берем симметрично
+        return modules, normalized, 11, err
+    }
+
+    modules, err := encodeCode128(code)
+    return modules, code, 10, err
+}
+
+// Начертания подписей; шрифт Go Regular содержит кириллицу
+type faces struct {
+    title font.Face
+    small font.Face
+}
+
+var parsedFont *opentype.Font
+
+// O(1) шрифт разбирается один раз, начертания - под разрешение
+func loadFaces(dpi int) (*faces, error) {
+    if parsedFont == nil {
+        parsed, err := opentype.Parse(goregular.TTF)
+        if err != nil {
+            return nil, fmt.Errorf("ошибка загрузки шрифта: %v", err)
+        }
+        parsedFont = parsed
+    }
+
+    title, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{Size: 9, DPI: float64(dpi), Hinting: font.HintingFull})
+    if err != nil {
+        return nil, err
+    }
+    small, err := opentype.NewFace(parsedFont, &opentype.FaceOptions{Size: 7, DPI: float64(dpi), Hinting: font.HintingFull})
+    if err != nil {
+        return nil, err
+    }
+    return &faces{title: title, small: small}, nil
+}
+
+// O(w * h) рисует этикетку: слева QR, справа подпись и штрихкод
+func Render(label Label, opts Options) (*image.Gray, error) {
+    if err := opts.validate(); err != nil {
+        return nil, err
+    }
+    f, err := loadFaces(opts.DPI)
+    if err != nil {
+        return nil, err
+    }
+    return render(label, opts, f)
+}
+
+// O(w * h)
+func render(label Label, opts Options, f *faces) (*image.Gray, error) {
+    width, height := opts.pixels(opts.WidthMM), opts.pixels(opts.HeightMM)
+    img := image.NewGray(image.Rect(0, 0, width, height))
+    draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+    padding := opts.pixels(2)
+    left := padding
+
+    if opts.ShowQR {
+        qr, err := encodeQR([]byte(fmt.Sprintf("%d", label.BookID)))
+        if err != nil {
+            return nil, err
+        }
+        side := height - 2*padding
+        if side > width/2 {
+            side = width / 2
+        }
+        // тихая зона QR - 4 модуля с каждой стороны, но часть ее дает отступ этикетки
+        moduleSize := side / (qr.size + 4)
+        if moduleSize < 1 {
+            return nil, fmt.Errorf("QR-код не помещается на этикетку, увеличьте ее или разрешение")
+        }
+        offsetX := padding + (side-moduleSize*qr.size)/2
+        offsetY := (height - moduleSize*qr.size) / 2
+        for y := 0; y < qr.size; y++ {
+            for x := 0; x < qr.size; x++ {
+                if qr.modules[y][x] {
+                    fillRect(img, offsetX+x*moduleSize, offsetY+y*moduleSize, moduleSize, moduleSize)
+                }
+            }
+        }
+        left += side + padding
+    }
+
+    textWidth := width - left - padding
+    top := padding
+
+    if opts.ShowText {
+        top += f.title.Metrics().Ascent.Ceil()
+        drawText(img, f.title, left, top, fitText(f.title, label.Title, textWidth))
+        top += f.title.Metrics().Descent.Ceil() + f.small.Metrics().Ascent.Ceil()
+        drawText(img, f.small, left, top, fitText(f.small, label.Author, textWidth))
+        top += f.small.Metrics().Descent.Ceil() + padding/2
+    }
+
+    modules, caption, quiet, err := barcodeModules(label.Code, opts.Symbology)
+    if err != nil && opts.Symbology == SymbologyEAN13 {
+        // штрихкод экземпляра не EAN - печатаем ISBN издания
+        if label.ISBN == "" {
+            return nil, fmt.Errorf("книга ID %d: код '%s' нельзя напечатать как EAN-13, а ISBN у книги не указан (%v)",
+                label.BookID, label.Code, err)
+        }
+        modules, caption, quiet, err = barcodeModules(label.ISBN, SymbologyEAN13)
+    }
+    if err != nil {
+        return nil, err
+    }
+    moduleWidth := textWidth / (len(modules) + 2*quiet)
+    if moduleWidth < 1 {
+        return nil, fmt.Errorf("штрихкод '%s' не помещается на этикетку, увеличьте ее или разрешение", label.Code)
+    }
+
+    captionHeight := f.small.Metrics().Ascent.Ceil() + f.small.Metrics().Descent.Ceil()
+    barsBottom := height - padding - captionHeight
+    if barsBottom-top < opts.pixels(5) {
+        return nil, fmt.Errorf("на этикетке не хватает места по высоте для штрихкода")
+    }
+
+    barsLeft := left + (textWidth-moduleWidth*len(modules))/2
+    for i, dark := range modules {
+        if dark {
+            fillRect(img, barsLeft+i*moduleWidth, top, moduleWidth, barsBottom-top)
+        }
+    }
+
+    captionWidth := font.MeasureString(f.small, caption).Ceil()
+    drawText(img, f.small, left+(textWidth-captionWidth)/2, barsBottom+f.small.Metrics().Ascent.Ceil(), caption)
+    return img, nil
+}
+
+// O(w * h)
+func fillRect(img *image.Gray, x, y, w, h int) {
+    draw.Draw(img, image.Rect(x, y, x+w, y+h), image.Black, image.Point{}, draw.Src)
+}
+
+// O(n)
+func drawText(img *image.Gray, face font.Face, x, y int, text string) {
+    drawer := &font.Drawer{
+        Dst:  img,
+        Src:  image.NewUniform(color.Black),
+        Face: face,
+        Dot:  fixed.P(x, y),
+    }
+    drawer.DrawString(text)
+}
+
+// O(n^2) обрезаем строку с многоточием, чтобы влезла в width пикселей
+func fitText(face font.Face, text string, width int) string {
+    if font.MeasureString(face, text).Ceil() <= width {
+        return text
+    }
+    runes := []rune(text)
+    for len(runes) > 0 {
+        runes = runes[:len(runes)-1]
+        candidate := strings.TrimSpace(string(runes)) + "…"
+        if font.MeasureString(face, candidate).Ceil() <= width {
+            return candidate
+        }
+    }
+    return ""
+}
+
+// O(n * w * h) каждая этикетка - отдельный PNG в каталоге dir,
+// имя файла по коду. Возвращает пути созданных файлов
+func SavePNG(dir string, labels []Label, opts Options) ([]string, error) {
+    if err := opts.validate(); err != nil {
+        return nil, err
+    }
+    f, err := loadFaces(opts.DPI)
+    if err != nil {
+        return nil, err
+    }
+
+    var paths []string
+    used := make(map[string]int)
+    for _, label := range labels {
+        img, err := render(label, opts, f)
+        if err != nil {
+            return paths, err
+        }
+
+        name := fmt.Sprintf("label_%d_%s", label.BookID, safeFileName(label.Code))
+        used[name]++
+        if used[name] > 1 {
+            name = fmt.Sprintf("%s_%d", name, used[name])
+        }
+        path := filepath.Join(dir, name+".png")
+
+        file, err := os.Create(path)
+        if err != nil {
+            return paths, fmt.Errorf("ошибка создания файла: %v", err)
+        }
+        err = png.Encode(file, img)
+        file.Close()
+        if err != nil {
+            return paths, fmt.Errorf("ошибка записи PNG: %v", err)
+        }
+        paths = append(paths, path)
+    }
+    return paths, nil
+}
+
+// O(n) в имени файла оставляем только буквы, цифры, '-' и '_'
+func safeFileName(s string) string {
+    return strings.Map(func(r rune) rune {
+        if r == '-' || r == '_' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+            return r
+        }
+        return '_'
+    }, s)
+}
\ No newline at end of file
@@ -0,0 +1,200 @@
+package labels
+
+import (
+    "bytes"
+    "compress/zlib"
+    "fmt"
+    "io"
+    "os"
+    "strings"
+)
+
+// Лист с сеткой этикеток. Размер этикетки считается из страницы, полей и зазоров
+type Sheet struct {
+    PageWidthMM  float64
+    PageHeightMM float64
+    MarginMM     float64
+    GapMM        float64
+    Columns      int
+    Rows         int
+    Options      Options
+}
+
+// A4, 3x8 этикеток 70x37 мм без полей
+func DefaultSheet() Sheet {
+    return Sheet{
+        PageWidthMM:  210,
+        PageHeightMM: 297,
+        MarginMM:     0,
+        GapMM:        0,
+        Columns:      3,
+        Rows:         8,
+        Options:      DefaultOptions(),
+    }
+}
+
+// O(1) размер ячейки сетки
+func (s Sheet) cellSize() (float64, float64, error) {
+    if s.Columns <= 0 || s.Rows <= 0 {
+        return 0, 0, fmt.Errorf("в сетке должна быть хотя бы одна строка и один столбец")
+    }
+    if s.MarginMM < 0 || s.GapMM < 0 {
+        return 0, 0, fmt.Errorf("поля и зазоры не могут быть отрицательными")
+    }
+    width := (s.PageWidthMM - 2*s.MarginMM - float64(s.Columns-1)*s.GapMM) / float64(s.Columns)
+    height := (s.PageHeightMM - 2*s.MarginMM - float64(s.Rows-1)*s.GapMM) / float64(s.Rows)
+    if width <= 0 || height <= 0 {
+        return 0, 0, fmt.Errorf("этикетки не помещаются на страницу")
+    }
+    return width, height, nil
+}
+
+// O(1) параметры одной этикетки с размером ячейки сетки - для PNG того же размера
+func (s Sheet) LabelOptions() (Options, error) {
+    width, height, err := s.cellSize()
+    if err != nil {
+        return Options{}, err
+    }
+    opts := s.Options
+    opts.WidthMM, opts.HeightMM = width, height
+    return opts, opts.validate()
+}
+
+// O(1)
+func mmToPoints(mm float64) float64 {
+    return mm * 72 / 25.4
+}
+
+// Минимальный писатель PDF: объекты пишутся по порядку, смещения идут в xref
+type pdfWriter struct {
+    buf     bytes.Buffer
+    offsets []int
+}
+
+// O(n)
+func (w *pdfWriter) writeObject(body string, stream []byte) int {
+    w.offsets = append(w.offsets, w.buf.Len())
+    number := len(w.offsets)
+    fmt.Fprintf(&w.buf, "%d 0 obj\n%s\n", number, body)
+    if stream != nil {
+        w.buf.WriteString("stream\n")
+        w.buf.Write(stream)
+        w.buf.WriteString("\nendstream\n")
+    }
+    w.buf.WriteString("endobj\n")
+    return number
+}
+
+// O(n)
+func deflate(data []byte) ([]byte, error) {
+    var out bytes.Buffer
+    zw := zlib.NewWriter(&out)
+    if _, err := zw.Write(data); err != nil {
+        return nil, err
+    }
+    if err := zw.Close(); err != nil {
+        return nil, err
+    }
+    return out.Bytes(), nil
+}
+
+// O(n * w * h) этикетки раскладываются слева направо, сверху вниз,
+// каждая вставляется картинкой в градациях серого
+func WritePDF(out io.Writer, labels []Label, sheet Sheet) error {
+    if len(labels) == 0 {
+        return fmt.Errorf("нет этикеток для печати")
+    }
+    opts, err := sheet.LabelOptions()
+    if err != nil {
+        return err
+    }
+    cellWidth, cellHeight := opts.WidthMM, opts.HeightMM
+    f, err := loadFaces(opts.DPI)
+    if err != nil {
+        return err
+    }
+
+    w := &pdfWriter{}
+    w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+    // каталог и дерево страниц пишем в конце, номера резервируем сразу
+    w.offsets = append(w.offsets, 0, 0)
+    const catalogObject, pagesObject = 1, 2
+
+    perPage := sheet.Columns * sheet.Rows
+    var pageObjects []int
+
+    for start := 0; start < len(labels); start += perPage {
+        end := start + perPage
+        if end > len(labels) {
+            end = len(labels)
+        }
+
+        var content strings.Builder
+        var resources strings.Builder
+        for i, label := range labels[start:end] {
+            img, err := render(label, opts, f)
+            if err != nil {
+                return err
+            }
+            compressed, err := deflate(img.Pix)
+            if err != nil {
+                return fmt.Errorf("ошибка сжатия изображения: %v", err)
+            }
+            bounds := img.Bounds()
+            imageObject := w.writeObject(fmt.Sprintf(
+                "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+                bounds.Dx(), bounds.Dy(), len(compressed)), compressed)
+
+            column, row := i%sheet.Columns, i/sheet.Columns
+            x := sheet.MarginMM + float64(column)*(cellWidth+sheet.GapMM)
+            // у PDF ось Y направлена вверх
+            y := sheet.PageHeightMM - sheet.MarginMM - float64(row+1)*cellHeight - float64(row)*sheet.GapMM
+
+            name := fmt.Sprintf("Im%d", i+1)
+            fmt.Fprintf(&resources, "/%s %d 0 R ", name, imageObject)
+            fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n",
+                mmToPoints(cellWidth), mmToPoints(cellHeight), mmToPoints(x), mmToPoints(y), name)
+        }
+
+        contentObject := w.writeObject(fmt.Sprintf("<< /Length %d >>", content.Len()), []byte(content.String()))
+        pageObject := w.writeObject(fmt.Sprintf(
+            "<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << %s>> >> /Contents %d 0 R >>",
+            pagesObject, mmToPoints(sheet.PageWidthMM), mmToPoints(sheet.PageHeightMM), resources.String(), contentObject), nil)
+        pageObjects = append(pageObjects, pageObject)
+    }
+
+    var kids strings.Builder
+    for _, page := range pageObjects {
+        fmt.Fprintf(&kids, "%d 0 R ", page)
+    }
+
+    w.offsets[catalogObject-1] = w.buf.Len()
+    fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Type /Catalog /Pages %d 0 R >>\nendobj\n", catalogObject, pagesObject)
+    w.offsets[pagesObject-1] = w.buf.Len()
+    fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", pagesObject, kids.String(), len(pageObjects))
+
+    xref := w.buf.Len()
+    fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+    for _, offset := range w.offsets {
+        fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+    }
+    fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, catalogObject, xref)
+
+    _, err = out.Write(w.buf.Bytes())
+    return err
+}
+
+// O(n * w * h)
+func SavePDF(filename string, labels []Label, sheet Sheet) error {
+    file, err := os.Create(filename)
+    if err != nil {
+        return fmt.Errorf("ошибка создания файла: %v", err)
+    }
+    defer file.Close()
+
+    if err := WritePDF(file, labels, sheet); err != nil {
+        return err
+    }
+    return file.Close()
+}
\ No newline at end of file
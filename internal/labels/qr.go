@@ -0,0 +1,476 @@
+package labels
+
+import (
+    "fmt"
+)
+
+// QR-код в байтовом режиме с уровнем коррекции M, версии 1-10 (до 213 байт) -
+// этого с запасом хватает для ID книги или штрихкода экземпляра
+
+// Параметры блоков уровня M: байты коррекции на блок, затем группы
+// (количество блоков, байтов данных в блоке)
+type qrVersionInfo struct {
+    ecPerBlock int
+    groups     [][2]int
+}
+
+var qrVersionsM = []qrVersionInfo{
+    {},
+    {10, [][2]int{{1, 16}}},
+    {16, [][2]int{{1, 28}}},
+    {26, [][2]int{{1, 44}}},
+    {18, [][2]int{{2, 32}}},
+    {24, [][2]int{{2, 43}}},
+    {16, [][2]int{{4, 27}}},
+    {18, [][2]int{{4, 31}}},
+    {22, [][2]int{{2, 38}, {2, 39}}},
+    {22, [][2]int{{3, 36}, {2, 37}}},
+    {26, [][2]int{{4, 43}, {1, 44}}},
+}
+
+// центры выравнивающих узоров по версиям
+var qrAlignment = [][]int{
+    {}, {}, {6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+    {6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+const qrMaxVersion = 10
+
+// O(1)
+func (v qrVersionInfo) dataCodewords() int {
+    total := 0
+    for _, group := range v.groups {
+        total += group[0] * group[1]
+    }
+    return total
+}
+
+type qrCode struct {
+    size       int
+    modules    [][]bool // [y][x], true - темный
+    isFunction [][]bool // служебные модули, маска к ним не применяется
+}
+
+// O(s^2) s - сторона кода в модулях
+func encodeQR(data []byte) (*qrCode, error) {
+    version := 0
+    for v := 1; v <= qrMaxVersion; v++ {
+        countBits := 8
+        if v >= 10 {
+            countBits = 16
+        }
+        if 4+countBits+len(data)*8 <= qrVersionsM[v].dataCodewords()*8 {
+            version = v
+            break
+        }
+    }
+    if version == 0 {
+        return nil, fmt.Errorf("слишком длинные данные для QR-кода: %d байт", len(data))
+    }
+
+    codewords := qrAddErrorCorrection(qrDataCodewords(data, version), version)
+
+    size := version*4 + 17
+    qr := &qrCode{size: size}
+    qr.modules = make([][]bool, size)
+    qr.isFunction = make([][]bool, size)
+    for y := range qr.modules {
+        qr.modules[y] = make([]bool, size)
+        qr.isFunction[y] = make([]bool, size)
+    }
+
+    qr.drawFunctionPatterns(version)
+    qr.drawCodewords(codewords)
+
+    // выбираем маску с наименьшим штрафом
+    bestMask, bestPenalty := 0, -1
+    for mask := 0; mask < 8; mask++ {
+        qr.applyMask(mask)
+        qr.drawFormatBits(mask)
+        penalty := qr.penalty()
+        if bestPenalty < 0 || penalty < bestPenalty {
+            bestMask, bestPenalty = mask, penalty
+        }
+        qr.applyMask(mask) // XOR - повторное применение снимает маску
+    }
+    qr.applyMask(bestMask)
+    qr.drawFormatBits(bestMask)
+    return qr, nil
+}
+
+// O(n) режим, длина, данные, терминатор и байты-заполнители 0xEC 0x11
+func qrDataCodewords(data []byte, version int) []byte {
+    capacity := qrVersionsM[version].dataCodewords()
+    countBits := 8
+    if version >= 10 {
+        countBits = 16
+    }
+
+    var bits []bool
+    appendBits := func(value, count int) {
+        for i := count - 1; i >= 0; i-- {
+            bits = append(bits, (value>>i)&1 == 1)
+        }
+    }
+    appendBits(0x4, 4) // байтовый режим
+    appendBits(len(data), countBits)
+    for _, b := range data {
+        appendBits(int(b), 8)
+    }
+
+    terminator := capacity*8 - len(bits)
+    if terminator > 4 {
+        terminator = 4
+    }
+    appendBits(0, terminator)
+    for len(bits)%8 != 0 {
+        bits = append(bits, false)
+    }
+
+    result := make([]byte, 0, capacity)
+    for i := 0; i < len(bits); i += 8 {
+        var b byte
+        for j := 0; j < 8; j++ {
+            if bits[i+j] {
+                b |= 1 << (7 - j)
+            }
+        }
+        result = append(result, b)
+    }
+    for pad := byte(0xEC); len(result) < capacity; pad ^= 0xEC ^ 0x11 {
+        result = append(result, pad)
+    }
+    return result
+}
+
+// O(n * e) делим данные на блоки, считаем коррекцию Рида-Соломона
+// и перемежаем байты блоков
+func qrAddErrorCorrection(data []byte, version int) []byte {
+    info := qrVersionsM[version]
+    divisor := reedSolomonDivisor(info.ecPerBlock)
+
+    var dataBlocks, ecBlocks [][]byte
+    offset := 0
+    for _, group := range info.groups {
+        for i := 0; i < group[0]; i++ {
+            block := data[offset : offset+group[1]]
+            offset += group[1]
+            dataBlocks = append(dataBlocks, block)
+            ecBlocks = append(ecBlocks, reedSolomonRemainder(block, divisor))
+        }
+    }
+
+    var result []byte
+    for i := 0; ; i++ {
+        added := false
+        for _, block := range dataBlocks {
+            if i < len(block) {
+                result = append(result, block[i])
+                added = true
+            }
+        }
+        if !added {
+            break
+        }
+    }
+    for i := 0; i < info.ecPerBlock; i++ {
+        for _, block := range ecBlocks {
+            result = append(result, block[i])
+        }
+    }
+    return result
+}
+
+// O(1) умножение в GF(256) по модулю x^8 + x^4 + x^3 + x^2 + 1
+func gfMultiply(x, y byte) byte {
+    var z byte
+    for i := 7; i >= 0; i-- {
+        carry := z >> 7
+        z = (z << 1) ^ (carry * 0x1D)
+        z ^= ((y >> uint(i)) & 1) * x
+    }
+    return z
+}
+
+// O(d^2) порождающий многочлен степени degree без старшего коэффициента
+func reedSolomonDivisor(degree int) []byte {
+    result := make([]byte, degree)
+    result[degree-1] = 1
+    root := byte(1)
+    for i := 0; i < degree; i++ {
+        for j := 0; j < degree; j++ {
+            result[j] = gfMultiply(result[j], root)
+            if j+1 < degree {
+                result[j] ^= result[j+1]
+            }
+        }
+        root = gfMultiply(root, 0x02)
+    }
+    return result
+}
+
+// O(n * d)
+func reedSolomonRemainder(data, divisor []byte) []byte {
+    result := make([]byte, len(divisor))
+    for _, b := range data {
+        factor := b ^ result[0]
+        copy(result, result[1:])
+        result[len(result)-1] = 0
+        for i, coefficient := range divisor {
+            result[i] ^= gfMultiply(coefficient, factor)
+        }
+    }
+    return result
+}
+
+// O(1)
+func (qr *qrCode) setFunction(x, y int, dark bool) {
+    qr.modules[y][x] = dark
+    qr.isFunction[y][x] = true
+}
+
+// O(s^2) поисковые и выравнивающие узоры, полосы синхронизации,
+// резерв под формат и версию
+func (qr *qrCode) drawFunctionPatterns(version int) {
+    for i := 0; i < qr.size; i++ {
+        qr.setFunction(6, i, i%2 == 0)
+        qr.setFunction(i, 6, i%2 == 0)
+    }
+
+    qr.drawFinder(3, 3)
+    qr.drawFinder(qr.size-4, 3)
+    qr.drawFinder(3, qr.size-4)
+
+    centers := qrAlignment[version]
+    for i, cx := range centers {
+        for j, cy := range centers {
+            // углы с поисковыми узорами пропускаем
+            if (i == 0 && j == 0) || (i == 0 && j == len(centers)-1) || (i == len(centers)-1 && j == 0) {
+                continue
+            }
+            for dy := -2; dy <= 2; dy++ {
+                for dx := -2; dx <= 2; dx++ {
+                    distance := maxInt(absInt(dx), absInt(dy))
+                    qr.setFunction(cx+dx, cy+dy, distance != 1)
+                }
+            }
+        }
+    }
+
+    // пока маска неизвестна, просто резервируем место под формат
+    qr.drawFormatBits(0)
+
+    if version >= 7 {
+        remainder := version
+        for i := 0; i < 12; i++ {
+            remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+        }
+        bits := version<<12 | remainder
+        for i := 0; i < 18; i++ {
+            dark := (bits>>uint(i))&1 == 1
+            a := qr.size - 11 + i%3
+            b := i / 3
+            qr.setFunction(a, b, dark)
+            qr.setFunction(b, a, dark)
+        }
+    }
+}
+
+// O(1) поисковый узор 7x7 с белой рамкой-разделителем
+func (qr *qrCode) drawFinder(cx, cy int) {
+    for dy := -4; dy <= 4; dy++ {
+        for dx := -4; dx <= 4; dx++ {
+            x, y := cx+dx, cy+dy
+            if x < 0 || x >= qr.size || y < 0 || y >= qr.size {
+                continue
+            }
+            distance := maxInt(absInt(dx), absInt(dy))
+            qr.setFunction(x, y, distance != 2 && distance != 4)
+        }
+    }
+}
+
+// O(1) 15 бит формата (уровень M = 00 и маска) с кодом БЧХ, две копии
+func (qr *qrCode) drawFormatBits(mask int) {
+    data := 0<<3 | mask
+    remainder := data
+    for i := 0; i < 10; i++ {
+        remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+    }
+    bits := (data<<10 | remainder) ^ 0x5412
+    bit := func(i int) bool {
+        return (bits>>uint(i))&1 == 1
+    }
+
+    for i := 0; i <= 5; i++ {
+        qr.setFunction(8, i, bit(i))
+    }
+    qr.setFunction(8, 7, bit(6))
+    qr.setFunction(8, 8, bit(7))
+    qr.setFunction(7, 8, bit(8))
+    for i := 9; i < 15; i++ {
+        qr.setFunction(14-i, 8, bit(i))
+    }
+
+    for i := 0; i < 8; i++ {
+        qr.setFunction(qr.size-1-i, 8, bit(i))
+    }
+    for i := 8; i < 15; i++ {
+        qr.setFunction(8, qr.size-15+i, bit(i))
+    }
+    qr.setFunction(8, qr.size-8, true) // всегда темный модуль
+}
+
+// O(s^2) змейкой по парам столбцов снизу вверх и обратно, столбец 6 пропускаем
+func (qr *qrCode) drawCodewords(codewords []byte) {
+    i := 0
+    for right := qr.size - 1; right >= 1; right -= 2 {
+        if right == 6 {
+            right = 5
+        }
+        for vertical := 0; vertical < qr.size; vertical++ {
+            for j := 0; j < 2; j++ {
+                x := right - j
+                upward := (right+1)&2 == 0
+                y := vertical
+                if upward {
+                    y = qr.size - 1 - vertical
+                }
+                if qr.isFunction[y][x] || i >= len(codewords)*8 {
+                    continue
+                }
+                qr.modules[y][x] = (codewords[i>>3]>>uint(7-i&7))&1 == 1
+                i++
+            }
+        }
+    }
+}
+
+// O(s^2)
+func (qr *qrCode) applyMask(mask int) {
+    for y := 0; y < qr.size; y++ {
+        for x := 0; x < qr.size; x++ {
+            var invert bool
+            switch mask {
+            case 0:
+                invert = (x+y)%2 == 0
+            case 1:
+                invert = y%2 == 0
+            case 2:
+                invert = x%3 == 0
+            case 3:
+                invert = (x+y)%3 == 0
+            case 4:
+                invert = (x/3+y/2)%2 == 0
+            case 5:
+                invert = x*y%2+x*y%3 == 0
+            case 6:
+                invert = (x*y%2+x*y%3)%2 == 0
+            case 7:
+                invert = ((x+y)%2+x*y%3)%2 == 0
+            }
+            if invert && !qr.isFunction[y][x] {
+                qr.modules[y][x] = !qr.modules[y][x]
+            }
+        }
+    }
+}
+
+// O(s^2) штраф по правилам стандарта: длинные серии, блоки 2x2,
+// узоры, похожие на поисковые, и перекос темных и светлых модулей
+func (qr *qrCode) penalty() int {
+    result := 0
+    at := func(x, y int, vertical bool) bool {
+        if vertical {
+            return qr.modules[x][y]
+        }
+        return qr.modules[y][x]
+    }
+
+    for _, vertical := range []bool{false, true} {
+        for y := 0; y < qr.size; y++ {
+            run := 1
+            for x := 1; x < qr.size; x++ {
+                if at(x, y, vertical) == at(x-1, y, vertical) {
+                    run++
+                    if run == 5 {
+                        result += 3
+                    } else if run > 5 {
+                        result++
+                    }
+                } else {
+                    run = 1
+                }
+            }
+
+            // 1:1:3:1:1 с четырьмя светлыми модулями с одной из сторон
+            for x := 0; x+7 <= qr.size; x++ {
+                pattern := []bool{true, false, true, true, true, false, true}
+                matched := true
+                for k, dark := range pattern {
+                    if at(x+k, y, vertical) != dark {
+                        matched = false
+                        break
+                    }
+                }
+                if !matched {
+                    continue
+                }
+                if qr.lightRun(x-4, x, y, vertical) || qr.lightRun(x+7, x+11, y, vertical) {
+                    result += 40
+                }
+            }
+        }
+    }
+
+    dark := 0
+    for y := 0; y < qr.size; y++ {
+        for x := 0; x < qr.size; x++ {
+            if qr.modules[y][x] {
+                dark++
+            }
+            if x+1 < qr.size && y+1 < qr.size {
+                c := qr.modules[y][x]
+                if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+                    result += 3
+                }
+            }
+        }
+    }
+
+    total := qr.size * qr.size
+    deviation := absInt(dark*20-total*10) / total
+    result += deviation * 10
+    return result
+}
+
+// O(1) светлые модули в [from, to); за краем кода - тихая зона, она светлая
+func (qr *qrCode) lightRun(from, to, line int, vertical bool) bool {
+    for i := from; i < to; i++ {
+        if i < 0 || i >= qr.size {
+            continue
+        }
+        dark := qr.modules[line][i]
+        if vertical {
+            dark = qr.modules[i][line]
+        }
+        if dark {
+            return false
+        }
+    }
+    return true
+}
+
+func absInt(x int) int {
+    if x < 0 {
+        return -x
+    }
+    return x
+}
+
+func maxInt(a, b int) int {
+    if a > b {
+        return a
+    }
+    return b
+}
\ No newline at end of file
@@ -0,0 +1,211 @@
+package marc
+
+import (
+    "bufio"
+    "bytes"
+    "fmt"
+    "io"
+    "strconv"
+)
+
+// Структура записи ISO 2709:
+// маркер        - 24 байта, в начале длина записи (5 цифр),
+//                 в позициях 12-16 - адрес начала данных
+// справочник    - по 12 байт на поле: тег (3), длина (4), начало (5),
+//                 завершается fieldTerminator
+// данные полей  - каждое поле завершается fieldTerminator,
+//                 подполя начинаются с subfieldDelimiter и кода
+// recordTerminator в конце записи
+const (
+    subfieldDelimiter = 0x1F
+    fieldTerminator   = 0x1E
+    recordTerminator  = 0x1D
+
+    directoryEntrySize = 12
+    maxRecordLength    = 99999
+    maxFieldLength     = 9999
+)
+
+// Потоковое чтение записей ISO 2709: в памяти всегда одна запись
+type Reader struct {
+    reader *bufio.Reader
+    count  int
+}
+
+// O(1)
+func NewReader(r io.Reader) *Reader {
+    return &Reader{reader: bufio.NewReader(r)}
+}
+
+// O(l) l - длина записи. В конце файла возвращает io.EOF
+func (r *Reader) Read() (*Record, error) {
+    // переводы строк между записями встречаются в файлах, сохраненных как текст
+    for {
+        b, err := r.reader.ReadByte()
+        if err != nil {
+            return nil, err
+        }
+        if b != '\n' && b != '\r' {
+            r.reader.UnreadByte()
+            break
+        }
+    }
+    r.count++
+
+    lengthBytes := make([]byte, 5)
+    if _, err := io.ReadFull(r.reader, lengthBytes); err != nil {
+        return nil, fmt.Errorf("запись %d: обрыв файла в маркере", r.count)
+    }
+    length, err := strconv.Atoi(string(lengthBytes))
+    if err != nil || length < LeaderSize+2 {
+        return nil, fmt.Errorf("запись %d: неверная длина записи %q", r.count, lengthBytes)
+    }
+
+    data := make([]byte, length)
+    copy(data, lengthBytes)
+    if _, err := io.ReadFull(r.reader, data[5:]); err != nil {
+        return nil, fmt.Errorf("запись %d: обрыв файла, ожидалось %d байт", r.count, length)
+    }
+
+    record, err := Decode(data)
+    if err != nil {
+        return nil, fmt.Errorf("запись %d: %v", r.count, err)
+    }
+    return record, nil
+}
+
+// O(l) разбор одной записи ISO 2709 вместе с recordTerminator
+func Decode(data []byte) (*Record, error) {
+    if len(data) < LeaderSize+2 || data[len(data)-1] != recordTerminator {
+        return nil, fmt.Errorf("нет признака конца записи")
+    }
+    leader := string(data[:LeaderSize])
+    base, err := strconv.Atoi(leader[12:17])
+    if err != nil || base <= LeaderSize || base > len(data) {
+        return nil, fmt.Errorf("неверный адрес начала данных %q", leader[12:17])
+    }
+    if data[base-1] != fieldTerminator {
+        return nil, fmt.Errorf("справочник не завершен")
+    }
+
+    directory := data[LeaderSize : base-1]
+    if len(directory)%directoryEntrySize != 0 {
+        return nil, fmt.Errorf("длина справочника %d не кратна %d", len(directory), directoryEntrySize)
+    }
+
+    record := &Record{Leader: leader}
+    fieldData := data[base : len(data)-1]
+    for offset := 0; offset < len(directory); offset += directoryEntrySize {
+        entry := directory[offset : offset+directoryEntrySize]
+        tag := string(entry[0:3])
+        length, err1 := strconv.Atoi(string(entry[3:7]))
+        start, err2 := strconv.Atoi(string(entry[7:12]))
+        if err1 != nil || err2 != nil || start+length > len(fieldData) || length == 0 {
+            return nil, fmt.Errorf("неверный элемент справочника для поля %s", tag)
+        }
+
+        value := fieldData[start : start+length]
+        if value[len(value)-1] == fieldTerminator {
+            value = value[:len(value)-1]
+        }
+        field, err := decodeField(tag, value)
+        if err != nil {
+            return nil, err
+        }
+        record.Fields = append(record.Fields, field)
+    }
+    return record, nil
+}
+
+// O(m) m - длина поля
+func decodeField(tag string, value []byte) (Field, error) {
+    field := Field{Tag: tag}
+    if IsControlTag(tag) {
+        field.Value = string(value)
+        return field, nil
+    }
+    if len(value) < 2 {
+        return field, fmt.Errorf("поле %s: нет индикаторов", tag)
+    }
+    field.Ind1, field.Ind2 = value[0], value[1]
+
+    // все до первого разделителя подполей - мусор, в MARC 21 его быть не должно
+    parts := bytes.Split(value[2:], []byte{subfieldDelimiter})
+    for _, part := range parts[1:] {
+        if len(part) == 0 {
+            continue
+        }
+        field.Subfields = append(field.Subfields, Subfield{Code: part[0], Value: string(part[1:])})
+    }
+    return field, nil
+}
+
+// O(l) запись в формате ISO 2709. Длина записи, адрес данных
+// и справочник в маркере пересчитываются
+func Encode(record *Record) ([]byte, error) {
+    if err := record.validate(); err != nil {
+        return nil, err
+    }
+
+    var directory, fieldData bytes.Buffer
+    for _, field := range record.Fields {
+        start := fieldData.Len()
+        if field.IsControl() {
+            fieldData.WriteString(field.Value)
+        } else {
+            fieldData.WriteByte(field.Ind1)
+            fieldData.WriteByte(field.Ind2)
+            for _, subfield := range field.Subfields {
+                fieldData.WriteByte(subfieldDelimiter)
+                fieldData.WriteByte(subfield.Code)
+                fieldData.WriteString(subfield.Value)
+            }
+        }
+        fieldData.WriteByte(fieldTerminator)
+
+        length := fieldData.Len() - start
+        if length > maxFieldLength {
+            return nil, fmt.Errorf("поле %s длиннее %d байт", field.Tag, maxFieldLength)
+        }
+        fmt.Fprintf(&directory, "%s%04d%05d", field.Tag, length, start)
+    }
+    directory.WriteByte(fieldTerminator)
+
+    base := LeaderSize + directory.Len()
+    total := base + fieldData.Len() + 1
+    if total > maxRecordLength {
+        return nil, fmt.Errorf("запись длиннее %d байт", maxRecordLength)
+    }
+
+    leader := []byte(record.Leader)
+    copy(leader[0:5], fmt.Sprintf("%05d", total))
+    copy(leader[10:12], "22")
+    copy(leader[12:17], fmt.Sprintf("%05d", base))
+    copy(leader[20:24], "4500")
+
+    data := make([]byte, 0, total)
+    data = append(data, leader...)
+    data = append(data, directory.Bytes()...)
+    data = append(data, fieldData.Bytes()...)
+    data = append(data, recordTerminator)
+    return data, nil
+}
+
+type Writer struct {
+    writer io.Writer
+}
+
+// O(1)
+func NewWriter(w io.Writer) *Writer {
+    return &Writer{writer: w}
+}
+
+// O(l)
+func (w *Writer) Write(record *Record) error {
+    data, err := Encode(record)
+    if err != nil {
+        return err
+    }
+    _, err = w.writer.Write(data)
+    return err
+}
\ No newline at end of file
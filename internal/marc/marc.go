@@ -0,0 +1,159 @@
+// Пакет marc читает и пишет библиографические записи MARC 21 в двух
+// обменных форматах: двоичном ISO 2709 и MARCXML. Пакет ничего не знает
+// о книгах базы - сопоставление полей MARC с полями книги делает database
+package marc
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Длина маркера записи
+const LeaderSize = 24
+
+// Маркер новой текстовой монографии в Unicode: статус n, тип a, уровень m,
+// позиция 9 'a' - UTF-8, длины индикаторов и кодов подполей - 2,
+// длины частей элемента справочника - 4, 5, 0
+const DefaultLeader = "00000nam a2200000 i 4500"
+
+type Subfield struct {
+    Code  byte
+    Value string
+}
+
+// Поле записи. У управляющих полей (001-009) есть только Value,
+// у полей данных - индикаторы и подполя
+type Field struct {
+    Tag       string
+    Value     string
+    Ind1      byte
+    Ind2      byte
+    Subfields []Subfield
+}
+
+type Record struct {
+    Leader string
+    Fields []Field
+}
+
+// O(1) теги 001-009 - управляющие поля
+func IsControlTag(tag string) bool {
+    return len(tag) == 3 && strings.HasPrefix(tag, "00")
+}
+
+// O(1)
+func validTag(tag string) bool {
+    if len(tag) != 3 {
+        return false
+    }
+    for i := 0; i < 3; i++ {
+        c := tag[i]
+        if !(c >= '0' && c <= '9') && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') {
+            return false
+        }
+    }
+    return true
+}
+
+// O(1)
+func (f Field) IsControl() bool {
+    return IsControlTag(f.Tag)
+}
+
+// O(s) s - количество подполей, значение первого подполя с кодом code
+func (f Field) Subfield(code byte) string {
+    for _, subfield := range f.Subfields {
+        if subfield.Code == code {
+            return subfield.Value
+        }
+    }
+    return ""
+}
+
+// O(s) значения всех подполей с кодом code
+func (f Field) SubfieldValues(code byte) []string {
+    var values []string
+    for _, subfield := range f.Subfields {
+        if subfield.Code == code {
+            values = append(values, subfield.Value)
+        }
+    }
+    return values
+}
+
+// O(1)
+func NewRecord() *Record {
+    return &Record{Leader: DefaultLeader}
+}
+
+// O(1)
+func (r *Record) AddControlField(tag, value string) {
+    r.Fields = append(r.Fields, Field{Tag: tag, Value: value})
+}
+
+// O(1) подполя пишутся в указанном порядке
+func (r *Record) AddDataField(tag string, ind1, ind2 byte, subfields ...Subfield) {
+    r.Fields = append(r.Fields, Field{Tag: tag, Ind1: ind1, Ind2: ind2, Subfields: subfields})
+}
+
+// O(f) f - количество полей, значение первого управляющего поля с тегом tag
+func (r *Record) ControlField(tag string) string {
+    for _, field := range r.Fields {
+        if field.Tag == tag {
+            return field.Value
+        }
+    }
+    return ""
+}
+
+// O(f) все поля с тегом tag в порядке записи
+func (r *Record) DataFields(tag string) []Field {
+    var fields []Field
+    for _, field := range r.Fields {
+        if field.Tag == tag {
+            fields = append(fields, field)
+        }
+    }
+    return fields
+}
+
+// O(1) запись в Unicode (позиция 9 маркера 'a'). Остальное - MARC-8
+func (r *Record) IsUnicode() bool {
+    return len(r.Leader) == LeaderSize && r.Leader[9] == 'a'
+}
+
+// O(f) проверка перед записью: маркер, теги, индикаторы и коды подполей
+func (r *Record) validate() error {
+    if len(r.Leader) != LeaderSize {
+        return fmt.Errorf("длина маркера %d вместо %d", len(r.Leader), LeaderSize)
+    }
+    for _, field := range r.Fields {
+        if !validTag(field.Tag) {
+            return fmt.Errorf("неверный тег поля: %q", field.Tag)
+        }
+        if field.IsControl() {
+            if hasSeparators(field.Value) {
+                return fmt.Errorf("поле %s: служебный символ в значении", field.Tag)
+            }
+            continue
+        }
+        if field.Ind1 == 0 || field.Ind2 == 0 {
+            return fmt.Errorf("поле %s: не заданы индикаторы", field.Tag)
+        }
+        for _, subfield := range field.Subfields {
+            if subfield.Code == 0 || subfield.Code == subfieldDelimiter ||
+                subfield.Code == fieldTerminator || subfield.Code == recordTerminator {
+                return fmt.Errorf("поле %s: неверный код подполя", field.Tag)
+            }
+            if hasSeparators(subfield.Value) {
+                return fmt.Errorf("поле %s: служебный символ в подполе $%c", field.Tag, subfield.Code)
+            }
+        }
+    }
+    return nil
+}
+
+// O(n) разделители ISO 2709 внутри значения сломали бы структуру записи
+func hasSeparators(value string) bool {
+    return strings.ContainsAny(value, string([]byte{subfieldDelimiter, fieldTerminator, recordTerminator}))
+}
\ No newline at end of file
@@ -0,0 +1,175 @@
+package marc
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+)
+
+// Пространство имен MARCXML
+const XMLNamespace = "http://www.loc.gov/MARC21/slim"
+
+// Элементы MARCXML. Управляющие поля в MARC 21 всегда идут перед полями
+// данных, поэтому два отдельных списка не меняют порядок полей
+type xmlRecord struct {
+    XMLName       xml.Name          `xml:"record"`
+    Leader        string            `xml:"leader"`
+    ControlFields []xmlControlField `xml:"controlfield"`
+    DataFields    []xmlDataField    `xml:"datafield"`
+}
+
+type xmlControlField struct {
+    Tag   string `xml:"tag,attr"`
+    Value string `xml:",chardata"`
+}
+
+type xmlDataField struct {
+    Tag       string        `xml:"tag,attr"`
+    Ind1      string        `xml:"ind1,attr"`
+    Ind2      string        `xml:"ind2,attr"`
+    Subfields []xmlSubfield `xml:"subfield"`
+}
+
+type xmlSubfield struct {
+    Code  string `xml:"code,attr"`
+    Value string `xml:",chardata"`
+}
+
+// O(1) пустой или отсутствующий индикатор - пробел
+func indicator(value string) byte {
+    if value == "" {
+        return ' '
+    }
+    return value[0]
+}
+
+// O(f)
+func (x xmlRecord) toRecord() (*Record, error) {
+    record := &Record{Leader: x.Leader}
+    if len(record.Leader) != LeaderSize {
+        record.Leader = DefaultLeader
+    }
+    // MARCXML всегда в Unicode, даже если маркер скопирован из записи в MARC-8
+    leader := []byte(record.Leader)
+    leader[9] = 'a'
+    record.Leader = string(leader)
+
+    for _, control := range x.ControlFields {
+        record.AddControlField(control.Tag, control.Value)
+    }
+    for _, data := range x.DataFields {
+        field := Field{Tag: data.Tag, Ind1: indicator(data.Ind1), Ind2: indicator(data.Ind2)}
+        for _, subfield := range data.Subfields {
+            if len(subfield.Code) != 1 {
+                return nil, fmt.Errorf("поле %s: неверный код подполя %q", data.Tag, subfield.Code)
+            }
+            field.Subfields = append(field.Subfields, Subfield{Code: subfield.Code[0], Value: subfield.Value})
+        }
+        record.Fields = append(record.Fields, field)
+    }
+    return record, nil
+}
+
+// O(f)
+func fromRecord(record *Record) xmlRecord {
+    x := xmlRecord{Leader: record.Leader}
+    for _, field := range record.Fields {
+        if field.IsControl() {
+            x.ControlFields = append(x.ControlFields, xmlControlField{Tag: field.Tag, Value: field.Value})
+            continue
+        }
+        data := xmlDataField{Tag: field.Tag, Ind1: string(field.Ind1), Ind2: string(field.Ind2)}
+        for _, subfield := range field.Subfields {
+            data.Subfields = append(data.Subfields, xmlSubfield{Code: string(subfield.Code), Value: subfield.Value})
+        }
+        x.DataFields = append(x.DataFields, data)
+    }
+    return x
+}
+
+// Потоковое чтение MARCXML: записи ищутся по имени элемента record
+// на любой глубине, так что подходят и collection, и одиночная запись
+type XMLReader struct {
+    decoder *xml.Decoder
+    count   int
+}
+
+// O(1)
+func NewXMLReader(r io.Reader) *XMLReader {
+    return &XMLReader{decoder: xml.NewDecoder(r)}
+}
+
+// O(l) в конце файла возвращает io.EOF
+func (r *XMLReader) Read() (*Record, error) {
+    for {
+        token, err := r.decoder.Token()
+        if err == io.EOF {
+            return nil, io.EOF
+        }
+        if err != nil {
+            return nil, fmt.Errorf("ошибка разбора XML: %v", err)
+        }
+        start, ok := token.(xml.StartElement)
+        if !ok || start.Name.Local != "record" {
+            continue
+        }
+
+        r.count++
+        var x xmlRecord
+        if err := r.decoder.DecodeElement(&x, &start); err != nil {
+            return nil, fmt.Errorf("запись %d: %v", r.count, err)
+        }
+        record, err := x.toRecord()
+        if err != nil {
+            return nil, fmt.Errorf("запись %d: %v", r.count, err)
+        }
+        return record, nil
+    }
+}
+
+// Запись коллекции MARCXML. После последней записи нужен Close
+type XMLWriter struct {
+    writer  io.Writer
+    encoder *xml.Encoder
+    started bool
+}
+
+// O(1)
+func NewXMLWriter(w io.Writer) *XMLWriter {
+    encoder := xml.NewEncoder(w)
+    encoder.Indent("  ", "  ")
+    return &XMLWriter{writer: w, encoder: encoder}
+}
+
+// O(1)
+func (w *XMLWriter) start() error {
+    if w.started {
+        return nil
+    }
+    w.started = true
+    _, err := io.WriteString(w.writer, xml.Header+`<collection xmlns="`+XMLNamespace+`">`+"\n")
+    return err
+}
+
+// O(l)
+func (w *XMLWriter) Write(record *Record) error {
+    if err := record.validate(); err != nil {
+        return err
+    }
+    if err := w.start(); err != nil {
+        return err
+    }
+    return w.encoder.Encode(fromRecord(record))
+}
+
+// O(1) закрывает collection; пустая коллекция тоже корректный документ
+func (w *XMLWriter) Close() error {
+    if err := w.start(); err != nil {
+        return err
+    }
+    if err := w.encoder.Flush(); err != nil {
+        return err
+    }
+    _, err := io.WriteString(w.writer, "\n</collection>\n")
+    return err
+}
\ No newline at end of file